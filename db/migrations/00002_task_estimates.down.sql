@@ -0,0 +1,3 @@
+ALTER TABLE tasks
+    DROP COLUMN IF EXISTS story_points,
+    DROP COLUMN IF EXISTS estimated_minutes;
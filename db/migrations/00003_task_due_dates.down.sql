@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS tasks_user_id_completed_at_idx;
+
+ALTER TABLE tasks
+    DROP COLUMN IF EXISTS due_date,
+    DROP COLUMN IF EXISTS completed_at;
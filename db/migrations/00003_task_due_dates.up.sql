@@ -0,0 +1,7 @@
+ALTER TABLE tasks
+    ADD COLUMN IF NOT EXISTS due_date TIMESTAMP,
+    ADD COLUMN IF NOT EXISTS completed_at TIMESTAMP;
+
+UPDATE tasks SET completed_at = updated_at WHERE status = 'done' AND completed_at IS NULL;
+
+CREATE INDEX IF NOT EXISTS tasks_user_id_completed_at_idx ON tasks (user_id, completed_at);
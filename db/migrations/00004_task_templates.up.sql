@@ -0,0 +1,17 @@
+ALTER TABLE tasks
+    ADD COLUMN IF NOT EXISTS tags TEXT[] NOT NULL DEFAULT '{}';
+
+CREATE TABLE IF NOT EXISTS task_templates (
+                                              id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+                                              user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+                                              name VARCHAR(100) NOT NULL,
+                                              title VARCHAR(255) NOT NULL,
+                                              description TEXT,
+                                              status VARCHAR(10) NOT NULL DEFAULT 'active',
+                                              checklist TEXT[] NOT NULL DEFAULT '{}',
+                                              tags TEXT[] NOT NULL DEFAULT '{}',
+                                              created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+                                              updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS task_templates_user_id_idx ON task_templates (user_id);
@@ -0,0 +1,18 @@
+CREATE TABLE IF NOT EXISTS refresh_tokens (
+                                              id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+                                              user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+                                              family_id VARCHAR(64) NOT NULL,
+                                              token_hash VARCHAR(64) UNIQUE NOT NULL,
+                                              expires_at TIMESTAMPTZ NOT NULL,
+                                              used_at TIMESTAMPTZ,
+                                              revoked_at TIMESTAMPTZ,
+                                              created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS refresh_tokens_family_id_idx ON refresh_tokens (family_id);
+CREATE INDEX IF NOT EXISTS refresh_tokens_user_id_idx ON refresh_tokens (user_id);
+
+CREATE TABLE IF NOT EXISTS revoked_tokens (
+                                              jti VARCHAR(64) PRIMARY KEY,
+                                              expires_at TIMESTAMPTZ NOT NULL
+);
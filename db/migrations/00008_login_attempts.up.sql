@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS login_attempts (
+                                              scope VARCHAR(16) NOT NULL,
+                                              key VARCHAR(255) NOT NULL,
+                                              failures INTEGER NOT NULL DEFAULT 0,
+                                              last_failed_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+                                              locked_until TIMESTAMPTZ,
+                                              PRIMARY KEY (scope, key)
+);
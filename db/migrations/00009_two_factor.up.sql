@@ -0,0 +1,17 @@
+CREATE TABLE IF NOT EXISTS user_totp (
+                                         user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+                                         secret VARCHAR(64) NOT NULL,
+                                         confirmed_at TIMESTAMPTZ,
+                                         last_used_step BIGINT NOT NULL DEFAULT 0,
+                                         created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS recovery_codes (
+                                              id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+                                              user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+                                              code_hash VARCHAR(64) NOT NULL,
+                                              used_at TIMESTAMPTZ,
+                                              created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS recovery_codes_user_id_idx ON recovery_codes (user_id);
@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS personal_access_tokens (
+                                                      id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+                                                      user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+                                                      name VARCHAR(255) NOT NULL,
+                                                      token_hash VARCHAR(64) UNIQUE NOT NULL,
+                                                      scopes TEXT[] NOT NULL DEFAULT '{}',
+                                                      expires_at TIMESTAMPTZ,
+                                                      last_used_at TIMESTAMPTZ,
+                                                      revoked_at TIMESTAMPTZ,
+                                                      created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS personal_access_tokens_user_id_idx ON personal_access_tokens (user_id);
@@ -0,0 +1,19 @@
+CREATE TABLE IF NOT EXISTS user_identities (
+                                               id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+                                               user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+                                               provider VARCHAR(255) NOT NULL,
+                                               subject VARCHAR(255) NOT NULL,
+                                               email VARCHAR(100),
+                                               created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+                                               last_login_at TIMESTAMPTZ,
+                                               UNIQUE (provider, subject)
+);
+
+CREATE INDEX IF NOT EXISTS user_identities_user_id_idx ON user_identities (user_id);
+
+CREATE TABLE IF NOT EXISTS oidc_login_states (
+                                                 state_hash VARCHAR(64) PRIMARY KEY,
+                                                 code_verifier VARCHAR(128) NOT NULL,
+                                                 nonce VARCHAR(64) NOT NULL,
+                                                 expires_at TIMESTAMPTZ NOT NULL
+);
@@ -0,0 +1,10 @@
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS password_change_required BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- Users created or updated through /users used to get their password stored
+-- as plain text, which no login could match. Hash those passwords so they
+-- work, and have the users pick a new one since an admin has seen it.
+UPDATE users
+SET password = crypt(password, gen_salt('bf', 10)),
+    password_change_required = TRUE
+WHERE password NOT LIKE '$%';
@@ -0,0 +1,13 @@
+ALTER TABLE tasks
+    ALTER COLUMN due_date TYPE TIMESTAMP USING due_date AT TIME ZONE 'UTC',
+    ALTER COLUMN completed_at TYPE TIMESTAMP USING completed_at AT TIME ZONE 'UTC',
+    ALTER COLUMN created_at TYPE TIMESTAMP USING created_at AT TIME ZONE 'UTC',
+    ALTER COLUMN updated_at TYPE TIMESTAMP USING updated_at AT TIME ZONE 'UTC';
+
+DROP TABLE IF EXISTS user_avatars;
+
+ALTER TABLE users
+    DROP COLUMN IF EXISTS default_task_view,
+    DROP COLUMN IF EXISTS date_format,
+    DROP COLUMN IF EXISTS locale,
+    DROP COLUMN IF EXISTS time_zone;
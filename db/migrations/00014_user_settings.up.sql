@@ -0,0 +1,20 @@
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS time_zone VARCHAR(64) NOT NULL DEFAULT 'UTC',
+    ADD COLUMN IF NOT EXISTS locale VARCHAR(35) NOT NULL DEFAULT 'en',
+    ADD COLUMN IF NOT EXISTS date_format VARCHAR(16) NOT NULL DEFAULT 'YYYY-MM-DD',
+    ADD COLUMN IF NOT EXISTS default_task_view VARCHAR(16) NOT NULL DEFAULT 'list';
+
+CREATE TABLE IF NOT EXISTS user_avatars (
+                                            user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+                                            content_type VARCHAR(32) NOT NULL,
+                                            data BYTEA NOT NULL,
+                                            updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Task times were stored without a zone and always meant UTC. Storing them
+-- as absolute times lets due dates and statistics follow each user's zone.
+ALTER TABLE tasks
+    ALTER COLUMN due_date TYPE TIMESTAMPTZ USING due_date AT TIME ZONE 'UTC',
+    ALTER COLUMN completed_at TYPE TIMESTAMPTZ USING completed_at AT TIME ZONE 'UTC',
+    ALTER COLUMN created_at TYPE TIMESTAMPTZ USING created_at AT TIME ZONE 'UTC',
+    ALTER COLUMN updated_at TYPE TIMESTAMPTZ USING updated_at AT TIME ZONE 'UTC';
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS data_exports;
+DROP TABLE IF EXISTS account_deletions;
+
+ALTER TABLE users
+    DROP COLUMN IF EXISTS anonymized_at;
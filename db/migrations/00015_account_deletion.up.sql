@@ -0,0 +1,26 @@
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS anonymized_at TIMESTAMPTZ;
+
+CREATE TABLE IF NOT EXISTS account_deletions (
+                                                 user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+                                                 mode VARCHAR(16) NOT NULL,
+                                                 requested_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+                                                 scheduled_for TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS account_deletions_scheduled_for_idx ON account_deletions (scheduled_for);
+
+CREATE TABLE IF NOT EXISTS data_exports (
+                                            id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+                                            user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+                                            status VARCHAR(16) NOT NULL DEFAULT 'pending',
+                                            archive BYTEA,
+                                            error TEXT,
+                                            requested_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+                                            started_at TIMESTAMPTZ,
+                                            completed_at TIMESTAMPTZ,
+                                            expires_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS data_exports_user_id_idx ON data_exports (user_id);
+CREATE INDEX IF NOT EXISTS data_exports_pending_idx ON data_exports (requested_at) WHERE status = 'pending';
@@ -0,0 +1,5 @@
+ALTER TABLE users
+    DROP COLUMN IF EXISTS pending_email;
+
+DROP INDEX IF EXISTS users_email_lower_idx;
+ALTER TABLE users ADD CONSTRAINT users_email_key UNIQUE (email);
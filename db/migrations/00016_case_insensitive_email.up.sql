@@ -0,0 +1,28 @@
+-- Emails differing only in case would collide under the new index. Stop
+-- with a list of them rather than pick which account keeps the address.
+DO $$
+DECLARE
+    duplicates TEXT;
+BEGIN
+    SELECT string_agg(email, ', ') INTO duplicates
+    FROM (
+        SELECT lower(btrim(email)) AS email
+        FROM users
+        GROUP BY lower(btrim(email))
+        HAVING COUNT(*) > 1
+    ) d;
+
+    IF duplicates IS NOT NULL THEN
+        RAISE EXCEPTION 'users with emails differing only in case must be merged first: %', duplicates;
+    END IF;
+END
+$$;
+
+UPDATE users SET email = lower(btrim(email)) WHERE email <> lower(btrim(email));
+
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_email_key;
+CREATE UNIQUE INDEX IF NOT EXISTS users_email_lower_idx ON users (lower(email));
+
+-- A requested email change waits here until the new address is confirmed.
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS pending_email VARCHAR(100);
@@ -0,0 +1,10 @@
+DROP INDEX IF EXISTS task_templates_workspace_id_user_id_idx;
+DROP INDEX IF EXISTS tasks_workspace_id_user_id_idx;
+
+ALTER TABLE task_templates
+    DROP COLUMN IF EXISTS workspace_id;
+ALTER TABLE tasks
+    DROP COLUMN IF EXISTS workspace_id;
+
+DROP TABLE IF EXISTS workspace_members;
+DROP TABLE IF EXISTS workspaces;
@@ -0,0 +1,41 @@
+CREATE TABLE IF NOT EXISTS workspaces (
+                                          id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+                                          name VARCHAR(100) NOT NULL,
+                                          created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+                                          updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS workspace_members (
+                                                 workspace_id UUID NOT NULL REFERENCES workspaces(id) ON DELETE CASCADE,
+                                                 user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+                                                 role VARCHAR(16) NOT NULL DEFAULT 'member' CHECK (role IN ('owner', 'admin', 'member')),
+                                                 created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+                                                 PRIMARY KEY (workspace_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS workspace_members_user_id_idx ON workspace_members (user_id);
+
+-- Every existing user gets a personal workspace holding their tasks and
+-- templates. It reuses the user's ID so the rows can be matched up below.
+INSERT INTO workspaces (id, name)
+SELECT id, 'Personal' FROM users
+ON CONFLICT (id) DO NOTHING;
+
+INSERT INTO workspace_members (workspace_id, user_id, role)
+SELECT id, id, 'owner' FROM users
+ON CONFLICT DO NOTHING;
+
+ALTER TABLE tasks
+    ADD COLUMN IF NOT EXISTS workspace_id UUID REFERENCES workspaces(id) ON DELETE CASCADE;
+UPDATE tasks SET workspace_id = user_id WHERE workspace_id IS NULL;
+ALTER TABLE tasks
+    ALTER COLUMN workspace_id SET NOT NULL;
+
+ALTER TABLE task_templates
+    ADD COLUMN IF NOT EXISTS workspace_id UUID REFERENCES workspaces(id) ON DELETE CASCADE;
+UPDATE task_templates SET workspace_id = user_id WHERE workspace_id IS NULL;
+ALTER TABLE task_templates
+    ALTER COLUMN workspace_id SET NOT NULL;
+
+CREATE INDEX IF NOT EXISTS tasks_workspace_id_user_id_idx ON tasks (workspace_id, user_id);
+CREATE INDEX IF NOT EXISTS task_templates_workspace_id_user_id_idx ON task_templates (workspace_id, user_id);
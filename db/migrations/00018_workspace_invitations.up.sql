@@ -0,0 +1,18 @@
+CREATE TABLE IF NOT EXISTS workspace_invitations (
+                                                     id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+                                                     workspace_id UUID NOT NULL REFERENCES workspaces(id) ON DELETE CASCADE,
+                                                     email VARCHAR(100) NOT NULL,
+                                                     role VARCHAR(16) NOT NULL CHECK (role IN ('owner', 'admin', 'member')),
+                                                     token_hash VARCHAR(64) UNIQUE NOT NULL,
+                                                     invited_by UUID REFERENCES users(id) ON DELETE SET NULL,
+                                                     created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+                                                     sent_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+                                                     expires_at TIMESTAMPTZ NOT NULL,
+                                                     accepted_at TIMESTAMPTZ,
+                                                     revoked_at TIMESTAMPTZ
+);
+
+-- An address has at most one open invitation per workspace.
+CREATE UNIQUE INDEX IF NOT EXISTS workspace_invitations_open_idx
+    ON workspace_invitations (workspace_id, lower(email))
+    WHERE accepted_at IS NULL AND revoked_at IS NULL;
@@ -0,0 +1,20 @@
+CREATE TABLE IF NOT EXISTS sessions (
+                                        id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+                                        user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+                                        family_id VARCHAR(64) UNIQUE NOT NULL,
+                                        user_agent VARCHAR(512) NOT NULL DEFAULT '',
+                                        ip VARCHAR(64) NOT NULL DEFAULT '',
+                                        created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+                                        last_seen_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+                                        revoked_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS sessions_user_id_idx ON sessions (user_id);
+
+-- Logins from before this migration become sessions without device details.
+INSERT INTO sessions (user_id, family_id, created_at, last_seen_at)
+SELECT user_id, family_id, MIN(created_at), MAX(created_at)
+FROM refresh_tokens
+WHERE revoked_at IS NULL AND expires_at > CURRENT_TIMESTAMP
+GROUP BY user_id, family_id
+ON CONFLICT (family_id) DO NOTHING;
@@ -0,0 +1,32 @@
+-- Actor and target are not foreign keys: the trail has to outlive the
+-- accounts it mentions.
+CREATE TABLE IF NOT EXISTS audit_events (
+                                            id BIGSERIAL PRIMARY KEY,
+                                            created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+                                            action VARCHAR(64) NOT NULL,
+                                            actor_id UUID,
+                                            target_id UUID,
+                                            ip VARCHAR(64) NOT NULL DEFAULT '',
+                                            user_agent VARCHAR(512) NOT NULL DEFAULT '',
+                                            request_id VARCHAR(64) NOT NULL DEFAULT '',
+                                            details JSONB NOT NULL DEFAULT '{}'
+);
+
+CREATE INDEX IF NOT EXISTS audit_events_created_at_idx ON audit_events (created_at);
+CREATE INDEX IF NOT EXISTS audit_events_actor_id_idx ON audit_events (actor_id, created_at);
+CREATE INDEX IF NOT EXISTS audit_events_target_id_idx ON audit_events (target_id, created_at);
+
+-- The table is append-only: rows cannot be changed or removed through SQL.
+CREATE OR REPLACE FUNCTION audit_events_append_only() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'audit_events is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER audit_events_no_update
+    BEFORE UPDATE OR DELETE ON audit_events
+    FOR EACH ROW EXECUTE FUNCTION audit_events_append_only();
+
+CREATE TRIGGER audit_events_no_truncate
+    BEFORE TRUNCATE ON audit_events
+    FOR EACH STATEMENT EXECUTE FUNCTION audit_events_append_only();
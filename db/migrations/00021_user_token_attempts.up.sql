@@ -0,0 +1,2 @@
+ALTER TABLE user_tokens
+    ADD COLUMN IF NOT EXISTS attempts INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS workspaces_personal_owner_id_idx;
+
+ALTER TABLE workspaces
+    DROP COLUMN IF EXISTS personal,
+    DROP COLUMN IF EXISTS owner_id;
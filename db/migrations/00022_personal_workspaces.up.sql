@@ -0,0 +1,24 @@
+ALTER TABLE workspaces
+    ADD COLUMN IF NOT EXISTS owner_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    ADD COLUMN IF NOT EXISTS personal BOOLEAN NOT NULL DEFAULT false;
+
+-- Workspaces created by 00017 share the ID of their user.
+UPDATE workspaces w
+SET owner_id = w.id, personal = true
+WHERE EXISTS (SELECT 1 FROM users u WHERE u.id = w.id);
+
+-- Later ones were created on first use; when concurrent requests created
+-- several, the oldest one becomes the personal workspace.
+UPDATE workspaces w
+SET owner_id = p.user_id, personal = true
+FROM (
+    SELECT DISTINCT ON (m.user_id) m.user_id, w.id
+    FROM workspaces w
+    JOIN workspace_members m ON m.workspace_id = w.id AND m.role = 'owner'
+    WHERE w.name = 'Personal'
+      AND NOT EXISTS (SELECT 1 FROM workspaces o WHERE o.owner_id = m.user_id AND o.personal)
+    ORDER BY m.user_id, w.created_at, w.id
+) p
+WHERE w.id = p.id AND NOT w.personal;
+
+CREATE UNIQUE INDEX IF NOT EXISTS workspaces_personal_owner_id_idx ON workspaces (owner_id) WHERE personal;
@@ -2,19 +2,29 @@ package app
 
 import (
 	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"github.com/yrss1/todo/internal/config"
 	"github.com/yrss1/todo/internal/handler"
 	"github.com/yrss1/todo/internal/repository"
 	"github.com/yrss1/todo/internal/service/account"
+	"github.com/yrss1/todo/internal/service/audit"
 	"github.com/yrss1/todo/internal/service/auth"
+	"github.com/yrss1/todo/internal/service/credentials"
+	"github.com/yrss1/todo/internal/service/privacy"
 	"github.com/yrss1/todo/internal/service/todo"
+	"github.com/yrss1/todo/internal/service/workspace"
 	"github.com/yrss1/todo/pkg/log"
+	"github.com/yrss1/todo/pkg/mail"
+	"github.com/yrss1/todo/pkg/oidc"
+	"github.com/yrss1/todo/pkg/password"
 	"github.com/yrss1/todo/pkg/server"
 	"go.uber.org/zap"
 	"os"
 	"os/signal"
+	"path"
+	"strings"
 	"syscall"
 	"time"
 )
@@ -34,20 +44,164 @@ func Run() {
 		return
 	}
 
+	var keys *auth.KeySet
+	if configs.APP.JWTAlgorithm == "HS256" {
+		keys, err = auth.NewHMACKeySet(configs.APP.JWT)
+	} else {
+		keys, err = auth.LoadKeySet(configs.APP.JWTAlgorithm, configs.APP.JWTKeys, configs.APP.JWTActiveKey)
+	}
+	if err != nil {
+		logger.Error("ERR_INIT_JWT_KEYS", zap.Error(err))
+		return
+	}
+
+	hasher, err := password.NewHasher(configs.APP.PasswordHasher, password.Argon2Params{
+		Memory:  configs.APP.Argon2Memory,
+		Time:    configs.APP.Argon2Time,
+		Threads: configs.APP.Argon2Threads,
+	}, configs.APP.BcryptCost)
+	if err != nil {
+		logger.Error("ERR_INIT_PASSWORD_HASHER", zap.Error(err))
+		return
+	}
+
+	policy, err := password.NewPolicy(configs.APP.PasswordMinLength, configs.APP.PasswordMaxLength, configs.APP.PasswordBreachedList)
+	if err != nil {
+		logger.Error("ERR_INIT_PASSWORD_POLICY", zap.Error(err))
+		return
+	}
+
+	var mailer mail.Mailer
+	switch configs.MAIL.Driver {
+	case "smtp":
+		if configs.MAIL.Host == "" {
+			err = errors.New("MAIL_HOST is required by the smtp mail driver")
+			break
+		}
+		mailer = mail.NewSMTPMailer(configs.MAIL.Host, configs.MAIL.Port, configs.MAIL.Username, configs.MAIL.Password, configs.MAIL.From)
+	case "file":
+		mailer, err = mail.NewFileMailer(configs.MAIL.Dir)
+	case "log":
+		mailer, err = mail.NewFileMailer("")
+	default:
+		err = fmt.Errorf("unknown mail driver %q", configs.MAIL.Driver)
+	}
+	if err != nil {
+		logger.Error("ERR_INIT_MAILER", zap.Error(err))
+		return
+	}
+
+	var provider *oidc.Provider
+	if configs.OIDC.Issuer != "" {
+		redirectURL := configs.OIDC.RedirectURL
+		if redirectURL == "" {
+			redirectURL = strings.TrimSuffix(configs.APP.PublicURL, "/") + path.Join("/", configs.APP.Path, "auth/oidc/callback")
+		}
+		provider = oidc.NewProvider(oidc.Config{
+			Issuer:       configs.OIDC.Issuer,
+			ClientID:     configs.OIDC.ClientID,
+			ClientSecret: configs.OIDC.ClientSecret,
+			RedirectURL:  redirectURL,
+			Scopes:       configs.OIDC.Scopes,
+		})
+	}
+
+	auditService, err := audit.New(
+		audit.WithAuditRepository(repositories.Audit),
+	)
+	if err != nil {
+		logger.Error("ERR_INIT_AUDIT_SERVICE", zap.Error(err))
+		return
+	}
+
+	credentialsService, err := credentials.New(
+		credentials.WithUserRepository(repositories.User),
+		credentials.WithTokenRepository(repositories.Token),
+		credentials.WithSessionRepository(repositories.Session),
+		credentials.WithHasher(hasher),
+		credentials.WithPolicy(policy),
+		credentials.WithAudit(auditService),
+	)
+	if err != nil {
+		logger.Error("ERR_INIT_CREDENTIALS_SERVICE", zap.Error(err))
+		return
+	}
+
 	authService, err := auth.New(
+		auth.WithKeySet(keys),
 		auth.WithUserRepository(repositories.User),
+		auth.WithTokenRepository(repositories.Token),
+		auth.WithSessionRepository(repositories.Session),
+		auth.WithTokenTTL(configs.APP.AccessTTL, configs.APP.RefreshTTL),
+		auth.WithImpersonationTTL(configs.APP.ImpersonationTTL),
+		auth.WithVerificationRepository(repositories.Verification),
+		auth.WithTwoFactorRepository(repositories.TwoFactor),
+		auth.WithAccessTokenRepository(repositories.AccessToken),
+		auth.WithOIDC(repositories.Identity, provider, configs.OIDC.AutoRegister),
+		auth.WithCredentials(credentialsService),
+		auth.WithAudit(auditService),
+		auth.WithMailer(mailer, configs.APP.PublicURL),
+		auth.WithRequireVerifiedEmail(configs.APP.RequireVerifiedEmail),
+		auth.WithLockout(repositories.Lockout, auth.LockoutPolicy{
+			FreeAttempts:  configs.APP.LoginFreeAttempts,
+			MaxAttempts:   configs.APP.LoginMaxAttempts,
+			IPMaxAttempts: configs.APP.LoginIPMaxAttempts,
+			Backoff:       configs.APP.LoginBackoff,
+			BackoffMax:    configs.APP.LoginBackoffMax,
+			Lockout:       configs.APP.LoginLockout,
+		}),
 	)
+	if err != nil {
+		logger.Error("ERR_INIT_AUTH_SERVICE", zap.Error(err))
+		return
+	}
 
 	accountService, err := account.New(
 		account.WithUserRepository(repositories.User),
+		account.WithTokenRepository(repositories.Token),
+		account.WithVerificationRepository(repositories.Verification),
+		account.WithCredentials(credentialsService),
+		account.WithAudit(auditService),
+		account.WithMailer(mailer, configs.APP.PublicURL),
 	)
 	if err != nil {
 		logger.Error("ERR_INIT_ACCOUNT_SERVICE", zap.Error(err))
 		return
 	}
 
+	privacyService, err := privacy.New(
+		privacy.WithPrivacyRepository(repositories.Privacy),
+		privacy.WithUserRepository(repositories.User),
+		privacy.WithTaskRepository(repositories.Task),
+		privacy.WithTemplateRepository(repositories.Template),
+		privacy.WithAccessTokenRepository(repositories.AccessToken),
+		privacy.WithIdentityRepository(repositories.Identity),
+		privacy.WithWorkspaceRepository(repositories.Workspace),
+		privacy.WithCredentials(credentialsService),
+		privacy.WithAudit(auditService),
+		privacy.WithMailer(mailer, configs.APP.PublicURL),
+		privacy.WithRetention(configs.APP.AccountDeletionGrace, configs.APP.DataExportTTL),
+	)
+	if err != nil {
+		logger.Error("ERR_INIT_PRIVACY_SERVICE", zap.Error(err))
+		return
+	}
+
+	workspaceService, err := workspace.New(
+		workspace.WithWorkspaceRepository(repositories.Workspace),
+		workspace.WithUserRepository(repositories.User),
+		workspace.WithAuthService(authService),
+		workspace.WithMailer(mailer, configs.APP.PublicURL),
+	)
+	if err != nil {
+		logger.Error("ERR_INIT_WORKSPACE_SERVICE", zap.Error(err))
+		return
+	}
+
 	todoService, err := todo.New(
-		todo.WithTaskRepository(repositories.Task))
+		todo.WithTaskRepository(repositories.Task),
+		todo.WithTemplateRepository(repositories.Template),
+		todo.WithUserRepository(repositories.User))
 	if err != nil {
 		logger.Error("ERR_INIT_TODO_SERVICE", zap.Error(err))
 		return
@@ -59,6 +213,10 @@ func Run() {
 			AuthService:    authService,
 			AccountService: accountService,
 			TodoService:    todoService,
+			PrivacyService: privacyService,
+
+			WorkspaceService: workspaceService,
+			AuditService:     auditService,
 		},
 		handler.WithHTTPHandler())
 	if err != nil {
@@ -77,6 +235,13 @@ func Run() {
 	}
 	logger.Info("http server started on http://localhost:" + configs.APP.Port + "/swagger/index.html")
 
+	jobs, stopJobs := context.WithCancel(context.Background())
+	jobsDone := make(chan struct{})
+	go func() {
+		defer close(jobsDone)
+		privacyService.Run(jobs, configs.APP.JobInterval)
+	}()
+
 	var wait time.Duration
 	flag.DurationVar(&wait, "graceful-timeout", time.Second*15, "the duration for which the httpServer gracefully wait for existing connections to finish - e.g. 15s or 1m")
 	flag.Parse()
@@ -93,6 +258,8 @@ func Run() {
 	}
 
 	fmt.Println("running cleanup tasks...")
+	stopJobs()
+	<-jobsDone
 
 	fmt.Println("server was successful shutdown.")
 }
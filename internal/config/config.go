@@ -15,12 +15,45 @@ const (
 	defaultAppGRPCPort = "9004"
 	defaultAppPath     = "/"
 	defaultAppTimeout  = 60 * time.Second
+
+	defaultAppJWTAlgorithm = "HS256"
+	defaultAppPublicURL    = "http://localhost:8080"
+
+	defaultMailDriver = "smtp"
+	defaultMailPort   = "587"
+	defaultMailFrom   = "no-reply@todo.local"
+
+	defaultAppAccessTTL  = 15 * time.Minute
+	defaultAppRefreshTTL = 30 * 24 * time.Hour
+
+	defaultAppImpersonationTTL = 15 * time.Minute
+
+	defaultAppLoginFreeAttempts  = 3
+	defaultAppLoginMaxAttempts   = 10
+	defaultAppLoginIPMaxAttempts = 100
+	defaultAppLoginBackoff       = time.Second
+	defaultAppLoginBackoffMax    = time.Minute
+	defaultAppLoginLockout       = 15 * time.Minute
+
+	defaultAppPasswordHasher    = "argon2id"
+	defaultAppPasswordMinLength = 8
+	defaultAppPasswordMaxLength = 128
+	defaultAppBcryptCost        = 10
+	defaultAppArgon2Memory      = 19 * 1024
+	defaultAppArgon2Time        = 2
+	defaultAppArgon2Threads     = 1
+
+	defaultAppAccountDeletionGrace = 14 * 24 * time.Hour
+	defaultAppDataExportTTL        = 7 * 24 * time.Hour
+	defaultAppJobInterval          = time.Minute
 )
 
 type (
 	Configs struct {
 		APP      AppConfig
 		POSTGRES StoreConfig
+		MAIL     MailConfig
+		OIDC     OIDCConfig
 	}
 
 	AppConfig struct {
@@ -30,11 +63,86 @@ type (
 		Path     string
 		Timeout  time.Duration
 		JWT      []byte
+
+		// JWTAlgorithm selects HS256 with the JWT secret, or RS256/EdDSA with
+		// the PEM files in JWTKeys. JWTActiveKey names the file (without
+		// extension) that signs new tokens; the others only verify.
+		JWTAlgorithm string
+		JWTKeys      []string
+		JWTActiveKey string
+
+		AccessTTL  time.Duration
+		RefreshTTL time.Duration
+
+		// ImpersonationTTL is how long a token an admin gets to act as
+		// another user stays valid. It cannot be refreshed.
+		ImpersonationTTL time.Duration
+
+		// PublicURL is the base of links sent in emails.
+		PublicURL            string
+		RequireVerifiedEmail bool
+
+		// Failed logins per account back off exponentially after
+		// LoginFreeAttempts and lock the account for LoginLockout after
+		// LoginMaxAttempts. LoginIPMaxAttempts locks a client IP the same way.
+		LoginFreeAttempts  int
+		LoginMaxAttempts   int
+		LoginIPMaxAttempts int
+		LoginBackoff       time.Duration
+		LoginBackoffMax    time.Duration
+		LoginLockout       time.Duration
+
+		// TrustedProxies are the addresses or CIDR ranges whose
+		// X-Forwarded-For header is believed. By default none is, and the
+		// client IP is the address of the connection.
+		TrustedProxies []string
+
+		// PasswordHasher is "argon2id" or "bcrypt". Hashes made with the
+		// other algorithm or other costs are upgraded on the next login.
+		// PasswordBreachedList is a file of forbidden passwords, one per line.
+		PasswordHasher       string
+		PasswordMinLength    int
+		PasswordMaxLength    int
+		PasswordBreachedList string
+		BcryptCost           int
+		Argon2Memory         uint32
+		Argon2Time           uint32
+		Argon2Threads        uint8
+
+		// AccountDeletionGrace is how long a deletion requested through
+		// /me/deletion can still be cancelled. Finished data exports can be
+		// downloaded for DataExportTTL. Background jobs run every JobInterval.
+		AccountDeletionGrace time.Duration
+		DataExportTTL        time.Duration
+		JobInterval          time.Duration
 	}
 
 	StoreConfig struct {
 		DSN string
 	}
+
+	MailConfig struct {
+		// Driver is "smtp", or for development "file" (messages written to
+		// Dir) or "log" (only recipients and subjects are logged).
+		Driver   string
+		Host     string
+		Port     string
+		Username string
+		Password string
+		From     string
+		Dir      string
+	}
+
+	// OIDCConfig enables single sign-on when Issuer is set. RedirectURL
+	// defaults to the callback route under APP_PUBLICURL.
+	OIDCConfig struct {
+		Issuer       string
+		ClientID     string
+		ClientSecret string
+		RedirectURL  string
+		Scopes       []string
+		AutoRegister bool
+	}
 )
 
 func New() (cfg Configs, err error) {
@@ -50,6 +158,34 @@ func New() (cfg Configs, err error) {
 		GRPCPort: defaultAppGRPCPort,
 		Path:     defaultAppPath,
 		Timeout:  defaultAppTimeout,
+
+		JWTAlgorithm: defaultAppJWTAlgorithm,
+
+		AccessTTL:  defaultAppAccessTTL,
+		RefreshTTL: defaultAppRefreshTTL,
+
+		ImpersonationTTL: defaultAppImpersonationTTL,
+
+		PublicURL: defaultAppPublicURL,
+
+		LoginFreeAttempts:  defaultAppLoginFreeAttempts,
+		LoginMaxAttempts:   defaultAppLoginMaxAttempts,
+		LoginIPMaxAttempts: defaultAppLoginIPMaxAttempts,
+		LoginBackoff:       defaultAppLoginBackoff,
+		LoginBackoffMax:    defaultAppLoginBackoffMax,
+		LoginLockout:       defaultAppLoginLockout,
+
+		PasswordHasher:    defaultAppPasswordHasher,
+		PasswordMinLength: defaultAppPasswordMinLength,
+		PasswordMaxLength: defaultAppPasswordMaxLength,
+		BcryptCost:        defaultAppBcryptCost,
+		Argon2Memory:      defaultAppArgon2Memory,
+		Argon2Time:        defaultAppArgon2Time,
+		Argon2Threads:     defaultAppArgon2Threads,
+
+		AccountDeletionGrace: defaultAppAccountDeletionGrace,
+		DataExportTTL:        defaultAppDataExportTTL,
+		JobInterval:          defaultAppJobInterval,
 	}
 
 	if err = envconfig.Process("APP", &cfg.APP); err != nil {
@@ -60,5 +196,23 @@ func New() (cfg Configs, err error) {
 		return
 	}
 
+	cfg.MAIL = MailConfig{
+		Driver: defaultMailDriver,
+		Port:   defaultMailPort,
+		From:   defaultMailFrom,
+	}
+
+	if err = envconfig.Process("MAIL", &cfg.MAIL); err != nil {
+		return
+	}
+
+	cfg.OIDC = OIDCConfig{
+		AutoRegister: true,
+	}
+
+	if err = envconfig.Process("OIDC", &cfg.OIDC); err != nil {
+		return
+	}
+
 	return
 }
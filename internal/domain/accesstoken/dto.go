@@ -0,0 +1,74 @@
+package accesstoken
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+type Request struct {
+	Name      *string    `json:"name"`
+	Scopes    []string   `json:"scopes"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+func (s *Request) Validate() error {
+	if s.Name == nil || strings.TrimSpace(*s.Name) == "" {
+		return errors.New("name: cannot be blank")
+	}
+
+	if len(s.Scopes) == 0 {
+		return errors.New("scopes: cannot be blank")
+	}
+
+	for _, scope := range s.Scopes {
+		if !slices.Contains(Scopes, scope) {
+			return fmt.Errorf("scopes: unknown scope %q, must be one of %s", scope, strings.Join(Scopes, ", "))
+		}
+	}
+
+	if s.ExpiresAt != nil && !s.ExpiresAt.After(time.Now()) {
+		return errors.New("expires_at: must be in the future")
+	}
+
+	return nil
+}
+
+type Response struct {
+	ID         string     `json:"id"`
+	Name       string     `json:"name"`
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// CreateResponse is returned once, when the token is created. Only its hash
+// is stored, so the token cannot be shown again.
+type CreateResponse struct {
+	Response
+	Token string `json:"token"`
+}
+
+func ParseFromEntity(data Entity) (res Response) {
+	res = Response{
+		ID:         data.ID,
+		Name:       data.Name,
+		Scopes:     make([]string, 0, len(data.Scopes)),
+		ExpiresAt:  data.ExpiresAt,
+		LastUsedAt: data.LastUsedAt,
+		CreatedAt:  data.CreatedAt,
+	}
+	res.Scopes = append(res.Scopes, data.Scopes...)
+	return
+}
+
+func ParseFromEntities(data []Entity) (res []Response) {
+	res = make([]Response, 0)
+	for _, object := range data {
+		res = append(res, ParseFromEntity(object))
+	}
+	return
+}
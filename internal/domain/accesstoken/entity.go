@@ -0,0 +1,34 @@
+package accesstoken
+
+import (
+	"github.com/lib/pq"
+	"time"
+)
+
+// Prefix marks personal access tokens so AuthMiddleware can tell them apart
+// from JWTs.
+const Prefix = "pat_"
+
+const (
+	ScopeTasksRead  = "tasks:read"
+	ScopeTasksWrite = "tasks:write"
+	ScopeUsersAdmin = "users:admin"
+)
+
+var Scopes = []string{ScopeTasksRead, ScopeTasksWrite, ScopeUsersAdmin}
+
+type Entity struct {
+	ID         string         `db:"id"`
+	UserID     string         `db:"user_id"`
+	Name       string         `db:"name"`
+	TokenHash  string         `db:"token_hash"`
+	Scopes     pq.StringArray `db:"scopes"`
+	ExpiresAt  *time.Time     `db:"expires_at"`
+	LastUsedAt *time.Time     `db:"last_used_at"`
+	RevokedAt  *time.Time     `db:"revoked_at"`
+	CreatedAt  time.Time      `db:"created_at"`
+}
+
+func (e Entity) Active() bool {
+	return e.RevokedAt == nil && (e.ExpiresAt == nil || time.Now().Before(*e.ExpiresAt))
+}
@@ -0,0 +1,11 @@
+package accesstoken
+
+import "context"
+
+type Repository interface {
+	List(ctx context.Context, userID string) (dest []Entity, err error)
+	Add(ctx context.Context, data Entity) (dest Entity, err error)
+	GetByHash(ctx context.Context, tokenHash string) (dest Entity, err error)
+	Touch(ctx context.Context, id string) (err error)
+	Revoke(ctx context.Context, userID string, id string) (err error)
+}
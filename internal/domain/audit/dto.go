@@ -0,0 +1,102 @@
+package audit
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	DefaultLimit = 50
+	MaxLimit     = 500
+)
+
+var uuidPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+// Filter selects audit events. Empty fields match everything; From is
+// inclusive and To exclusive. Events are returned newest first.
+type Filter struct {
+	Action   string
+	ActorID  string
+	TargetID string
+	IP       string
+	From     *time.Time
+	To       *time.Time
+
+	Page  int
+	Limit int
+}
+
+// Validate fills in the defaults and rejects unknown actions and malformed
+// user IDs.
+func (s *Filter) Validate() error {
+	if s.Action != "" && !slices.Contains(Actions, s.Action) {
+		return fmt.Errorf("action must be one of %s", strings.Join(Actions, ", "))
+	}
+
+	if s.ActorID != "" && !uuidPattern.MatchString(s.ActorID) {
+		return errors.New("actor_id: must be a user ID")
+	}
+
+	if s.TargetID != "" && !uuidPattern.MatchString(s.TargetID) {
+		return errors.New("target_id: must be a user ID")
+	}
+
+	if s.From != nil && s.To != nil && !s.From.Before(*s.To) {
+		return errors.New("from must be before to")
+	}
+
+	if s.Page < 1 {
+		s.Page = 1
+	}
+	if s.Limit < 1 {
+		s.Limit = DefaultLimit
+	}
+	if s.Limit > MaxLimit {
+		s.Limit = MaxLimit
+	}
+
+	return nil
+}
+
+type Response struct {
+	ID        int64           `json:"id"`
+	CreatedAt time.Time       `json:"created_at"`
+	Action    string          `json:"action"`
+	ActorID   *string         `json:"actor_id"`
+	TargetID  *string         `json:"target_id"`
+	IP        string          `json:"ip"`
+	UserAgent string          `json:"user_agent"`
+	RequestID string          `json:"request_id"`
+	Details   json.RawMessage `json:"details" swaggertype:"object"`
+}
+
+func ParseFromEntity(data Entity) Response {
+	res := Response{
+		ID:        data.ID,
+		CreatedAt: data.CreatedAt,
+		Action:    data.Action,
+		ActorID:   data.ActorID,
+		TargetID:  data.TargetID,
+		IP:        data.IP,
+		UserAgent: data.UserAgent,
+		RequestID: data.RequestID,
+		Details:   json.RawMessage(data.Details),
+	}
+	if len(res.Details) == 0 {
+		res.Details = json.RawMessage("{}")
+	}
+	return res
+}
+
+func ParseFromEntities(data []Entity) (res []Response) {
+	res = make([]Response, 0)
+	for _, object := range data {
+		res = append(res, ParseFromEntity(object))
+	}
+	return
+}
@@ -0,0 +1,70 @@
+package audit
+
+import (
+	"context"
+	"time"
+)
+
+const (
+	ActionLoginSucceeded         = "login.succeeded"
+	ActionLoginFailed            = "login.failed"
+	ActionTokenRefreshed         = "token.refreshed"
+	ActionTokenReused            = "token.reused"
+	ActionPasswordChanged        = "password.changed"
+	ActionPasswordChangeRequired = "password.change_required"
+	ActionRoleChanged            = "user.role_changed"
+	ActionUserDeleted            = "user.deleted"
+	ActionImpersonationStarted   = "impersonation.started"
+	ActionImpersonatedRequest    = "impersonation.request"
+)
+
+var Actions = []string{
+	ActionLoginSucceeded,
+	ActionLoginFailed,
+	ActionTokenRefreshed,
+	ActionTokenReused,
+	ActionPasswordChanged,
+	ActionPasswordChangeRequired,
+	ActionRoleChanged,
+	ActionUserDeleted,
+	ActionImpersonationStarted,
+	ActionImpersonatedRequest,
+}
+
+// Entity is one row of the audit trail. Rows are never changed once
+// written.
+type Entity struct {
+	ID        int64     `db:"id"`
+	CreatedAt time.Time `db:"created_at"`
+	Action    string    `db:"action"`
+	ActorID   *string   `db:"actor_id"`
+	TargetID  *string   `db:"target_id"`
+	IP        string    `db:"ip"`
+	UserAgent string    `db:"user_agent"`
+	RequestID string    `db:"request_id"`
+	Details   []byte    `db:"details"`
+}
+
+// Source describes where an action came from: the request and, once it is
+// authenticated, the user making it. While an admin impersonates a user,
+// ActorID is the admin and OnBehalfOf the impersonated user.
+type Source struct {
+	ActorID    string
+	OnBehalfOf string
+	IP         string
+	UserAgent  string
+	RequestID  string
+}
+
+type source struct{}
+
+func ContextWithSource(ctx context.Context, src Source) context.Context {
+	return context.WithValue(ctx, source{}, src)
+}
+
+// SourceFromContext returns the source of the request, or an empty one for
+// work that does not come from a request, such as background jobs.
+func SourceFromContext(ctx context.Context) Source {
+	src, _ := ctx.Value(source{}).(Source)
+	return src
+}
@@ -0,0 +1,10 @@
+package audit
+
+import "context"
+
+type Repository interface {
+	Add(ctx context.Context, data Entity) (err error)
+	List(ctx context.Context, filter Filter) (dest []Entity, total int, err error)
+	// Stream calls fn for every event matching filter, ignoring pagination.
+	Stream(ctx context.Context, filter Filter, fn func(Entity) error) (err error)
+}
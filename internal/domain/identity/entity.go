@@ -0,0 +1,20 @@
+package identity
+
+import "time"
+
+// Entity links an account at an external identity provider to a user.
+type Entity struct {
+	ID       string  `db:"id"`
+	UserID   string  `db:"user_id"`
+	Provider string  `db:"provider"`
+	Subject  string  `db:"subject"`
+	Email    *string `db:"email"`
+}
+
+// State is a pending OIDC login, keyed by the hash of the state parameter.
+type State struct {
+	StateHash    string    `db:"state_hash"`
+	CodeVerifier string    `db:"code_verifier"`
+	Nonce        string    `db:"nonce"`
+	ExpiresAt    time.Time `db:"expires_at"`
+}
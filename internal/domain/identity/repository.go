@@ -0,0 +1,13 @@
+package identity
+
+import "context"
+
+type Repository interface {
+	List(ctx context.Context, userID string) (dest []Entity, err error)
+	GetBySubject(ctx context.Context, provider, subject string) (dest Entity, err error)
+	Add(ctx context.Context, data Entity) (id string, err error)
+	Touch(ctx context.Context, id string) (err error)
+
+	AddState(ctx context.Context, data State) (err error)
+	ConsumeState(ctx context.Context, stateHash string) (dest State, err error)
+}
@@ -0,0 +1,17 @@
+package lockout
+
+import "time"
+
+const (
+	ScopeAccount = "account"
+	ScopeIP      = "ip"
+)
+
+// Entity counts the recent failed logins for an email address or a client IP.
+type Entity struct {
+	Scope        string     `db:"scope"`
+	Key          string     `db:"key"`
+	Failures     int        `db:"failures"`
+	LastFailedAt time.Time  `db:"last_failed_at"`
+	LockedUntil  *time.Time `db:"locked_until"`
+}
@@ -0,0 +1,13 @@
+package lockout
+
+import (
+	"context"
+	"time"
+)
+
+type Repository interface {
+	Get(ctx context.Context, scope, key string) (dest Entity, err error)
+	RecordFailure(ctx context.Context, scope, key string, window time.Duration) (dest Entity, err error)
+	Lock(ctx context.Context, scope, key string, until time.Time) (err error)
+	Reset(ctx context.Context, scope, key string) (err error)
+}
@@ -0,0 +1,77 @@
+package privacy
+
+import (
+	"errors"
+	"time"
+)
+
+// DeletionRequest schedules the deletion of the current account. The
+// password confirms that the account owner is asking.
+type DeletionRequest struct {
+	Password *string `json:"password"`
+	Mode     *string `json:"mode"`
+}
+
+func (s *DeletionRequest) Validate() error {
+	if s.Password == nil {
+		return errors.New("password: cannot be blank")
+	}
+
+	if s.Mode == nil {
+		mode := ModeDelete
+		s.Mode = &mode
+	}
+
+	if *s.Mode != ModeDelete && *s.Mode != ModeAnonymize {
+		return errors.New("mode must be either 'delete' or 'anonymize'")
+	}
+
+	return nil
+}
+
+type DeletionResponse struct {
+	Mode         string    `json:"mode"`
+	RequestedAt  time.Time `json:"requested_at"`
+	ScheduledFor time.Time `json:"scheduled_for"`
+}
+
+func ParseFromDeletion(data Deletion) DeletionResponse {
+	return DeletionResponse{
+		Mode:         data.Mode,
+		RequestedAt:  data.RequestedAt,
+		ScheduledFor: data.ScheduledFor,
+	}
+}
+
+type ExportResponse struct {
+	ID          string     `json:"id"`
+	Status      string     `json:"status"`
+	Error       string     `json:"error,omitempty"`
+	Size        *int       `json:"size,omitempty"`
+	RequestedAt time.Time  `json:"requested_at"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+}
+
+func ParseFromExport(data Export) (res ExportResponse) {
+	res = ExportResponse{
+		ID:          data.ID,
+		Status:      data.Status,
+		Size:        data.Size,
+		RequestedAt: data.RequestedAt,
+		CompletedAt: data.CompletedAt,
+		ExpiresAt:   data.ExpiresAt,
+	}
+	if data.Error != nil {
+		res.Error = *data.Error
+	}
+	return
+}
+
+func ParseFromExports(data []Export) (res []ExportResponse) {
+	res = make([]ExportResponse, 0)
+	for _, object := range data {
+		res = append(res, ParseFromExport(object))
+	}
+	return
+}
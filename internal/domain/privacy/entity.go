@@ -0,0 +1,40 @@
+package privacy
+
+import "time"
+
+const (
+	// ModeDelete removes the account with all of its data. ModeAnonymize
+	// keeps the account row, so tasks others rely on survive, but strips
+	// every personal detail from it.
+	ModeDelete    = "delete"
+	ModeAnonymize = "anonymize"
+)
+
+const (
+	ExportPending = "pending"
+	ExportRunning = "running"
+	ExportReady   = "ready"
+	ExportFailed  = "failed"
+)
+
+// Deletion is a scheduled account deletion. Until ScheduledFor passes the
+// user can cancel it by logging in.
+type Deletion struct {
+	UserID       string    `db:"user_id"`
+	Mode         string    `db:"mode"`
+	RequestedAt  time.Time `db:"requested_at"`
+	ScheduledFor time.Time `db:"scheduled_for"`
+}
+
+// Export is a request for an archive of all data of a user. The archive is
+// built in the background and kept until ExpiresAt.
+type Export struct {
+	ID          string     `db:"id"`
+	UserID      string     `db:"user_id"`
+	Status      string     `db:"status"`
+	Error       *string    `db:"error"`
+	Size        *int       `db:"size"`
+	RequestedAt time.Time  `db:"requested_at"`
+	CompletedAt *time.Time `db:"completed_at"`
+	ExpiresAt   *time.Time `db:"expires_at"`
+}
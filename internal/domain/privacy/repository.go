@@ -0,0 +1,21 @@
+package privacy
+
+import (
+	"context"
+	"time"
+)
+
+type Repository interface {
+	GetDeletion(ctx context.Context, userID string) (dest Deletion, err error)
+	ScheduleDeletion(ctx context.Context, data Deletion) (dest Deletion, err error)
+	CancelDeletion(ctx context.Context, userID string) (err error)
+	DueDeletions(ctx context.Context, now time.Time) (dest []Deletion, err error)
+
+	AddExport(ctx context.Context, userID string) (dest Export, err error)
+	ListExports(ctx context.Context, userID string) (dest []Export, err error)
+	GetArchive(ctx context.Context, userID string, id string) (archive []byte, err error)
+	ClaimExport(ctx context.Context, staleAfter time.Duration) (dest Export, err error)
+	CompleteExport(ctx context.Context, id string, archive []byte, expiresAt time.Time) (err error)
+	FailExport(ctx context.Context, id string, reason string) (err error)
+	DeleteExpiredExports(ctx context.Context, now time.Time) (err error)
+}
@@ -0,0 +1,33 @@
+package session
+
+import "time"
+
+type Response struct {
+	ID         string    `json:"id"`
+	UserAgent  string    `json:"user_agent"`
+	IP         string    `json:"ip"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+	Current    bool      `json:"current"`
+}
+
+// ParseFromEntity converts a session; currentID is the session of the
+// request, which is flagged as current.
+func ParseFromEntity(data Entity, currentID string) Response {
+	return Response{
+		ID:         data.ID,
+		UserAgent:  data.UserAgent,
+		IP:         data.IP,
+		CreatedAt:  data.CreatedAt,
+		LastSeenAt: data.LastSeenAt,
+		Current:    data.ID == currentID,
+	}
+}
+
+func ParseFromEntities(data []Entity, currentID string) (res []Response) {
+	res = make([]Response, 0)
+	for _, object := range data {
+		res = append(res, ParseFromEntity(object, currentID))
+	}
+	return
+}
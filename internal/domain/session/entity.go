@@ -0,0 +1,22 @@
+package session
+
+import "time"
+
+// Entity is a login on one device. It lives as long as the refresh token
+// family it was created with.
+type Entity struct {
+	ID         string     `db:"id"`
+	UserID     string     `db:"user_id"`
+	FamilyID   string     `db:"family_id"`
+	UserAgent  string     `db:"user_agent"`
+	IP         string     `db:"ip"`
+	CreatedAt  time.Time  `db:"created_at"`
+	LastSeenAt time.Time  `db:"last_seen_at"`
+	RevokedAt  *time.Time `db:"revoked_at"`
+}
+
+// Client describes the device a request came from.
+type Client struct {
+	UserAgent string
+	IP        string
+}
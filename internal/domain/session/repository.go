@@ -0,0 +1,13 @@
+package session
+
+import "context"
+
+type Repository interface {
+	// List returns the sessions of the user that can still be refreshed.
+	List(ctx context.Context, userID string) (dest []Entity, err error)
+	// Save creates the session of a refresh token family, or records new
+	// activity on it when it exists.
+	Save(ctx context.Context, data Entity) (id string, err error)
+	Get(ctx context.Context, id string) (dest Entity, err error)
+	Touch(ctx context.Context, id string, client Client) (err error)
+}
@@ -3,13 +3,19 @@ package task
 import (
 	"errors"
 	"github.com/yrss1/todo/pkg/helpers"
+	"time"
 )
 
 type Request struct {
-	UserID      *string `json:"user_id"`
-	Title       *string `json:"title"`
-	Description *string `json:"description"`
-	Status      *string `json:"status"`
+	UserID           *string    `json:"user_id"`
+	WorkspaceID      *string    `json:"-"`
+	Title            *string    `json:"title"`
+	Description      *string    `json:"description"`
+	Status           *string    `json:"status"`
+	StoryPoints      *int       `json:"story_points"`
+	EstimatedMinutes *int       `json:"estimated_minutes"`
+	DueDate          *time.Time `json:"due_date"`
+	Tags             []string   `json:"tags"`
 }
 
 func (s *Request) Validate() error {
@@ -29,17 +35,21 @@ func (s *Request) Validate() error {
 		return errors.New("status must be either 'active' or 'done'")
 	}
 
-	return nil
+	return s.validateEstimates()
 }
 
 func (s *Request) IsEmpty(check string) error {
 	if check == "update" {
-		if s.UserID == nil && s.Title == nil && s.Description == nil && s.Status == nil {
+		if s.UserID == nil && s.Title == nil && s.Description == nil && s.Status == nil &&
+			s.StoryPoints == nil && s.EstimatedMinutes == nil && s.DueDate == nil && s.Tags == nil {
 			return errors.New("data cannot be blank")
 		}
 		if s.Status != nil && (*s.Status != "active" && *s.Status != "done") {
 			return errors.New("status must be either 'active' or 'done'")
 		}
+		if err := s.validateEstimates(); err != nil {
+			return err
+		}
 	}
 
 	if check == "search" {
@@ -51,18 +61,41 @@ func (s *Request) IsEmpty(check string) error {
 	return nil
 }
 
+func (s *Request) validateEstimates() error {
+	if s.StoryPoints != nil && *s.StoryPoints < 0 {
+		return errors.New("story_points: cannot be negative")
+	}
+
+	if s.EstimatedMinutes != nil && *s.EstimatedMinutes < 0 {
+		return errors.New("estimated_minutes: cannot be negative")
+	}
+
+	return nil
+}
+
 type Response struct {
-	ID          string `json:"id"`
-	Title       string `json:"title"`
-	Description string `json:"description"`
-	Status      string `json:"status"`
+	ID               string     `json:"id"`
+	Title            string     `json:"title"`
+	Description      string     `json:"description"`
+	Status           string     `json:"status"`
+	StoryPoints      *int       `json:"story_points,omitempty"`
+	EstimatedMinutes *int       `json:"estimated_minutes,omitempty"`
+	DueDate          *time.Time `json:"due_date,omitempty"`
+	CompletedAt      *time.Time `json:"completed_at,omitempty"`
+	Tags             []string   `json:"tags"`
 }
 
 func ParseFromEntity(data Entity) (res Response) {
 	res = Response{
-		ID:    data.ID,
-		Title: *data.Title,
+		ID:               data.ID,
+		Title:            *data.Title,
+		StoryPoints:      data.StoryPoints,
+		EstimatedMinutes: data.EstimatedMinutes,
+		DueDate:          data.DueDate,
+		CompletedAt:      data.CompletedAt,
+		Tags:             make([]string, 0, len(data.Tags)),
 	}
+	res.Tags = append(res.Tags, data.Tags...)
 	if data.Description != nil {
 		res.Description = *data.Description
 	}
@@ -79,3 +112,85 @@ func ParseFromEntities(data []Entity) (res []Response) {
 	}
 	return
 }
+
+type Rollup struct {
+	Key              string `json:"key,omitempty"`
+	Tasks            int    `json:"tasks"`
+	Estimated        int    `json:"estimated"`
+	StoryPoints      int    `json:"story_points"`
+	EstimatedMinutes int    `json:"estimated_minutes"`
+}
+
+type RollupResponse struct {
+	Total    Rollup   `json:"total"`
+	ByStatus []Rollup `json:"by_status"`
+}
+
+func ParseFromRollupEntity(data RollupEntity) Rollup {
+	return Rollup{
+		Key:              data.Key,
+		Tasks:            data.Tasks,
+		Estimated:        data.Estimated,
+		StoryPoints:      data.StoryPoints,
+		EstimatedMinutes: data.EstimatedMinutes,
+	}
+}
+
+func ParseFromRollupEntities(data []RollupEntity) (res RollupResponse) {
+	res.ByStatus = make([]Rollup, 0)
+	for _, object := range data {
+		rollup := ParseFromRollupEntity(object)
+		res.ByStatus = append(res.ByStatus, rollup)
+
+		res.Total.Tasks += rollup.Tasks
+		res.Total.Estimated += rollup.Estimated
+		res.Total.StoryPoints += rollup.StoryPoints
+		res.Total.EstimatedMinutes += rollup.EstimatedMinutes
+	}
+	return
+}
+
+type StatusCount struct {
+	Status string `json:"status"`
+	Tasks  int    `json:"tasks"`
+}
+
+type PeriodCount struct {
+	Period string `json:"period"`
+	Tasks  int    `json:"tasks"`
+}
+
+type StatsResponse struct {
+	ByStatus                 []StatusCount `json:"by_status"`
+	Completed                []PeriodCount `json:"completed"`
+	AverageCompletionSeconds *float64      `json:"average_completion_seconds"`
+	Overdue                  int           `json:"overdue"`
+}
+
+func ParseFromStatsEntity(data StatsEntity) (res StatsResponse) {
+	res = StatsResponse{
+		ByStatus:                 make([]StatusCount, 0),
+		Completed:                make([]PeriodCount, 0),
+		AverageCompletionSeconds: data.AverageCompletion,
+		Overdue:                  data.Overdue,
+	}
+	for _, object := range data.ByStatus {
+		res.ByStatus = append(res.ByStatus, StatusCount{Status: object.Status, Tasks: object.Tasks})
+	}
+	for _, object := range data.Completed {
+		res.Completed = append(res.Completed, PeriodCount{Period: object.Period.Format(time.DateOnly), Tasks: object.Tasks})
+	}
+	return
+}
+
+type ImportRowError struct {
+	Row   int    `json:"row"`
+	Error string `json:"error"`
+}
+
+type ImportResponse struct {
+	DryRun   bool             `json:"dry_run"`
+	Total    int              `json:"total"`
+	Imported int              `json:"imported"`
+	Errors   []ImportRowError `json:"errors"`
+}
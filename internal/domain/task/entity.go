@@ -1,9 +1,45 @@
 package task
 
+import (
+	"github.com/lib/pq"
+	"time"
+)
+
 type Entity struct {
-	ID          string  `db:"id"`
-	UserID      *string `db:"user_id"`
-	Title       *string `db:"title"`
-	Description *string `db:"description"`
-	Status      *string `db:"status"`
+	ID               string         `db:"id"`
+	UserID           *string        `db:"user_id"`
+	WorkspaceID      *string        `db:"workspace_id"`
+	Title            *string        `db:"title"`
+	Description      *string        `db:"description"`
+	Status           *string        `db:"status"`
+	StoryPoints      *int           `db:"story_points"`
+	EstimatedMinutes *int           `db:"estimated_minutes"`
+	DueDate          *time.Time     `db:"due_date"`
+	CompletedAt      *time.Time     `db:"completed_at"`
+	Tags             pq.StringArray `db:"tags"`
+}
+
+type RollupEntity struct {
+	Key              string `db:"key"`
+	Tasks            int    `db:"tasks"`
+	Estimated        int    `db:"estimated"`
+	StoryPoints      int    `db:"story_points"`
+	EstimatedMinutes int    `db:"estimated_minutes"`
+}
+
+type StatusCountEntity struct {
+	Status string `db:"status"`
+	Tasks  int    `db:"tasks"`
+}
+
+type PeriodCountEntity struct {
+	Period time.Time `db:"period"`
+	Tasks  int       `db:"tasks"`
+}
+
+type StatsEntity struct {
+	ByStatus          []StatusCountEntity
+	Completed         []PeriodCountEntity
+	AverageCompletion *float64
+	Overdue           int
 }
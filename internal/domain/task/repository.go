@@ -1,11 +1,18 @@
 package task
 
-import "context"
+import (
+	"context"
+	"time"
+)
 
 type Repository interface {
-	List(ctx context.Context, userID, titleFilter, statusFilter, sortBy, sortOrder string, page, limit int) (dest []Entity, err error)
+	List(ctx context.Context, userID, workspaceID, titleFilter, statusFilter, sortBy, sortOrder string, page, limit int) (dest []Entity, err error)
 	Add(ctx context.Context, data Entity) (id string, err error)
-	Get(ctx context.Context, userID string, taskID string) (dest Entity, err error)
-	Update(ctx context.Context, userID string, taskID string, dest Entity) (err error)
-	Delete(ctx context.Context, userID string, taskID string) (err error)
+	AddMany(ctx context.Context, data []Entity) (ids []string, err error)
+	Get(ctx context.Context, userID, workspaceID string, taskID string) (dest Entity, err error)
+	Update(ctx context.Context, userID, workspaceID string, taskID string, dest Entity) (err error)
+	Delete(ctx context.Context, userID, workspaceID string, taskID string) (err error)
+	Stream(ctx context.Context, userID, workspaceID, titleFilter, statusFilter, sortBy, sortOrder string, fn func(Entity) error) (err error)
+	Rollup(ctx context.Context, userID, workspaceID string) (dest []RollupEntity, err error)
+	Stats(ctx context.Context, userID, workspaceID string, from, to time.Time, interval, timeZone string) (dest StatsEntity, err error)
 }
@@ -0,0 +1,99 @@
+package template
+
+import (
+	"errors"
+	"github.com/yrss1/todo/pkg/helpers"
+)
+
+type Request struct {
+	UserID      *string  `json:"user_id"`
+	WorkspaceID *string  `json:"-"`
+	Name        *string  `json:"name"`
+	Title       *string  `json:"title"`
+	Description *string  `json:"description"`
+	Status      *string  `json:"status"`
+	Checklist   []string `json:"checklist"`
+	Tags        []string `json:"tags"`
+}
+
+func (s *Request) Validate() error {
+	if s.UserID == nil {
+		return errors.New("user_id: cannot be blank")
+	}
+
+	if s.Name == nil {
+		return errors.New("name: cannot be blank")
+	}
+
+	if s.Title == nil {
+		return errors.New("title: cannot be blank")
+	}
+
+	if s.Status == nil {
+		s.Status = helpers.GetStringPtr("active")
+	}
+
+	if s.Status != nil && (*s.Status != "active" && *s.Status != "done") {
+		return errors.New("status must be either 'active' or 'done'")
+	}
+
+	return nil
+}
+
+func (s *Request) IsEmpty(check string) error {
+	if check == "update" {
+		if s.Name == nil && s.Title == nil && s.Description == nil && s.Status == nil &&
+			s.Checklist == nil && s.Tags == nil {
+			return errors.New("data cannot be blank")
+		}
+		if s.Status != nil && (*s.Status != "active" && *s.Status != "done") {
+			return errors.New("status must be either 'active' or 'done'")
+		}
+	}
+
+	return nil
+}
+
+// InstantiateRequest describes the tasks to create from a template. Each
+// entry of Instances holds the variables for one task; an empty list creates
+// a single task with only the built-in variables.
+type InstantiateRequest struct {
+	Instances []map[string]string `json:"instances"`
+}
+
+type Response struct {
+	ID          string   `json:"id"`
+	Name        string   `json:"name"`
+	Title       string   `json:"title"`
+	Description string   `json:"description"`
+	Status      string   `json:"status"`
+	Checklist   []string `json:"checklist"`
+	Tags        []string `json:"tags"`
+}
+
+func ParseFromEntity(data Entity) (res Response) {
+	res = Response{
+		ID:        data.ID,
+		Name:      *data.Name,
+		Title:     *data.Title,
+		Checklist: make([]string, 0, len(data.Checklist)),
+		Tags:      make([]string, 0, len(data.Tags)),
+	}
+	if data.Description != nil {
+		res.Description = *data.Description
+	}
+	if data.Status != nil {
+		res.Status = *data.Status
+	}
+	res.Checklist = append(res.Checklist, data.Checklist...)
+	res.Tags = append(res.Tags, data.Tags...)
+	return
+}
+
+func ParseFromEntities(data []Entity) (res []Response) {
+	res = make([]Response, 0)
+	for _, object := range data {
+		res = append(res, ParseFromEntity(object))
+	}
+	return
+}
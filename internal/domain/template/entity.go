@@ -0,0 +1,15 @@
+package template
+
+import "github.com/lib/pq"
+
+type Entity struct {
+	ID          string         `db:"id"`
+	UserID      *string        `db:"user_id"`
+	WorkspaceID *string        `db:"workspace_id"`
+	Name        *string        `db:"name"`
+	Title       *string        `db:"title"`
+	Description *string        `db:"description"`
+	Status      *string        `db:"status"`
+	Checklist   pq.StringArray `db:"checklist"`
+	Tags        pq.StringArray `db:"tags"`
+}
@@ -0,0 +1,11 @@
+package template
+
+import "context"
+
+type Repository interface {
+	List(ctx context.Context, userID, workspaceID string) (dest []Entity, err error)
+	Add(ctx context.Context, data Entity) (id string, err error)
+	Get(ctx context.Context, userID, workspaceID string, templateID string) (dest Entity, err error)
+	Update(ctx context.Context, userID, workspaceID string, templateID string, data Entity) (err error)
+	Delete(ctx context.Context, userID, workspaceID string, templateID string) (err error)
+}
@@ -0,0 +1,17 @@
+package token
+
+import (
+	"context"
+	"time"
+)
+
+type Repository interface {
+	Add(ctx context.Context, data Entity) (id string, err error)
+	GetByHash(ctx context.Context, tokenHash string) (dest Entity, err error)
+	MarkUsed(ctx context.Context, id string) (err error)
+	RevokeFamily(ctx context.Context, familyID string) (err error)
+	RevokeByUser(ctx context.Context, userID string) (err error)
+	RevokeOtherFamilies(ctx context.Context, userID, familyID string) (err error)
+	RevokeAccess(ctx context.Context, jti string, expiresAt time.Time) (err error)
+	IsAccessRevoked(ctx context.Context, jti string) (revoked bool, err error)
+}
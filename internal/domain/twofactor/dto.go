@@ -0,0 +1,43 @@
+package twofactor
+
+import "errors"
+
+type CodeRequest struct {
+	Code *string `json:"code"`
+}
+
+func (s *CodeRequest) Validate() error {
+	if s.Code == nil || *s.Code == "" {
+		return errors.New("code: cannot be blank")
+	}
+
+	return nil
+}
+
+// ChallengeRequest completes a login that requires a second factor. Code is
+// either a TOTP code or one of the recovery codes.
+type ChallengeRequest struct {
+	ChallengeToken *string `json:"challenge_token"`
+	Code           *string `json:"code"`
+}
+
+func (s *ChallengeRequest) Validate() error {
+	if s.ChallengeToken == nil || *s.ChallengeToken == "" {
+		return errors.New("challenge_token: cannot be blank")
+	}
+
+	if s.Code == nil || *s.Code == "" {
+		return errors.New("code: cannot be blank")
+	}
+
+	return nil
+}
+
+type EnrollmentResponse struct {
+	Secret string `json:"secret"`
+	URI    string `json:"otpauth_uri"`
+}
+
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
@@ -0,0 +1,16 @@
+package twofactor
+
+import "time"
+
+// Entity is the TOTP enrollment of a user. It only protects logins once
+// ConfirmedAt is set.
+type Entity struct {
+	UserID       string     `db:"user_id"`
+	Secret       string     `db:"secret"`
+	ConfirmedAt  *time.Time `db:"confirmed_at"`
+	LastUsedStep int64      `db:"last_used_step"`
+}
+
+func (e Entity) Enabled() bool {
+	return e.ConfirmedAt != nil
+}
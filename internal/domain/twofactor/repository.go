@@ -0,0 +1,14 @@
+package twofactor
+
+import "context"
+
+type Repository interface {
+	Get(ctx context.Context, userID string) (dest Entity, err error)
+	Save(ctx context.Context, data Entity) (err error)
+	Confirm(ctx context.Context, userID string) (err error)
+	UseStep(ctx context.Context, userID string, step int64) (err error)
+	Delete(ctx context.Context, userID string) (err error)
+
+	ReplaceRecoveryCodes(ctx context.Context, userID string, codeHashes []string) (err error)
+	UseRecoveryCode(ctx context.Context, userID string, codeHash string) (err error)
+}
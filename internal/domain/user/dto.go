@@ -2,12 +2,18 @@ package user
 
 import (
 	"errors"
+	"golang.org/x/text/language"
+	"slices"
+	"time"
 )
 
+var ErrEmailTaken = errors.New("email address is already in use")
+
 type Request struct {
 	Name     *string `json:"name"`
 	Email    *string `json:"email"`
 	Password *string `json:"password"`
+	Role     *string `json:"role"`
 }
 
 func (s *Request) Validate() error {
@@ -18,6 +24,21 @@ func (s *Request) Validate() error {
 	if s.Email == nil {
 		return errors.New("email: cannot be blank")
 	}
+	s.normalizeEmail()
+
+	if s.Password == nil {
+		return errors.New("password: cannot be blank")
+	}
+
+	return s.validateRole()
+}
+
+// ValidateLogin checks the fields a login needs.
+func (s *Request) ValidateLogin() error {
+	if s.Email == nil {
+		return errors.New("email: cannot be blank")
+	}
+	s.normalizeEmail()
 
 	if s.Password == nil {
 		return errors.New("password: cannot be blank")
@@ -28,24 +49,185 @@ func (s *Request) Validate() error {
 
 func (s *Request) IsEmpty(check string) error {
 	if check == "update" {
-		if s.Name == nil && s.Email == nil && s.Password == nil {
+		if s.Name == nil && s.Email == nil && s.Password == nil && s.Role == nil {
 			return errors.New("data cannot be blank")
 		}
+		s.normalizeEmail()
+		if err := s.validateRole(); err != nil {
+			return err
+		}
 	}
 
-	if check == "search" {
-		if s.Name == nil && s.Email == nil {
-			return errors.New("invalid query")
+	return nil
+}
+
+func (s *Request) normalizeEmail() {
+	if s.Email != nil {
+		email := NormalizeEmail(*s.Email)
+		s.Email = &email
+	}
+}
+
+func (s *Request) validateRole() error {
+	if s.Role != nil && *s.Role != RoleUser && *s.Role != RoleAdmin {
+		return errors.New("role must be either 'user' or 'admin'")
+	}
+
+	return nil
+}
+
+const (
+	DefaultSearchLimit = 20
+	MaxSearchLimit     = 100
+)
+
+// SearchRequest selects a page of the user directory. Query matches the name
+// or the email, Name and Email match only that field; all three are
+// case-insensitive substrings. Results are ranked by relevance to Query
+// unless SortBy names a field. WorkspaceID limits the results to members of
+// that workspace.
+type SearchRequest struct {
+	Query       string
+	Name        string
+	Email       string
+	Role        string
+	WorkspaceID string
+
+	SortBy    string
+	SortOrder string
+
+	Page  int
+	Limit int
+}
+
+// Validate fills in the defaults and rejects unknown sort options.
+func (s *SearchRequest) Validate() error {
+	switch s.SortBy {
+	case "":
+		s.SortBy = "relevance"
+	case "relevance", "name", "email", "created_at":
+	default:
+		return errors.New("sortBy must be one of relevance, name, email, created_at")
+	}
+
+	switch s.SortOrder {
+	case "":
+		s.SortOrder = "asc"
+	case "asc", "desc":
+	default:
+		return errors.New("sortOrder must be either 'asc' or 'desc'")
+	}
+
+	if s.Role != "" && s.Role != RoleUser && s.Role != RoleAdmin {
+		return errors.New("role must be either 'user' or 'admin'")
+	}
+
+	if s.Page < 1 {
+		s.Page = 1
+	}
+	if s.Limit < 1 {
+		s.Limit = DefaultSearchLimit
+	}
+	if s.Limit > MaxSearchLimit {
+		s.Limit = MaxSearchLimit
+	}
+
+	return nil
+}
+
+type PasswordRequest struct {
+	OldPassword *string `json:"old_password"`
+	NewPassword *string `json:"new_password"`
+}
+
+func (s *PasswordRequest) Validate() error {
+	if s.OldPassword == nil {
+		return errors.New("old_password: cannot be blank")
+	}
+
+	if s.NewPassword == nil {
+		return errors.New("new_password: cannot be blank")
+	}
+
+	return nil
+}
+
+// SettingsRequest changes the display settings of the current user. Fields
+// left out keep their value.
+type SettingsRequest struct {
+	TimeZone        *string `json:"time_zone"`
+	Locale          *string `json:"locale"`
+	DateFormat      *string `json:"date_format"`
+	DefaultTaskView *string `json:"default_task_view"`
+}
+
+func (s *SettingsRequest) Validate() error {
+	if s.TimeZone == nil && s.Locale == nil && s.DateFormat == nil && s.DefaultTaskView == nil {
+		return errors.New("data cannot be blank")
+	}
+
+	if s.TimeZone != nil {
+		// "Local" would mean the zone of the server, not of the user.
+		if _, err := time.LoadLocation(*s.TimeZone); err != nil || *s.TimeZone == "" || *s.TimeZone == "Local" {
+			return errors.New("time_zone: must be an IANA time zone such as 'Europe/Berlin'")
 		}
 	}
 
+	if s.Locale != nil {
+		tag, err := language.Parse(*s.Locale)
+		if err != nil {
+			return errors.New("locale: must be a language tag such as 'en-US'")
+		}
+		locale := tag.String()
+		s.Locale = &locale
+	}
+
+	if s.DateFormat != nil && !slices.Contains(DateFormats, *s.DateFormat) {
+		return errors.New("date_format: must be one of YYYY-MM-DD, DD.MM.YYYY, DD/MM/YYYY, MM/DD/YYYY")
+	}
+
+	if s.DefaultTaskView != nil && !slices.Contains(TaskViews, *s.DefaultTaskView) {
+		return errors.New("default_task_view: must be one of list, board, calendar")
+	}
+
 	return nil
 }
 
+type SettingsResponse struct {
+	TimeZone        string `json:"time_zone"`
+	Locale          string `json:"locale"`
+	DateFormat      string `json:"date_format"`
+	DefaultTaskView string `json:"default_task_view"`
+
+	// AvatarUpdatedAt is set when the user has an avatar at /me/avatar.
+	AvatarUpdatedAt *time.Time `json:"avatar_updated_at,omitempty"`
+}
+
+func ParseSettingsFromEntity(data Entity) (res SettingsResponse) {
+	if data.TimeZone != nil {
+		res.TimeZone = *data.TimeZone
+	}
+	if data.Locale != nil {
+		res.Locale = *data.Locale
+	}
+	if data.DateFormat != nil {
+		res.DateFormat = *data.DateFormat
+	}
+	if data.DefaultTaskView != nil {
+		res.DefaultTaskView = *data.DefaultTaskView
+	}
+	return
+}
+
 type Response struct {
 	ID    string `json:"id"`
 	Name  string `json:"name"`
 	Email string `json:"email"`
+	Role  string `json:"role,omitempty"`
+
+	// PendingEmail is the new address of an email change until it is
+	// confirmed.
+	PendingEmail string `json:"pending_email,omitempty"`
 }
 
 func ParseFromEntity(data Entity) (res Response) {
@@ -54,6 +236,12 @@ func ParseFromEntity(data Entity) (res Response) {
 		Name:  *data.Name,
 		Email: *data.Email,
 	}
+	if data.Role != nil {
+		res.Role = *data.Role
+	}
+	if data.PendingEmail != nil {
+		res.PendingEmail = *data.PendingEmail
+	}
 	return
 }
 
@@ -64,3 +252,22 @@ func ParseFromEntities(data []Entity) (res []Response) {
 	}
 	return
 }
+
+// DirectoryResponse is what other members of a workspace see of a user.
+type DirectoryResponse struct {
+	ID    string `json:"id"`
+	Name  string `json:"name"`
+	Email string `json:"email"`
+}
+
+func ParseFromDirectoryEntities(data []Entity) (res []DirectoryResponse) {
+	res = make([]DirectoryResponse, 0)
+	for _, object := range data {
+		res = append(res, DirectoryResponse{
+			ID:    object.ID,
+			Name:  *object.Name,
+			Email: *object.Email,
+		})
+	}
+	return
+}
@@ -1,8 +1,63 @@
 package user
 
+import (
+	"strings"
+	"time"
+)
+
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+)
+
+// DateFormats and TaskViews list the accepted display settings. The first
+// entry of each is the default.
+var (
+	DateFormats = []string{"YYYY-MM-DD", "DD.MM.YYYY", "DD/MM/YYYY", "MM/DD/YYYY"}
+	TaskViews   = []string{"list", "board", "calendar"}
+)
+
 type Entity struct {
 	ID       string  `db:"id"`
 	Name     *string `db:"name"`
 	Email    *string `db:"email"`
 	Password *string `db:"password"`
+	Role     *string `db:"role"`
+
+	PendingEmail           *string    `db:"pending_email"`
+	EmailVerifiedAt        *time.Time `db:"email_verified_at"`
+	PasswordChangeRequired *bool      `db:"password_change_required"`
+
+	TimeZone        *string `db:"time_zone"`
+	Locale          *string `db:"locale"`
+	DateFormat      *string `db:"date_format"`
+	DefaultTaskView *string `db:"default_task_view"`
+}
+
+// NormalizeEmail returns the form emails are stored and compared in.
+// Addresses are treated as case-insensitive throughout.
+func NormalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// Location returns the time zone of the user, or UTC when it is unset or
+// unknown.
+func (e Entity) Location() *time.Location {
+	if e.TimeZone == nil {
+		return time.UTC
+	}
+
+	loc, err := time.LoadLocation(*e.TimeZone)
+	if err != nil {
+		return time.UTC
+	}
+
+	return loc
+}
+
+type Avatar struct {
+	UserID      string    `db:"user_id"`
+	ContentType string    `db:"content_type"`
+	Data        []byte    `db:"data"`
+	UpdatedAt   time.Time `db:"updated_at"`
 }
@@ -8,6 +8,12 @@ type Repository interface {
 	Get(ctx context.Context, id string) (dest Entity, err error)
 	Update(ctx context.Context, id string, dest Entity) (err error)
 	Delete(ctx context.Context, id string) (err error)
-	Search(ctx context.Context, data Entity) (dest []Entity, err error)
+	Anonymize(ctx context.Context, id string) (err error)
+	ChangeEmail(ctx context.Context, id string, email string) (err error)
+	Search(ctx context.Context, req SearchRequest) (dest []Entity, total int, err error)
 	GetByEmail(ctx context.Context, id string) (dest Entity, err error)
+
+	GetAvatar(ctx context.Context, id string) (dest Avatar, err error)
+	SaveAvatar(ctx context.Context, data Avatar) (err error)
+	DeleteAvatar(ctx context.Context, id string) (err error)
 }
@@ -0,0 +1,63 @@
+package verification
+
+import "errors"
+
+type ForgotPasswordRequest struct {
+	Email *string `json:"email"`
+}
+
+func (s *ForgotPasswordRequest) Validate() error {
+	if s.Email == nil {
+		return errors.New("email: cannot be blank")
+	}
+
+	return nil
+}
+
+type ResetPasswordRequest struct {
+	Token    *string `json:"token"`
+	Password *string `json:"password"`
+}
+
+func (s *ResetPasswordRequest) Validate() error {
+	if s.Token == nil {
+		return errors.New("token: cannot be blank")
+	}
+
+	if s.Password == nil {
+		return errors.New("password: cannot be blank")
+	}
+
+	return nil
+}
+
+type VerifyEmailRequest struct {
+	Token *string `json:"token"`
+}
+
+func (s *VerifyEmailRequest) Validate() error {
+	if s.Token == nil {
+		return errors.New("token: cannot be blank")
+	}
+
+	return nil
+}
+
+// PasswordChangeRequest completes a login for an account that must choose a
+// new password first.
+type PasswordChangeRequest struct {
+	ChallengeToken *string `json:"challenge_token"`
+	Password       *string `json:"password"`
+}
+
+func (s *PasswordChangeRequest) Validate() error {
+	if s.ChallengeToken == nil || *s.ChallengeToken == "" {
+		return errors.New("challenge_token: cannot be blank")
+	}
+
+	if s.Password == nil {
+		return errors.New("password: cannot be blank")
+	}
+
+	return nil
+}
@@ -0,0 +1,21 @@
+package verification
+
+import "time"
+
+const (
+	PurposePasswordReset     = "password_reset"
+	PurposeEmailVerification = "email_verification"
+	PurposeTwoFactorLogin    = "two_factor_login"
+	PurposePasswordChange    = "password_change"
+	PurposeEmailChange       = "email_change"
+)
+
+type Entity struct {
+	ID        string     `db:"id"`
+	UserID    string     `db:"user_id"`
+	Purpose   string     `db:"purpose"`
+	TokenHash string     `db:"token_hash"`
+	ExpiresAt time.Time  `db:"expires_at"`
+	UsedAt    *time.Time `db:"used_at"`
+	Attempts  int        `db:"attempts"`
+}
@@ -0,0 +1,11 @@
+package verification
+
+import "context"
+
+type Repository interface {
+	Add(ctx context.Context, data Entity) (id string, err error)
+	Get(ctx context.Context, purpose string, tokenHash string) (dest Entity, err error)
+	Consume(ctx context.Context, purpose string, tokenHash string) (dest Entity, err error)
+	Attempt(ctx context.Context, purpose string, tokenHash string, maxAttempts int) (dest Entity, err error)
+	Invalidate(ctx context.Context, userID string, purpose string) (err error)
+}
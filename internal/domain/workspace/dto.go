@@ -0,0 +1,191 @@
+package workspace
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+type Request struct {
+	Name *string `json:"name"`
+}
+
+func (s *Request) Validate() error {
+	if s.Name == nil || strings.TrimSpace(*s.Name) == "" {
+		return errors.New("name: cannot be blank")
+	}
+
+	if len(*s.Name) > 100 {
+		return errors.New("name: must be at most 100 characters")
+	}
+
+	return nil
+}
+
+type MemberRequest struct {
+	Email *string `json:"email"`
+	Role  *string `json:"role"`
+}
+
+// Validate checks a new member. The role defaults to member.
+func (s *MemberRequest) Validate() error {
+	if s.Email == nil {
+		return errors.New("email: cannot be blank")
+	}
+
+	if s.Role == nil {
+		role := RoleMember
+		s.Role = &role
+	}
+
+	return validateRole(*s.Role)
+}
+
+type RoleRequest struct {
+	Role *string `json:"role"`
+}
+
+func (s *RoleRequest) Validate() error {
+	if s.Role == nil {
+		return errors.New("role: cannot be blank")
+	}
+
+	return validateRole(*s.Role)
+}
+
+func validateRole(role string) error {
+	if !slices.Contains(Roles, role) {
+		return fmt.Errorf("role: must be one of %s", strings.Join(Roles, ", "))
+	}
+
+	return nil
+}
+
+type InvitationRequest struct {
+	Email *string `json:"email"`
+	Role  *string `json:"role"`
+}
+
+// Validate checks an invitation. The role defaults to member.
+func (s *InvitationRequest) Validate() error {
+	if s.Email == nil || !strings.Contains(*s.Email, "@") {
+		return errors.New("email: must be an email address")
+	}
+
+	if s.Role == nil {
+		role := RoleMember
+		s.Role = &role
+	}
+
+	return validateRole(*s.Role)
+}
+
+// AcceptInvitationRequest accepts an invitation with the token from its link.
+// Name and password are only needed when no account exists for the invited
+// address yet; an existing account accepts while logged in.
+type AcceptInvitationRequest struct {
+	Token    *string `json:"token"`
+	Name     *string `json:"name"`
+	Password *string `json:"password"`
+}
+
+func (s *AcceptInvitationRequest) Validate() error {
+	if s.Token == nil {
+		return errors.New("token: cannot be blank")
+	}
+
+	return nil
+}
+
+type Response struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	Role      string    `json:"role"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func ParseFromEntity(data Entity) Response {
+	return Response{
+		ID:        data.ID,
+		Name:      data.Name,
+		Role:      data.Role,
+		CreatedAt: data.CreatedAt,
+	}
+}
+
+func ParseFromEntities(data []Entity) (res []Response) {
+	res = make([]Response, 0)
+	for _, object := range data {
+		res = append(res, ParseFromEntity(object))
+	}
+	return
+}
+
+type MemberResponse struct {
+	UserID    string    `json:"user_id"`
+	Name      string    `json:"name"`
+	Email     string    `json:"email"`
+	Role      string    `json:"role"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func ParseFromMembers(data []Member) (res []MemberResponse) {
+	res = make([]MemberResponse, 0)
+	for _, object := range data {
+		res = append(res, MemberResponse{
+			UserID:    object.UserID,
+			Name:      object.Name,
+			Email:     object.Email,
+			Role:      object.Role,
+			CreatedAt: object.CreatedAt,
+		})
+	}
+	return
+}
+
+type InvitationResponse struct {
+	ID        string    `json:"id"`
+	Email     string    `json:"email"`
+	Role      string    `json:"role"`
+	Expired   bool      `json:"expired"`
+	CreatedAt time.Time `json:"created_at"`
+	SentAt    time.Time `json:"sent_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+func ParseFromInvitation(data Invitation) InvitationResponse {
+	return InvitationResponse{
+		ID:        data.ID,
+		Email:     data.Email,
+		Role:      data.Role,
+		Expired:   data.Expired(),
+		CreatedAt: data.CreatedAt,
+		SentAt:    data.SentAt,
+		ExpiresAt: data.ExpiresAt,
+	}
+}
+
+func ParseFromInvitations(data []Invitation) (res []InvitationResponse) {
+	res = make([]InvitationResponse, 0)
+	for _, object := range data {
+		res = append(res, ParseFromInvitation(object))
+	}
+	return
+}
+
+// InvitationPreviewResponse describes an invitation to the person holding
+// its token. It does not tell whether the address has an account.
+type InvitationPreviewResponse struct {
+	WorkspaceName string    `json:"workspace_name"`
+	Email         string    `json:"email"`
+	Role          string    `json:"role"`
+	ExpiresAt     time.Time `json:"expires_at"`
+}
+
+type AcceptInvitationResponse struct {
+	UserID         string   `json:"user_id"`
+	AccountCreated bool     `json:"account_created"`
+	Workspace      Response `json:"workspace"`
+}
@@ -0,0 +1,63 @@
+package workspace
+
+import "time"
+
+// Header selects the active workspace of a request. Requests without it use
+// the default workspace of the user.
+const Header = "X-Workspace-ID"
+
+const (
+	RoleOwner  = "owner"
+	RoleAdmin  = "admin"
+	RoleMember = "member"
+)
+
+var Roles = []string{RoleOwner, RoleAdmin, RoleMember}
+
+type Entity struct {
+	ID        string    `db:"id"`
+	Name      string    `db:"name"`
+	CreatedAt time.Time `db:"created_at"`
+
+	// Role is the role of the user the workspace was read for.
+	Role string `db:"role"`
+}
+
+type Member struct {
+	WorkspaceID string    `db:"workspace_id"`
+	UserID      string    `db:"user_id"`
+	Role        string    `db:"role"`
+	CreatedAt   time.Time `db:"created_at"`
+
+	Name  string `db:"name"`
+	Email string `db:"email"`
+}
+
+// Invitation asks someone to join a workspace with a preset role. Only the
+// hash of its token is stored.
+type Invitation struct {
+	ID          string     `db:"id"`
+	WorkspaceID string     `db:"workspace_id"`
+	Email       string     `db:"email"`
+	Role        string     `db:"role"`
+	TokenHash   string     `db:"token_hash"`
+	InvitedBy   *string    `db:"invited_by"`
+	CreatedAt   time.Time  `db:"created_at"`
+	SentAt      time.Time  `db:"sent_at"`
+	ExpiresAt   time.Time  `db:"expires_at"`
+	AcceptedAt  *time.Time `db:"accepted_at"`
+	RevokedAt   *time.Time `db:"revoked_at"`
+
+	// WorkspaceName is only read with GetInvitationByHash.
+	WorkspaceName string `db:"workspace_name"`
+}
+
+func (e Invitation) Expired() bool {
+	return !time.Now().Before(e.ExpiresAt)
+}
+
+// CanManage reports whether the role may rename the workspace and manage
+// its members.
+func CanManage(role string) bool {
+	return role == RoleOwner || role == RoleAdmin
+}
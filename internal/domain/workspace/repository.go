@@ -0,0 +1,40 @@
+package workspace
+
+import (
+	"context"
+	"time"
+)
+
+type Repository interface {
+	List(ctx context.Context, userID string) (dest []Entity, err error)
+	// Add creates the workspace with ownerID as its owner.
+	Add(ctx context.Context, name, ownerID string) (dest Entity, err error)
+	// AddPersonal returns the personal workspace of ownerID, creating it
+	// first if there is none. Each user has at most one.
+	AddPersonal(ctx context.Context, name, ownerID string) (dest Entity, err error)
+	Get(ctx context.Context, id, userID string) (dest Entity, err error)
+	Update(ctx context.Context, id, name string) (err error)
+	Delete(ctx context.Context, id string) (err error)
+
+	ListMembers(ctx context.Context, id string) (dest []Member, err error)
+	GetMember(ctx context.Context, id, userID string) (dest Member, err error)
+	AddMember(ctx context.Context, id, userID, role string) (err error)
+	UpdateMember(ctx context.Context, id, userID, role string) (err error)
+	DeleteMember(ctx context.Context, id, userID string) (err error)
+	CountOwners(ctx context.Context, id string) (count int, err error)
+
+	// ListInvitations returns the invitations that were neither accepted nor
+	// revoked, including expired ones.
+	ListInvitations(ctx context.Context, id string) (dest []Invitation, err error)
+	// AddInvitation revokes any open invitation of the same address to the
+	// workspace and stores the new one.
+	AddInvitation(ctx context.Context, data Invitation) (dest Invitation, err error)
+	GetInvitation(ctx context.Context, id, invitationID string) (dest Invitation, err error)
+	// GetInvitationByHash returns an open invitation that has not expired.
+	GetInvitationByHash(ctx context.Context, tokenHash string) (dest Invitation, err error)
+	RenewInvitation(ctx context.Context, id, invitationID, tokenHash string, expiresAt time.Time) (dest Invitation, err error)
+	RevokeInvitation(ctx context.Context, id, invitationID string) (err error)
+	// AcceptInvitation marks the invitation accepted and adds userID with its
+	// role. Users who are already members keep their role.
+	AcceptInvitation(ctx context.Context, invitationID, userID string) (err error)
+}
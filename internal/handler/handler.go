@@ -7,10 +7,15 @@ import (
 	ginSwagger "github.com/swaggo/gin-swagger"
 	"github.com/yrss1/todo/docs"
 	"github.com/yrss1/todo/internal/config"
+	"github.com/yrss1/todo/internal/domain/accesstoken"
+	"github.com/yrss1/todo/internal/domain/user"
 	"github.com/yrss1/todo/internal/handler/http"
 	"github.com/yrss1/todo/internal/service/account"
+	"github.com/yrss1/todo/internal/service/audit"
 	"github.com/yrss1/todo/internal/service/auth"
+	"github.com/yrss1/todo/internal/service/privacy"
 	"github.com/yrss1/todo/internal/service/todo"
+	"github.com/yrss1/todo/internal/service/workspace"
 	"github.com/yrss1/todo/pkg/server/response"
 	"github.com/yrss1/todo/pkg/server/router"
 )
@@ -21,6 +26,10 @@ type Dependencies struct {
 	AccountService *account.Service
 	TodoService    *todo.Service
 	AuthService    *auth.Service
+	PrivacyService *privacy.Service
+
+	WorkspaceService *workspace.Service
+	AuditService     *audit.Service
 }
 type Handler struct {
 	dependencies Dependencies
@@ -55,7 +64,14 @@ func New(d Dependencies, configs ...Configuration) (h *Handler, err error) {
 func WithHTTPHandler() Configuration {
 	return func(h *Handler) (err error) {
 		h.HTTP = router.New()
-		h.HTTP.Use(timeout.New(
+		if err = h.HTTP.SetTrustedProxies(h.dependencies.Configs.APP.TrustedProxies); err != nil {
+			return
+		}
+		h.HTTP.Use(http.SourceMiddleware())
+
+		// The timeout buffers the whole response until the handler returns,
+		// so it is added to every group except the streamed downloads.
+		withTimeout := timeout.New(
 			timeout.WithTimeout(h.dependencies.Configs.APP.Timeout),
 			timeout.WithHandler(func(ctx *gin.Context) {
 				ctx.Next()
@@ -63,29 +79,71 @@ func WithHTTPHandler() Configuration {
 			timeout.WithResponse(func(ctx *gin.Context) {
 				response.StatusRequestTimeout(ctx)
 			}),
-		))
+		)
 
 		docs.SwaggerInfo.BasePath = h.dependencies.Configs.APP.Path
-		h.HTTP.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
+		h.HTTP.GET("/swagger/*any", withTimeout, ginSwagger.WrapHandler(swaggerFiles.Handler))
 
-		authHandler := http.NewAuthHandler(h.dependencies.AuthService, h.dependencies.Configs.APP.JWT)
+		authHandler := http.NewAuthHandler(h.dependencies.AuthService)
 		healthHandler := http.NewHealthHandler()
+		workspaceHandler := http.NewWorkspaceHandler(h.dependencies.WorkspaceService)
+
+		authHandler.WellKnownRoutes(h.HTTP.Group("", withTimeout))
 
-		authAPI := h.HTTP.Group(h.dependencies.Configs.APP.Path)
+		authAPI := h.HTTP.Group(h.dependencies.Configs.APP.Path, withTimeout)
 		{
 			authHandler.Routes(authAPI)
 			healthHandler.Routes(authAPI)
+			workspaceHandler.InvitationRoutes(authAPI, authHandler.OptionalAuthMiddleware(), authHandler.RequireScope("", ""), authHandler.RejectImpersonation())
 		}
 
 		userHandler := http.NewUserHandler(h.dependencies.AccountService)
+		auditHandler := http.NewAuditHandler(h.dependencies.AuditService)
+		profileHandler := http.NewProfileHandler(h.dependencies.AccountService)
+		privacyHandler := http.NewPrivacyHandler(h.dependencies.PrivacyService)
 		taskHandler := http.NewTaskHandler(h.dependencies.TodoService)
+		templateHandler := http.NewTemplateHandler(h.dependencies.TodoService)
+		statsHandler := http.NewStatsHandler(h.dependencies.TodoService)
 
 		api := h.HTTP.Group(h.dependencies.Configs.APP.Path)
 		{
 			api.Use(authHandler.AuthMiddleware())
 
-			userHandler.Routes(api)
-			taskHandler.Routes(api)
+			// Downloads written while the rows are read can take longer than
+			// the request timeout.
+			stream := api.Group("")
+			limited := api.Group("", withTimeout)
+
+			// Personal access tokens only reach the routes their scopes cover.
+			account := limited.Group("", authHandler.RequireScope("", ""))
+			profileHandler.Routes(account, authHandler.RejectImpersonation())
+			workspaceHandler.Routes(account)
+			userHandler.DirectoryRoutes(account.Group("", workspaceHandler.Middleware()))
+
+			// Impersonating admins cannot change how the account is secured.
+			owner := account.Group("", authHandler.RejectImpersonation())
+			privacyHandler.Routes(owner)
+			authHandler.TwoFactorRoutes(owner)
+			authHandler.AccessTokenRoutes(owner)
+			authHandler.SessionRoutes(owner)
+
+			users := limited.Group("", authHandler.RequireScope(accesstoken.ScopeUsersAdmin, accesstoken.ScopeUsersAdmin))
+			userHandler.Routes(users, authHandler.RequireRole(user.RoleAdmin))
+			auditHandler.Routes(users, authHandler.RequireRole(user.RoleAdmin))
+			authHandler.ImpersonationRoutes(users, authHandler.RequireRole(user.RoleAdmin))
+
+			// Tasks and templates live in the workspace chosen by the
+			// X-Workspace-ID header.
+			tasks := limited.Group("", authHandler.RequireScope(accesstoken.ScopeTasksRead, accesstoken.ScopeTasksWrite), workspaceHandler.Middleware())
+			taskHandler.Routes(tasks)
+			templateHandler.Routes(tasks)
+			statsHandler.Routes(tasks)
+
+			taskExports := stream.Group("", authHandler.RequireScope(accesstoken.ScopeTasksRead, accesstoken.ScopeTasksWrite), workspaceHandler.Middleware())
+			taskHandler.ExportRoutes(taskExports)
+
+			auditExports := stream.Group("", authHandler.RequireScope(accesstoken.ScopeUsersAdmin, accesstoken.ScopeUsersAdmin))
+			auditHandler.ExportRoutes(auditExports, authHandler.RequireRole(user.RoleAdmin))
 		}
 		return
 	}
@@ -0,0 +1,111 @@
+package http
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/yrss1/todo/internal/domain/accesstoken"
+	"github.com/yrss1/todo/internal/service/auth"
+	"github.com/yrss1/todo/pkg/server/response"
+	"github.com/yrss1/todo/pkg/store"
+)
+
+// AccessTokenRoutes registers the personal access token routes of the
+// authenticated user.
+func (h *AuthHandler) AccessTokenRoutes(r *gin.RouterGroup) {
+	api := r.Group("/me/tokens")
+	{
+		api.GET("/", h.listAccessTokens)
+		api.POST("/", h.createAccessToken)
+		api.DELETE("/:id", h.revokeAccessToken)
+	}
+}
+
+// listAccessTokens godoc
+// @Summary List personal access tokens
+// @Description List the personal access tokens of the current user that were not revoked
+// @Tags me
+// @Produce  json
+// @Security BearerAuth
+// @Success 200 {array} accesstoken.Response "Tokens"
+// @Failure 500 {object} response.Object "Internal Server Error"
+// @Router /me/tokens [get]
+func (h *AuthHandler) listAccessTokens(c *gin.Context) {
+	userID := c.Value("userID").(string)
+
+	res, err := h.authService.ListAccessTokens(c, userID)
+	if err != nil {
+		response.InternalServerError(c, err)
+		return
+	}
+
+	response.OK(c, res)
+}
+
+// createAccessToken godoc
+// @Summary Create a personal access token
+// @Description Create a token for scripts, sent as "Authorization: Bearer pat_...". Scopes are tasks:read, tasks:write and users:admin (admins only). The token is only shown in this response.
+// @Tags me
+// @Accept  json
+// @Produce  json
+// @Security BearerAuth
+// @Param request body accesstoken.Request true "Token name, scopes and optional expiry"
+// @Success 201 {object} accesstoken.CreateResponse "Created token"
+// @Failure 400 {object} response.Object "Bad Request"
+// @Failure 403 {object} response.Object "Scope not allowed"
+// @Failure 500 {object} response.Object "Internal Server Error"
+// @Router /me/tokens [post]
+func (h *AuthHandler) createAccessToken(c *gin.Context) {
+	userID := c.Value("userID").(string)
+	role := c.Value("role").(string)
+
+	req := accesstoken.Request{}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err, req)
+		return
+	}
+	if err := req.Validate(); err != nil {
+		response.BadRequest(c, err, req)
+		return
+	}
+
+	res, err := h.authService.CreateAccessToken(c, userID, role, req)
+	if err != nil {
+		switch {
+		case errors.Is(err, auth.ErrScopeNotAllowed):
+			response.Forbidden(c, err)
+		default:
+			response.InternalServerError(c, err)
+		}
+		return
+	}
+
+	response.Created(c, res)
+}
+
+// revokeAccessToken godoc
+// @Summary Revoke a personal access token
+// @Description Revoke a personal access token of the current user
+// @Tags me
+// @Produce  json
+// @Security BearerAuth
+// @Param id path string true "Token ID"
+// @Success 200 {string} string "Token revoked"
+// @Failure 404 {object} response.Object "Token not found"
+// @Failure 500 {object} response.Object "Internal Server Error"
+// @Router /me/tokens/{id} [delete]
+func (h *AuthHandler) revokeAccessToken(c *gin.Context) {
+	userID := c.Value("userID").(string)
+	id := c.Param("id")
+
+	if err := h.authService.RevokeAccessToken(c, userID, id); err != nil {
+		switch {
+		case errors.Is(err, store.ErrorNotFound):
+			response.NotFound(c, err)
+		default:
+			response.InternalServerError(c, err)
+		}
+		return
+	}
+
+	response.OK(c, "Token revoked")
+}
@@ -0,0 +1,160 @@
+package http
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/yrss1/todo/internal/domain/audit"
+	auditService "github.com/yrss1/todo/internal/service/audit"
+	"github.com/yrss1/todo/pkg/server/response"
+	"github.com/yrss1/todo/pkg/server/router"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type AuditHandler struct {
+	auditService *auditService.Service
+}
+
+func NewAuditHandler(s *auditService.Service) *AuditHandler {
+	return &AuditHandler{auditService: s}
+}
+
+func (h *AuditHandler) Routes(r *gin.RouterGroup, admin gin.HandlerFunc) {
+	api := r.Group("/audit", admin)
+	{
+		api.GET("/", h.list)
+	}
+}
+
+// ExportRoutes registers the CSV export, which is written while the events
+// are read and so has to be registered outside of the request timeout.
+func (h *AuditHandler) ExportRoutes(r *gin.RouterGroup, admin gin.HandlerFunc) {
+	api := r.Group("/audit", admin)
+	{
+		api.GET("/export", h.export)
+	}
+}
+
+// SourceMiddleware records where the request came from, so audit events
+// written while handling it carry its IP address, user agent and ID.
+func SourceMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		withSource(c, audit.Source{
+			IP:        c.ClientIP(),
+			UserAgent: c.Request.UserAgent(),
+			RequestID: router.RequestIDFromContext(c),
+		})
+		c.Next()
+	}
+}
+
+func withSource(c *gin.Context, src audit.Source) {
+	c.Request = c.Request.WithContext(audit.ContextWithSource(c.Request.Context(), src))
+}
+
+// list godoc
+// @Summary List audit events
+// @Description List logins, token refreshes, password and role changes and user deletions, newest first. The total number of matches is returned in the X-Total-Count header. Requires the admin role.
+// @Tags audit
+// @Produce  json
+// @Security BearerAuth
+// @Param action query string false "Action" Enums(login.succeeded, login.failed, token.refreshed, token.reused, password.changed, password.change_required, user.role_changed, user.deleted, impersonation.started, impersonation.request)
+// @Param actor_id query string false "User who acted"
+// @Param target_id query string false "User acted on"
+// @Param ip query string false "IP address of the request"
+// @Param from query string false "Start of the period (RFC 3339), inclusive"
+// @Param to query string false "End of the period (RFC 3339), exclusive"
+// @Param page query int false "Page number for pagination" default(1)
+// @Param limit query int false "Number of events per page, at most 500" default(50)
+// @Success 200 {array} audit.Response "Audit events"
+// @Header 200 {integer} X-Total-Count "Number of matches on all pages"
+// @Failure 400 {object} response.Object "Bad Request"
+// @Failure 403 {object} response.Object "Forbidden"
+// @Failure 500 {object} response.Object "Internal Server Error"
+// @Router /audit [get]
+func (h *AuditHandler) list(c *gin.Context) {
+	filter, err := parseAuditFilter(c)
+	if err != nil {
+		response.BadRequest(c, err, nil)
+		return
+	}
+
+	res, total, err := h.auditService.ListEvents(c, filter)
+	if err != nil {
+		response.InternalServerError(c, err)
+		return
+	}
+
+	c.Header("X-Total-Count", strconv.Itoa(total))
+	response.OK(c, res)
+}
+
+// export godoc
+// @Summary Export audit events
+// @Description Export every audit event matching the same filters as the list as CSV, newest first. Cells starting with =, +, -, @, tab or carriage return are prefixed with a quote. Requires the admin role.
+// @Tags audit
+// @Produce  text/csv
+// @Security BearerAuth
+// @Param action query string false "Action" Enums(login.succeeded, login.failed, token.refreshed, token.reused, password.changed, password.change_required, user.role_changed, user.deleted, impersonation.started, impersonation.request)
+// @Param actor_id query string false "User who acted"
+// @Param target_id query string false "User acted on"
+// @Param ip query string false "IP address of the request"
+// @Param from query string false "Start of the period (RFC 3339), inclusive"
+// @Param to query string false "End of the period (RFC 3339), exclusive"
+// @Success 200 {file} file "Audit events"
+// @Failure 400 {object} response.Object "Bad Request"
+// @Failure 403 {object} response.Object "Forbidden"
+// @Failure 500 {object} response.Object "Internal Server Error"
+// @Router /audit/export [get]
+func (h *AuditHandler) export(c *gin.Context) {
+	filter, err := parseAuditFilter(c)
+	if err != nil {
+		response.BadRequest(c, err, nil)
+		return
+	}
+
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", `attachment; filename="audit.csv"`)
+	c.Status(http.StatusOK)
+
+	if err := h.auditService.ExportEvents(c, c.Writer, filter); err != nil {
+		c.Error(err)
+	}
+}
+
+func parseAuditFilter(c *gin.Context) (filter audit.Filter, err error) {
+	filter = audit.Filter{
+		Action:   c.Query("action"),
+		ActorID:  strings.TrimSpace(c.Query("actor_id")),
+		TargetID: strings.TrimSpace(c.Query("target_id")),
+		IP:       strings.TrimSpace(c.Query("ip")),
+	}
+	filter.Page, _ = strconv.Atoi(c.Query("page"))
+	filter.Limit, _ = strconv.Atoi(c.Query("limit"))
+
+	if filter.From, err = parseTime(c.Query("from")); err != nil {
+		return filter, errors.New("from: must be an RFC 3339 time")
+	}
+	if filter.To, err = parseTime(c.Query("to")); err != nil {
+		return filter, errors.New("to: must be an RFC 3339 time")
+	}
+
+	err = filter.Validate()
+
+	return
+}
+
+func parseTime(s string) (*time.Time, error) {
+	if s == "" {
+		return nil, nil
+	}
+
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return nil, err
+	}
+
+	return &t, nil
+}
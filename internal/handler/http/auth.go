@@ -1,24 +1,57 @@
 package http
 
 import (
+	"errors"
 	"github.com/gin-gonic/gin"
+	"github.com/yrss1/todo/internal/domain/accesstoken"
+	"github.com/yrss1/todo/internal/domain/audit"
+	"github.com/yrss1/todo/internal/domain/session"
 	"github.com/yrss1/todo/internal/domain/user"
+	"github.com/yrss1/todo/internal/domain/verification"
 	"github.com/yrss1/todo/internal/service/auth"
+	"github.com/yrss1/todo/pkg/log"
+	"github.com/yrss1/todo/pkg/password"
 	"github.com/yrss1/todo/pkg/server/response"
+	"go.uber.org/zap"
+	"math"
 	"net/http"
+	"slices"
+	"strconv"
 	"strings"
 )
 
+type TokenResponse struct {
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int64  `json:"expires_in"`
+}
+
+// PasswordChangeChallengeResponse is returned by a login that has to set a
+// new password at /auth/login/password-change before getting tokens.
+type PasswordChangeChallengeResponse struct {
+	PasswordChangeRequired bool   `json:"password_change_required"`
+	ChallengeToken         string `json:"challenge_token"`
+	ExpiresIn              int64  `json:"expires_in"`
+}
+
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+func newTokenResponse(data auth.TokenPair) TokenResponse {
+	return TokenResponse{
+		Token:        "Bearer " + data.AccessToken,
+		RefreshToken: data.RefreshToken,
+		ExpiresIn:    int64(data.ExpiresIn.Seconds()),
+	}
+}
+
 type AuthHandler struct {
 	authService *auth.Service
-	JWTKey      []byte
 }
 
-func NewAuthHandler(s *auth.Service, jwtKey []byte) *AuthHandler {
-	return &AuthHandler{
-		authService: s,
-		JWTKey:      jwtKey,
-	}
+func NewAuthHandler(s *auth.Service) *AuthHandler {
+	return &AuthHandler{authService: s}
 }
 
 func (h *AuthHandler) Routes(r *gin.RouterGroup) {
@@ -26,6 +59,26 @@ func (h *AuthHandler) Routes(r *gin.RouterGroup) {
 	{
 		api.POST("/register", h.register)
 		api.POST("/login", h.login)
+		api.POST("/login/2fa", h.loginTwoFactor)
+		api.POST("/login/password-change", h.loginPasswordChange)
+		api.GET("/oidc/login", h.oidcLogin)
+		api.GET("/oidc/callback", h.oidcCallback)
+		api.POST("/refresh", h.refresh)
+		api.POST("/logout", h.AuthMiddleware(), h.RequireScope("", ""), h.logout)
+
+		api.POST("/forgot-password", h.forgotPassword)
+		api.POST("/reset-password", h.resetPassword)
+		api.POST("/verify-email", h.verifyEmail)
+		api.POST("/verify-email/resend", h.resendVerification)
+	}
+}
+
+// WellKnownRoutes registers the discovery documents served outside of the
+// API base path.
+func (h *AuthHandler) WellKnownRoutes(r *gin.RouterGroup) {
+	api := r.Group("/.well-known")
+	{
+		api.GET("/jwks.json", h.jwks)
 	}
 }
 
@@ -38,6 +91,7 @@ func (h *AuthHandler) Routes(r *gin.RouterGroup) {
 // @Param user body user.Request true "User registration data"
 // @Success 200 {object} user.Response "User registered successfully"
 // @Failure 400 {object} response.Object "Bad Request"
+// @Failure 409 {object} response.Object "Email address is already in use"
 // @Failure 500 {object} response.Object "Internal Server Error"
 // @Router /auth/register [post]
 func (h *AuthHandler) register(c *gin.Context) {
@@ -53,7 +107,14 @@ func (h *AuthHandler) register(c *gin.Context) {
 
 	res, err := h.authService.Register(c, req)
 	if err != nil {
-		response.InternalServerError(c, err)
+		switch {
+		case errors.Is(err, password.ErrWeakPassword):
+			response.BadRequest(c, err, nil)
+		case errors.Is(err, user.ErrEmailTaken):
+			response.Conflict(c, err)
+		default:
+			response.InternalServerError(c, err)
+		}
 		return
 	}
 
@@ -62,35 +123,207 @@ func (h *AuthHandler) register(c *gin.Context) {
 
 // login godoc
 // @Summary Login a user
-// @Description Login a user and receive JWT token
+// @Description Login a user and receive a short-lived JWT access token and a refresh token. Users with two-factor authentication get a challenge token instead, to be completed at /auth/login/2fa. Users who must change their password get a challenge token for /auth/login/password-change.
 // @Tags auth
 // @Accept  json
 // @Produce  json
 // @Param user body user.Request true "User login data"
-// @Success 200 {object} map[string]string "JWT token"
-// @Failure 400 {object} response.Object "Bad Request"
+// @Success 200 {object} TokenResponse "Access and refresh tokens"
+// @Failure 400 {object} response.Object "Bad Request or invalid email or password"
+// @Failure 403 {object} response.Object "Email address is not verified"
+// @Failure 429 {object} response.Object "Too many failed attempts, see the Retry-After header"
 // @Failure 500 {object} response.Object "Internal Server Error"
 // @Router /auth/login [post]
 func (h *AuthHandler) login(c *gin.Context) {
 	req := user.Request{}
 	if err := c.ShouldBindJSON(&req); err != nil {
-		response.BadRequest(c, err, req)
+		response.BadRequest(c, err, nil)
+		return
+	}
+	if err := req.ValidateLogin(); err != nil {
+		response.BadRequest(c, err, nil)
 		return
 	}
 
-	id, err := h.authService.ValidateUser(c, req)
+	id, err := h.authService.ValidateUser(c, req, c.ClientIP())
 	if err != nil {
-		response.BadRequest(c, err, req)
+		var locked *auth.LockedError
+		switch {
+		case errors.As(err, &locked):
+			tooManyAttempts(c, locked)
+		case errors.Is(err, auth.ErrInvalidCredentials):
+			response.BadRequest(c, err, nil)
+		case errors.Is(err, auth.ErrEmailNotVerified):
+			response.Forbidden(c, err)
+		default:
+			response.InternalServerError(c, err)
+		}
+		return
+	}
+
+	h.completeLogin(c, id)
+}
+
+// completeLogin responds with a two-factor challenge when the user has a
+// second factor, and continues with finishLogin otherwise.
+func (h *AuthHandler) completeLogin(c *gin.Context, id string) {
+	challenge, err := h.authService.TwoFactorChallenge(c, id)
+	if err != nil {
+		response.InternalServerError(c, err)
+		return
+	}
+	if challenge != "" {
+		response.OK(c, TwoFactorChallengeResponse{
+			TwoFactorRequired: true,
+			ChallengeToken:    challenge,
+			ExpiresIn:         int64(auth.TwoFactorChallengeTTL.Seconds()),
+		})
+		return
+	}
+
+	h.finishLogin(c, id)
+}
+
+// finishLogin responds with a password change challenge when the user has
+// to choose a new password, and with new tokens otherwise.
+func (h *AuthHandler) finishLogin(c *gin.Context, id string) {
+	challenge, err := h.authService.PasswordChangeChallenge(c, id)
+	if err != nil {
+		response.InternalServerError(c, err)
+		return
+	}
+	if challenge != "" {
+		response.OK(c, PasswordChangeChallengeResponse{
+			PasswordChangeRequired: true,
+			ChallengeToken:         challenge,
+			ExpiresIn:              int64(auth.PasswordChangeChallengeTTL.Seconds()),
+		})
+		return
+	}
+
+	res, err := h.authService.IssueTokens(c, id, clientOf(c))
+	if err != nil {
+		response.InternalServerError(c, err)
+		return
+	}
+
+	response.OK(c, newTokenResponse(res))
+}
+
+// loginPasswordChange godoc
+// @Summary Complete a login with a new password
+// @Description Exchange the challenge token from /auth/login and a new password for access and refresh tokens. Used after an admin set a temporary password or required a change.
+// @Tags auth
+// @Accept  json
+// @Produce  json
+// @Param request body verification.PasswordChangeRequest true "Challenge token and new password"
+// @Success 200 {object} TokenResponse "Access and refresh tokens"
+// @Failure 400 {object} response.Object "Bad Request"
+// @Failure 500 {object} response.Object "Internal Server Error"
+// @Router /auth/login/password-change [post]
+func (h *AuthHandler) loginPasswordChange(c *gin.Context) {
+	req := verification.PasswordChangeRequest{}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err, nil)
+		return
+	}
+	if err := req.Validate(); err != nil {
+		response.BadRequest(c, err, nil)
+		return
+	}
+
+	id, err := h.authService.CompletePasswordChange(c, req)
+	if err != nil {
+		switch {
+		case errors.Is(err, auth.ErrInvalidVerificationToken), errors.Is(err, password.ErrWeakPassword):
+			response.BadRequest(c, err, nil)
+		default:
+			response.InternalServerError(c, err)
+		}
+		return
+	}
+
+	res, err := h.authService.IssueTokens(c, id, clientOf(c))
+	if err != nil {
+		response.InternalServerError(c, err)
+		return
+	}
+
+	response.OK(c, newTokenResponse(res))
+}
+
+// tooManyAttempts responds 429 with the time until the lockout ends.
+func tooManyAttempts(c *gin.Context, err *auth.LockedError) {
+	c.Header("Retry-After", strconv.Itoa(int(math.Ceil(err.RetryAfter.Seconds()))))
+	response.TooManyRequests(c, err)
+}
+
+// refresh godoc
+// @Summary Refresh tokens
+// @Description Exchange a refresh token for a new access token and a rotated refresh token. Reusing a refresh token revokes all tokens issued from the same login.
+// @Tags auth
+// @Accept  json
+// @Produce  json
+// @Param token body RefreshRequest true "Refresh token"
+// @Success 200 {object} TokenResponse "Access and refresh tokens"
+// @Failure 400 {object} response.Object "Bad Request"
+// @Failure 401 {object} response.Object "Invalid refresh token"
+// @Failure 500 {object} response.Object "Internal Server Error"
+// @Router /auth/refresh [post]
+func (h *AuthHandler) refresh(c *gin.Context) {
+	req := RefreshRequest{}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err, nil)
+		return
+	}
+	if req.RefreshToken == "" {
+		response.BadRequest(c, errors.New("refresh_token: cannot be blank"), nil)
 		return
 	}
 
-	res, err := h.authService.GenerateJWT(c, id, h.JWTKey)
+	res, err := h.authService.RefreshTokens(c, req.RefreshToken, clientOf(c))
 	if err != nil {
+		switch {
+		case errors.Is(err, auth.ErrInvalidRefreshToken), errors.Is(err, auth.ErrRefreshTokenReuse):
+			response.Unauthorized(c, err)
+		default:
+			response.InternalServerError(c, err)
+		}
+		return
+	}
+
+	response.OK(c, newTokenResponse(res))
+}
+
+// logout godoc
+// @Summary Logout
+// @Description Revoke the current access token, end its session and, when given, revoke the refresh token issued with it
+// @Tags auth
+// @Accept  json
+// @Produce  json
+// @Security BearerAuth
+// @Param token body RefreshRequest false "Refresh token"
+// @Success 200 {string} string "Logged out"
+// @Failure 401 {object} response.Object "Unauthorized"
+// @Failure 500 {object} response.Object "Internal Server Error"
+// @Router /auth/logout [post]
+func (h *AuthHandler) logout(c *gin.Context) {
+	claims := c.Value("claims").(*auth.Claims)
+
+	req := RefreshRequest{}
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			response.BadRequest(c, err, nil)
+			return
+		}
+	}
+
+	if err := h.authService.Logout(c, claims, req.RefreshToken); err != nil {
 		response.InternalServerError(c, err)
 		return
 	}
 
-	response.OK(c, map[string]string{"token": "Bearer " + res})
+	response.OK(c, "Logged out")
 }
 
 func (h *AuthHandler) AuthMiddleware() gin.HandlerFunc {
@@ -110,14 +343,250 @@ func (h *AuthHandler) AuthMiddleware() gin.HandlerFunc {
 
 		tokenString = strings.TrimPrefix(tokenString, "Bearer ")
 
-		claims, err := h.authService.ValidateJWT(c, tokenString, h.JWTKey)
+		var claims *auth.Claims
+		var err error
+		if strings.HasPrefix(tokenString, accesstoken.Prefix) {
+			claims, err = h.authService.ValidateAccessToken(c, tokenString)
+		} else {
+			claims, err = h.authService.ValidateJWT(c, tokenString)
+		}
 		if err != nil {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
 			c.Abort()
 			return
 		}
 
+		h.authService.TouchSession(c, claims.SessionID, clientOf(c))
+
+		src := audit.SourceFromContext(c)
+		src.ActorID = claims.UserID
+		if claims.ImpersonatorID != "" {
+			src.ActorID = claims.ImpersonatorID
+			src.OnBehalfOf = claims.UserID
+
+			c.Header(ImpersonationHeader, claims.ImpersonatorID)
+			logger := log.LoggerFromContext(c).With(zap.String("impersonatorID", claims.ImpersonatorID), zap.String("impersonatedUserID", claims.UserID))
+			c.Request = c.Request.WithContext(log.ContextWithLogger(c.Request.Context(), logger))
+		}
+		withSource(c, src)
+
 		c.Set("userID", claims.UserID)
+		c.Set("role", claims.Role)
+		c.Set("claims", claims)
 		c.Next()
+
+		if claims.ImpersonatorID != "" {
+			h.authService.ImpersonatedRequest(c, claims, c.Request.Method, c.FullPath(), c.Writer.Status())
+		}
+	}
+}
+
+// OptionalAuthMiddleware authenticates requests with an Authorization header
+// like AuthMiddleware and lets requests without one through anonymously.
+func (h *AuthHandler) OptionalAuthMiddleware() gin.HandlerFunc {
+	authenticate := h.AuthMiddleware()
+
+	return func(c *gin.Context) {
+		if c.GetHeader("Authorization") == "" {
+			c.Next()
+			return
+		}
+
+		authenticate(c)
+	}
+}
+
+// clientOf describes the device that sent the request.
+func clientOf(c *gin.Context) session.Client {
+	return session.Client{
+		UserAgent: c.Request.UserAgent(),
+		IP:        c.ClientIP(),
+	}
+}
+
+// RequireScope limits personal access tokens to routes covered by their
+// scopes: reads need the read scope, every other method the write scope. An
+// empty scope is never granted, so routes using RequireScope("", "") only
+// accept tokens from a login. Login tokens pass unchanged.
+func (h *AuthHandler) RequireScope(read, write string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claims, ok := c.Value("claims").(*auth.Claims)
+		if !ok || claims.Scopes == nil {
+			c.Next()
+			return
+		}
+
+		scope := write
+		if c.Request.Method == http.MethodGet || c.Request.Method == http.MethodHead {
+			scope = read
+		}
+
+		if scope == "" || !slices.Contains(claims.Scopes, scope) {
+			response.Forbidden(c, errors.New("access token lacks the required scope"))
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// forgotPassword godoc
+// @Summary Request a password reset
+// @Description Send a single-use password reset link to the email address. The response is the same whether or not the address is registered.
+// @Tags auth
+// @Accept  json
+// @Produce  json
+// @Param request body verification.ForgotPasswordRequest true "Account email"
+// @Success 200 {string} string "Password reset email sent"
+// @Failure 400 {object} response.Object "Bad Request"
+// @Failure 500 {object} response.Object "Internal Server Error"
+// @Router /auth/forgot-password [post]
+func (h *AuthHandler) forgotPassword(c *gin.Context) {
+	req := verification.ForgotPasswordRequest{}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err, nil)
+		return
+	}
+	if err := req.Validate(); err != nil {
+		response.BadRequest(c, err, nil)
+		return
+	}
+
+	if err := h.authService.ForgotPassword(c, *req.Email); err != nil {
+		response.InternalServerError(c, err)
+		return
+	}
+
+	response.OK(c, "Password reset email sent")
+}
+
+// resetPassword godoc
+// @Summary Reset the password
+// @Description Set a new password with the token from the password reset email. The token can be used once and all refresh tokens are revoked.
+// @Tags auth
+// @Accept  json
+// @Produce  json
+// @Param request body verification.ResetPasswordRequest true "Reset token and new password"
+// @Success 200 {string} string "Password changed"
+// @Failure 400 {object} response.Object "Bad Request"
+// @Failure 500 {object} response.Object "Internal Server Error"
+// @Router /auth/reset-password [post]
+func (h *AuthHandler) resetPassword(c *gin.Context) {
+	req := verification.ResetPasswordRequest{}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err, nil)
+		return
+	}
+	if err := req.Validate(); err != nil {
+		response.BadRequest(c, err, nil)
+		return
+	}
+
+	if err := h.authService.ResetPassword(c, req); err != nil {
+		switch {
+		case errors.Is(err, auth.ErrInvalidVerificationToken), errors.Is(err, password.ErrWeakPassword):
+			response.BadRequest(c, err, nil)
+		default:
+			response.InternalServerError(c, err)
+		}
+		return
+	}
+
+	response.OK(c, "Password changed")
+}
+
+// verifyEmail godoc
+// @Summary Verify the email address
+// @Description Confirm the email address with the token from the verification email
+// @Tags auth
+// @Accept  json
+// @Produce  json
+// @Param request body verification.VerifyEmailRequest true "Verification token"
+// @Success 200 {string} string "Email verified"
+// @Failure 400 {object} response.Object "Bad Request"
+// @Failure 500 {object} response.Object "Internal Server Error"
+// @Router /auth/verify-email [post]
+func (h *AuthHandler) verifyEmail(c *gin.Context) {
+	req := verification.VerifyEmailRequest{}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err, nil)
+		return
+	}
+	if err := req.Validate(); err != nil {
+		response.BadRequest(c, err, nil)
+		return
+	}
+
+	if err := h.authService.VerifyEmail(c, *req.Token); err != nil {
+		switch {
+		case errors.Is(err, auth.ErrInvalidVerificationToken):
+			response.BadRequest(c, err, nil)
+		default:
+			response.InternalServerError(c, err)
+		}
+		return
+	}
+
+	response.OK(c, "Email verified")
+}
+
+// resendVerification godoc
+// @Summary Resend the verification email
+// @Description Send a new verification link if the account exists and is not verified yet. The response does not reveal whether the address is registered.
+// @Tags auth
+// @Accept  json
+// @Produce  json
+// @Param request body verification.ForgotPasswordRequest true "Account email"
+// @Success 200 {string} string "Verification email sent"
+// @Failure 400 {object} response.Object "Bad Request"
+// @Failure 500 {object} response.Object "Internal Server Error"
+// @Router /auth/verify-email/resend [post]
+func (h *AuthHandler) resendVerification(c *gin.Context) {
+	req := verification.ForgotPasswordRequest{}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err, nil)
+		return
+	}
+	if err := req.Validate(); err != nil {
+		response.BadRequest(c, err, nil)
+		return
+	}
+
+	if err := h.authService.ResendEmailVerification(c, *req.Email); err != nil {
+		response.InternalServerError(c, err)
+		return
+	}
+
+	response.OK(c, "Verification email sent")
+}
+
+// jwks godoc
+// @Summary JSON Web Key Set
+// @Description Public keys for verifying access tokens offline, identified by the kid token header
+// @Tags auth
+// @Produce  json
+// @Success 200 {object} auth.JWKS "Public signing keys"
+// @Router /.well-known/jwks.json [get]
+func (h *AuthHandler) jwks(c *gin.Context) {
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, h.authService.JWKS())
+}
+
+// RequireRole lets the request through only when the authenticated user has
+// one of the given roles. It must run after AuthMiddleware.
+func (h *AuthHandler) RequireRole(roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claims := c.Value("claims").(*auth.Claims)
+
+		for _, role := range roles {
+			if claims.Role == role {
+				c.Next()
+				return
+			}
+		}
+
+		response.Forbidden(c, errors.New("insufficient permissions"))
+		c.Abort()
 	}
 }
@@ -0,0 +1,95 @@
+package http
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/yrss1/todo/internal/service/auth"
+	"github.com/yrss1/todo/pkg/server/response"
+	"github.com/yrss1/todo/pkg/store"
+)
+
+// ImpersonationHeader is set on every response to a request made with an
+// impersonation token and holds the ID of the admin behind it, so clients
+// can show a banner.
+const ImpersonationHeader = "X-Impersonated-By"
+
+type ImpersonationRequest struct {
+	Reason string `json:"reason"`
+}
+
+type ImpersonationResponse struct {
+	Token     string `json:"token"`
+	ExpiresIn int64  `json:"expires_in"`
+}
+
+// ImpersonationRoutes registers the route admins use to act as another
+// user.
+func (h *AuthHandler) ImpersonationRoutes(r *gin.RouterGroup, admin gin.HandlerFunc) {
+	api := r.Group("/users", admin)
+	{
+		api.POST("/:id/impersonate", h.impersonate)
+	}
+}
+
+// RejectImpersonation keeps impersonation tokens away from routes that
+// change how the account is secured or remove it.
+func (h *AuthHandler) RejectImpersonation() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claims, ok := c.Value("claims").(*auth.Claims)
+		if ok && claims.ImpersonatorID != "" {
+			response.Forbidden(c, errors.New("not allowed while impersonating"))
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// impersonate godoc
+// @Summary Impersonate a user
+// @Description Get a short-lived access token that acts as the user, to reproduce a problem they report. The token cannot be refreshed and cannot edit the profile, password, two-factor settings, tokens or sessions of the user, or delete the account. Responses to its requests carry the X-Impersonated-By header, and every request is written to the audit log under the admin's ID. Requires the admin role and a login token; admins cannot be impersonated.
+// @Tags users
+// @Accept  json
+// @Produce  json
+// @Security BearerAuth
+// @Param id path string true "User ID"
+// @Param request body ImpersonationRequest false "Reason, kept in the audit log"
+// @Success 200 {object} ImpersonationResponse "Impersonation token"
+// @Failure 400 {object} response.Object "Bad Request"
+// @Failure 403 {object} response.Object "Forbidden"
+// @Failure 404 {object} response.Object "User not found"
+// @Failure 500 {object} response.Object "Internal Server Error"
+// @Router /users/{id}/impersonate [post]
+func (h *AuthHandler) impersonate(c *gin.Context) {
+	claims := c.Value("claims").(*auth.Claims)
+	id := c.Param("id")
+
+	req := ImpersonationRequest{}
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			response.BadRequest(c, err, nil)
+			return
+		}
+	}
+
+	res, err := h.authService.Impersonate(c, claims, id, req.Reason)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrorNotFound):
+			response.NotFound(c, err)
+		case errors.Is(err, auth.ErrImpersonationNotAllowed), errors.Is(err, auth.ErrImpersonateAdmin):
+			response.Forbidden(c, err)
+		case errors.Is(err, auth.ErrImpersonateSelf):
+			response.BadRequest(c, err, nil)
+		default:
+			response.InternalServerError(c, err)
+		}
+		return
+	}
+
+	response.OK(c, ImpersonationResponse{
+		Token:     "Bearer " + res.AccessToken,
+		ExpiresIn: int64(res.ExpiresIn.Seconds()),
+	})
+}
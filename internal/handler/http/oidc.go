@@ -0,0 +1,82 @@
+package http
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/yrss1/todo/internal/service/auth"
+	"github.com/yrss1/todo/pkg/oidc"
+	"github.com/yrss1/todo/pkg/server/response"
+	"net/http"
+)
+
+// oidcLogin godoc
+// @Summary Start a single sign-on login
+// @Description Redirect to the configured OpenID Connect provider. The provider sends the user back to /auth/oidc/callback.
+// @Tags auth
+// @Success 302 {string} string "Redirect to the identity provider"
+// @Failure 404 {object} response.Object "Single sign-on is not configured"
+// @Failure 500 {object} response.Object "Internal Server Error"
+// @Router /auth/oidc/login [get]
+func (h *AuthHandler) oidcLogin(c *gin.Context) {
+	link, err := h.authService.OIDCLoginURL(c)
+	if err != nil {
+		switch {
+		case errors.Is(err, auth.ErrOIDCDisabled):
+			response.NotFound(c, err)
+		default:
+			response.InternalServerError(c, err)
+		}
+		return
+	}
+
+	c.Redirect(http.StatusFound, link)
+}
+
+// oidcCallback godoc
+// @Summary Complete a single sign-on login
+// @Description Exchange the authorization code from the identity provider for access and refresh tokens, or a two-factor challenge
+// @Tags auth
+// @Produce  json
+// @Param code query string true "Authorization code"
+// @Param state query string true "State from /auth/oidc/login"
+// @Success 200 {object} TokenResponse "Access and refresh tokens"
+// @Failure 400 {object} response.Object "Bad Request"
+// @Failure 401 {object} response.Object "Invalid ID token"
+// @Failure 403 {object} response.Object "No matching account, or the matching account is not verified"
+// @Failure 404 {object} response.Object "Single sign-on is not configured"
+// @Failure 500 {object} response.Object "Internal Server Error"
+// @Router /auth/oidc/callback [get]
+func (h *AuthHandler) oidcCallback(c *gin.Context) {
+	if msg := c.Query("error"); msg != "" {
+		if desc := c.Query("error_description"); desc != "" {
+			msg += ": " + desc
+		}
+		response.BadRequest(c, errors.New(msg), nil)
+		return
+	}
+
+	state, code := c.Query("state"), c.Query("code")
+	if state == "" || code == "" {
+		response.BadRequest(c, errors.New("state and code are required"), nil)
+		return
+	}
+
+	id, err := h.authService.OIDCCallback(c, state, code)
+	if err != nil {
+		switch {
+		case errors.Is(err, auth.ErrOIDCDisabled):
+			response.NotFound(c, err)
+		case errors.Is(err, auth.ErrInvalidOIDCState), errors.Is(err, auth.ErrOIDCEmailRequired):
+			response.BadRequest(c, err, nil)
+		case errors.Is(err, oidc.ErrInvalidIDToken):
+			response.Unauthorized(c, err)
+		case errors.Is(err, auth.ErrOIDCNoAccount), errors.Is(err, auth.ErrOIDCUnverifiedAccount):
+			response.Forbidden(c, err)
+		default:
+			response.InternalServerError(c, err)
+		}
+		return
+	}
+
+	h.completeLogin(c, id)
+}
@@ -0,0 +1,204 @@
+package http
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/yrss1/todo/internal/domain/privacy"
+	"github.com/yrss1/todo/internal/service/credentials"
+	privacyService "github.com/yrss1/todo/internal/service/privacy"
+	"github.com/yrss1/todo/pkg/server/response"
+	"github.com/yrss1/todo/pkg/store"
+	"net/http"
+)
+
+type PrivacyHandler struct {
+	privacyService *privacyService.Service
+}
+
+func NewPrivacyHandler(s *privacyService.Service) *PrivacyHandler {
+	return &PrivacyHandler{privacyService: s}
+}
+
+func (h *PrivacyHandler) Routes(r *gin.RouterGroup) {
+	api := r.Group("/me")
+	{
+		api.DELETE("/", h.scheduleDeletion)
+
+		api.GET("/deletion", h.getDeletion)
+		api.POST("/deletion", h.scheduleDeletion)
+		api.DELETE("/deletion", h.cancelDeletion)
+
+		api.GET("/exports", h.listExports)
+		api.POST("/exports", h.requestExport)
+		api.GET("/exports/:id/download", h.downloadExport)
+	}
+}
+
+// scheduleDeletion godoc
+// @Summary Schedule account deletion
+// @Description Delete the authenticated user after a grace period, or anonymize the account to keep tasks but remove all personal data. The password confirms the request; logging in and cancelling stops it.
+// @Tags me
+// @Accept  json
+// @Produce  json
+// @Security BearerAuth
+// @Param request body privacy.DeletionRequest true "Password and mode"
+// @Success 200 {object} privacy.DeletionResponse "Scheduled deletion"
+// @Failure 400 {object} response.Object "Bad Request"
+// @Failure 404 {object} response.Object "User not found"
+// @Failure 500 {object} response.Object "Internal Server Error"
+// @Router /me/deletion [post]
+// @Router /me [delete]
+func (h *PrivacyHandler) scheduleDeletion(c *gin.Context) {
+	userID := c.Value("userID").(string)
+	req := privacy.DeletionRequest{}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err, nil)
+		return
+	}
+
+	if err := req.Validate(); err != nil {
+		response.BadRequest(c, err, nil)
+		return
+	}
+
+	res, err := h.privacyService.ScheduleDeletion(c, userID, req)
+	if err != nil {
+		switch {
+		case errors.Is(err, credentials.ErrInvalidPassword):
+			response.BadRequest(c, err, nil)
+		case errors.Is(err, store.ErrorNotFound):
+			response.NotFound(c, err)
+		default:
+			response.InternalServerError(c, err)
+		}
+		return
+	}
+
+	response.OK(c, res)
+}
+
+// getDeletion godoc
+// @Summary Get the scheduled deletion
+// @Description Get when the authenticated user's account is going to be deleted
+// @Tags me
+// @Produce  json
+// @Security BearerAuth
+// @Success 200 {object} privacy.DeletionResponse "Scheduled deletion"
+// @Failure 404 {object} response.Object "No deletion scheduled"
+// @Failure 500 {object} response.Object "Internal Server Error"
+// @Router /me/deletion [get]
+func (h *PrivacyHandler) getDeletion(c *gin.Context) {
+	userID := c.Value("userID").(string)
+
+	res, err := h.privacyService.GetDeletion(c, userID)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrorNotFound):
+			response.NotFound(c, err)
+		default:
+			response.InternalServerError(c, err)
+		}
+		return
+	}
+
+	response.OK(c, res)
+}
+
+// cancelDeletion godoc
+// @Summary Cancel the scheduled deletion
+// @Description Keep the authenticated user's account
+// @Tags me
+// @Produce  json
+// @Security BearerAuth
+// @Success 200 {string} string "ok"
+// @Failure 404 {object} response.Object "No deletion scheduled"
+// @Failure 500 {object} response.Object "Internal Server Error"
+// @Router /me/deletion [delete]
+func (h *PrivacyHandler) cancelDeletion(c *gin.Context) {
+	userID := c.Value("userID").(string)
+
+	if err := h.privacyService.CancelDeletion(c, userID); err != nil {
+		switch {
+		case errors.Is(err, store.ErrorNotFound):
+			response.NotFound(c, err)
+		default:
+			response.InternalServerError(c, err)
+		}
+		return
+	}
+
+	response.OK(c, "ok")
+}
+
+// listExports godoc
+// @Summary List data exports
+// @Description List the data exports of the authenticated user, newest first
+// @Tags me
+// @Produce  json
+// @Security BearerAuth
+// @Success 200 {array} privacy.ExportResponse "Exports"
+// @Failure 500 {object} response.Object "Internal Server Error"
+// @Router /me/exports [get]
+func (h *PrivacyHandler) listExports(c *gin.Context) {
+	userID := c.Value("userID").(string)
+
+	res, err := h.privacyService.ListExports(c, userID)
+	if err != nil {
+		response.InternalServerError(c, err)
+		return
+	}
+
+	response.OK(c, res)
+}
+
+// requestExport godoc
+// @Summary Request a data export
+// @Description Queue a zip archive of all data of the authenticated user. The archive is built in the background; poll /me/exports until it is ready.
+// @Tags me
+// @Produce  json
+// @Security BearerAuth
+// @Success 200 {object} privacy.ExportResponse "Queued export"
+// @Failure 500 {object} response.Object "Internal Server Error"
+// @Router /me/exports [post]
+func (h *PrivacyHandler) requestExport(c *gin.Context) {
+	userID := c.Value("userID").(string)
+
+	res, err := h.privacyService.RequestExport(c, userID)
+	if err != nil {
+		response.InternalServerError(c, err)
+		return
+	}
+
+	response.OK(c, res)
+}
+
+// downloadExport godoc
+// @Summary Download a data export
+// @Description Download the zip archive of a finished export
+// @Tags me
+// @Produce  application/zip
+// @Security BearerAuth
+// @Param id path string true "Export ID"
+// @Success 200 {file} file "Zip archive"
+// @Failure 404 {object} response.Object "Export not found, not ready or expired"
+// @Failure 500 {object} response.Object "Internal Server Error"
+// @Router /me/exports/{id}/download [get]
+func (h *PrivacyHandler) downloadExport(c *gin.Context) {
+	userID := c.Value("userID").(string)
+	id := c.Param("id")
+
+	archive, err := h.privacyService.GetArchive(c, userID, id)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrorNotFound):
+			response.NotFound(c, err)
+		default:
+			response.InternalServerError(c, err)
+		}
+		return
+	}
+
+	c.Header("Content-Disposition", `attachment; filename="todo-export-`+id+`.zip"`)
+	c.Data(http.StatusOK, "application/zip", archive)
+}
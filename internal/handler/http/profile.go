@@ -0,0 +1,210 @@
+package http
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/yrss1/todo/internal/domain/user"
+	"github.com/yrss1/todo/internal/domain/verification"
+	"github.com/yrss1/todo/internal/service/account"
+	"github.com/yrss1/todo/internal/service/auth"
+	"github.com/yrss1/todo/internal/service/credentials"
+	"github.com/yrss1/todo/pkg/password"
+	"github.com/yrss1/todo/pkg/server/response"
+	"github.com/yrss1/todo/pkg/store"
+)
+
+type ProfileHandler struct {
+	accountService *account.Service
+}
+
+func NewProfileHandler(s *account.Service) *ProfileHandler {
+	return &ProfileHandler{accountService: s}
+}
+
+// Routes registers the profile routes. owner guards the routes that change
+// the credentials of the account.
+func (h *ProfileHandler) Routes(r *gin.RouterGroup, owner gin.HandlerFunc) {
+	api := r.Group("/me")
+	{
+		api.GET("/", h.get)
+		api.PATCH("/", owner, h.update)
+
+		api.POST("/password", owner, h.changePassword)
+		api.POST("/email/confirm", owner, h.confirmEmail)
+
+		api.GET("/settings", h.getSettings)
+		api.PATCH("/settings", h.updateSettings)
+
+		api.GET("/avatar", h.getAvatar)
+		api.PUT("/avatar", h.uploadAvatar)
+		api.DELETE("/avatar", h.deleteAvatar)
+	}
+
+	// Kept for clients of the earlier /users/me routes.
+	legacy := r.Group("/users/me")
+	{
+		legacy.GET("", h.get)
+		legacy.PUT("", owner, h.update)
+	}
+}
+
+// get godoc
+// @Summary Get the current user
+// @Description Get the profile of the authenticated user
+// @Tags me
+// @Accept  json
+// @Produce  json
+// @Security BearerAuth
+// @Success 200 {object} user.Response "User details"
+// @Failure 404 {object} response.Object "User not found"
+// @Failure 500 {object} response.Object "Internal Server Error"
+// @Router /me [get]
+func (h *ProfileHandler) get(c *gin.Context) {
+	userID := c.Value("userID").(string)
+
+	res, err := h.accountService.GetUser(c, userID)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrorNotFound):
+			response.NotFound(c, err)
+		default:
+			response.InternalServerError(c, err)
+		}
+		return
+	}
+
+	response.OK(c, res)
+}
+
+// update godoc
+// @Summary Update the current user
+// @Description Update name or email of the authenticated user. A new email is only applied once it is confirmed at /me/email/confirm with the token sent to it. The role cannot be changed and the password is changed through /me/password.
+// @Tags me
+// @Accept  json
+// @Produce  json
+// @Security BearerAuth
+// @Param user body user.Request true "User request"
+// @Success 200 {string} string "ok"
+// @Failure 400 {object} response.Object "Bad Request"
+// @Failure 404 {object} response.Object "User not found"
+// @Failure 409 {object} response.Object "Email address is already in use"
+// @Failure 500 {object} response.Object "Internal Server Error"
+// @Router /me [patch]
+func (h *ProfileHandler) update(c *gin.Context) {
+	userID := c.Value("userID").(string)
+	req := user.Request{}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err, req)
+		return
+	}
+
+	if req.Role != nil || req.Password != nil {
+		response.BadRequest(c, errors.New("role and password cannot be changed here"), nil)
+		return
+	}
+
+	if err := req.IsEmpty("update"); err != nil {
+		response.BadRequest(c, err, req)
+		return
+	}
+
+	if err := h.accountService.UpdateUser(c, userID, req); err != nil {
+		switch {
+		case errors.Is(err, store.ErrorNotFound):
+			response.NotFound(c, err)
+		case errors.Is(err, user.ErrEmailTaken):
+			response.Conflict(c, err)
+		default:
+			response.InternalServerError(c, err)
+		}
+		return
+	}
+
+	response.OK(c, "ok")
+}
+
+// confirmEmail godoc
+// @Summary Confirm a new email address
+// @Description Switch the authenticated user to the pending email address with the token sent to it. The previous address is notified.
+// @Tags me
+// @Accept  json
+// @Produce  json
+// @Security BearerAuth
+// @Param request body verification.VerifyEmailRequest true "Token from the confirmation email"
+// @Success 200 {string} string "Email changed"
+// @Failure 400 {object} response.Object "Invalid or expired token"
+// @Failure 409 {object} response.Object "Email address is already in use"
+// @Failure 500 {object} response.Object "Internal Server Error"
+// @Router /me/email/confirm [post]
+func (h *ProfileHandler) confirmEmail(c *gin.Context) {
+	userID := c.Value("userID").(string)
+
+	req := verification.VerifyEmailRequest{}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err, nil)
+		return
+	}
+	if err := req.Validate(); err != nil {
+		response.BadRequest(c, err, nil)
+		return
+	}
+
+	if err := h.accountService.ConfirmEmailChange(c, userID, *req.Token); err != nil {
+		switch {
+		case errors.Is(err, account.ErrInvalidEmailChangeToken):
+			response.BadRequest(c, err, nil)
+		case errors.Is(err, user.ErrEmailTaken):
+			response.Conflict(c, err)
+		default:
+			response.InternalServerError(c, err)
+		}
+		return
+	}
+
+	response.OK(c, "Email changed")
+}
+
+// changePassword godoc
+// @Summary Change the password
+// @Description Change the password of the authenticated user. The old password is required. Every other session is signed out; the session of the request stays signed in.
+// @Tags me
+// @Accept  json
+// @Produce  json
+// @Security BearerAuth
+// @Param password body user.PasswordRequest true "Old and new password"
+// @Success 200 {string} string "Password changed"
+// @Failure 400 {object} response.Object "Bad Request"
+// @Failure 404 {object} response.Object "User not found"
+// @Failure 500 {object} response.Object "Internal Server Error"
+// @Router /me/password [post]
+func (h *ProfileHandler) changePassword(c *gin.Context) {
+	userID := c.Value("userID").(string)
+	req := user.PasswordRequest{}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err, nil)
+		return
+	}
+
+	if err := req.Validate(); err != nil {
+		response.BadRequest(c, err, nil)
+		return
+	}
+
+	claims := c.Value("claims").(*auth.Claims)
+
+	if err := h.accountService.ChangePassword(c, userID, claims.SessionID, req); err != nil {
+		switch {
+		case errors.Is(err, credentials.ErrInvalidPassword), errors.Is(err, password.ErrWeakPassword):
+			response.BadRequest(c, err, nil)
+		case errors.Is(err, store.ErrorNotFound):
+			response.NotFound(c, err)
+		default:
+			response.InternalServerError(c, err)
+		}
+		return
+	}
+
+	response.OK(c, "Password changed")
+}
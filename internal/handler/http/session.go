@@ -0,0 +1,91 @@
+package http
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/yrss1/todo/internal/service/auth"
+	"github.com/yrss1/todo/pkg/server/response"
+	"github.com/yrss1/todo/pkg/store"
+)
+
+// SessionRoutes registers the routes to see and end the logins of the
+// authenticated user.
+func (h *AuthHandler) SessionRoutes(r *gin.RouterGroup) {
+	api := r.Group("/me/sessions")
+	{
+		api.GET("/", h.listSessions)
+		api.DELETE("/", h.revokeOtherSessions)
+		api.DELETE("/:id", h.revokeSession)
+	}
+}
+
+// listSessions godoc
+// @Summary List sessions
+// @Description List the devices the current user is logged in on, with user agent, IP address and last activity. The session of the request is flagged as current.
+// @Tags me
+// @Produce  json
+// @Security BearerAuth
+// @Success 200 {array} session.Response "Sessions"
+// @Failure 500 {object} response.Object "Internal Server Error"
+// @Router /me/sessions [get]
+func (h *AuthHandler) listSessions(c *gin.Context) {
+	userID := c.Value("userID").(string)
+	claims := c.Value("claims").(*auth.Claims)
+
+	res, err := h.authService.ListSessions(c, userID, claims.SessionID)
+	if err != nil {
+		response.InternalServerError(c, err)
+		return
+	}
+
+	response.OK(c, res)
+}
+
+// revokeOtherSessions godoc
+// @Summary Revoke all other sessions
+// @Description Log out every device of the current user except the one making the request
+// @Tags me
+// @Produce  json
+// @Security BearerAuth
+// @Success 200 {string} string "Other sessions revoked"
+// @Failure 500 {object} response.Object "Internal Server Error"
+// @Router /me/sessions [delete]
+func (h *AuthHandler) revokeOtherSessions(c *gin.Context) {
+	userID := c.Value("userID").(string)
+	claims := c.Value("claims").(*auth.Claims)
+
+	if err := h.authService.RevokeOtherSessions(c, userID, claims.SessionID); err != nil {
+		response.InternalServerError(c, err)
+		return
+	}
+
+	response.OK(c, "Other sessions revoked")
+}
+
+// revokeSession godoc
+// @Summary Revoke a session
+// @Description Log out one device of the current user. Its refresh token stops working and its access token is rejected from the next request on.
+// @Tags me
+// @Produce  json
+// @Security BearerAuth
+// @Param id path string true "Session ID"
+// @Success 200 {string} string "Session revoked"
+// @Failure 404 {object} response.Object "Session not found"
+// @Failure 500 {object} response.Object "Internal Server Error"
+// @Router /me/sessions/{id} [delete]
+func (h *AuthHandler) revokeSession(c *gin.Context) {
+	userID := c.Value("userID").(string)
+	id := c.Param("id")
+
+	if err := h.authService.RevokeSession(c, userID, id); err != nil {
+		switch {
+		case errors.Is(err, store.ErrorNotFound):
+			response.NotFound(c, err)
+		default:
+			response.InternalServerError(c, err)
+		}
+		return
+	}
+
+	response.OK(c, "Session revoked")
+}
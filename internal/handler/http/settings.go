@@ -0,0 +1,193 @@
+package http
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/yrss1/todo/internal/domain/user"
+	"github.com/yrss1/todo/internal/service/account"
+	"github.com/yrss1/todo/pkg/server/response"
+	"github.com/yrss1/todo/pkg/store"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// getSettings godoc
+// @Summary Get display settings
+// @Description Get the time zone, locale, date format and default task view of the authenticated user
+// @Tags me
+// @Produce  json
+// @Security BearerAuth
+// @Success 200 {object} user.SettingsResponse "Settings"
+// @Failure 404 {object} response.Object "User not found"
+// @Failure 500 {object} response.Object "Internal Server Error"
+// @Router /me/settings [get]
+func (h *ProfileHandler) getSettings(c *gin.Context) {
+	userID := c.Value("userID").(string)
+
+	res, err := h.accountService.GetSettings(c, userID)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrorNotFound):
+			response.NotFound(c, err)
+		default:
+			response.InternalServerError(c, err)
+		}
+		return
+	}
+
+	response.OK(c, res)
+}
+
+// updateSettings godoc
+// @Summary Update display settings
+// @Description Change any of the time zone, locale, date format and default task view of the authenticated user. Due dates and statistics follow the time zone.
+// @Tags me
+// @Accept  json
+// @Produce  json
+// @Security BearerAuth
+// @Param settings body user.SettingsRequest true "Settings to change"
+// @Success 200 {string} string "ok"
+// @Failure 400 {object} response.Object "Bad Request"
+// @Failure 404 {object} response.Object "User not found"
+// @Failure 500 {object} response.Object "Internal Server Error"
+// @Router /me/settings [patch]
+func (h *ProfileHandler) updateSettings(c *gin.Context) {
+	userID := c.Value("userID").(string)
+	req := user.SettingsRequest{}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err, nil)
+		return
+	}
+
+	if err := req.Validate(); err != nil {
+		response.BadRequest(c, err, nil)
+		return
+	}
+
+	if err := h.accountService.UpdateSettings(c, userID, req); err != nil {
+		switch {
+		case errors.Is(err, store.ErrorNotFound):
+			response.NotFound(c, err)
+		default:
+			response.InternalServerError(c, err)
+		}
+		return
+	}
+
+	response.OK(c, "ok")
+}
+
+// getAvatar godoc
+// @Summary Get the avatar
+// @Description Download the avatar image of the authenticated user
+// @Tags me
+// @Produce  image/png,image/jpeg,image/gif,image/webp
+// @Security BearerAuth
+// @Success 200 {file} file "Avatar image"
+// @Failure 404 {object} response.Object "No avatar"
+// @Failure 500 {object} response.Object "Internal Server Error"
+// @Router /me/avatar [get]
+func (h *ProfileHandler) getAvatar(c *gin.Context) {
+	userID := c.Value("userID").(string)
+
+	res, err := h.accountService.GetAvatar(c, userID)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrorNotFound):
+			response.NotFound(c, err)
+		default:
+			response.InternalServerError(c, err)
+		}
+		return
+	}
+
+	c.Header("Cache-Control", "private, max-age=300")
+	c.Header("Last-Modified", res.UpdatedAt.UTC().Format(http.TimeFormat))
+	c.Data(http.StatusOK, res.ContentType, res.Data)
+}
+
+// uploadAvatar godoc
+// @Summary Upload an avatar
+// @Description Replace the avatar of the authenticated user with a PNG, JPEG, GIF or WebP image of at most 1 MiB
+// @Tags me
+// @Accept  multipart/form-data,image/png,image/jpeg,image/gif,image/webp
+// @Produce  json
+// @Security BearerAuth
+// @Param avatar formData file false "Image file; the raw request body is used when omitted"
+// @Success 200 {string} string "ok"
+// @Failure 400 {object} response.Object "Bad Request"
+// @Failure 500 {object} response.Object "Internal Server Error"
+// @Router /me/avatar [put]
+func (h *ProfileHandler) uploadAvatar(c *gin.Context) {
+	userID := c.Value("userID").(string)
+
+	// Leave room for the multipart framing around the image.
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, account.MaxAvatarSize+64<<10)
+
+	var body io.Reader = c.Request.Body
+	if strings.HasPrefix(c.ContentType(), "multipart/form-data") {
+		fileHeader, err := c.FormFile("avatar")
+		if err != nil {
+			response.BadRequest(c, err, nil)
+			return
+		}
+		file, err := fileHeader.Open()
+		if err != nil {
+			response.BadRequest(c, err, nil)
+			return
+		}
+		defer file.Close()
+
+		body = file
+	}
+
+	image, err := io.ReadAll(io.LimitReader(body, account.MaxAvatarSize+1))
+	if err != nil {
+		response.BadRequest(c, err, nil)
+		return
+	}
+	if len(image) > account.MaxAvatarSize {
+		response.BadRequest(c, errors.New("avatar must not be larger than 1 MiB"), nil)
+		return
+	}
+
+	if err = h.accountService.SetAvatar(c, userID, image); err != nil {
+		switch {
+		case errors.Is(err, account.ErrUnsupportedAvatar):
+			response.BadRequest(c, err, nil)
+		default:
+			response.InternalServerError(c, err)
+		}
+		return
+	}
+
+	response.OK(c, "ok")
+}
+
+// deleteAvatar godoc
+// @Summary Remove the avatar
+// @Description Remove the avatar of the authenticated user
+// @Tags me
+// @Produce  json
+// @Security BearerAuth
+// @Success 200 {string} string "ok"
+// @Failure 404 {object} response.Object "No avatar"
+// @Failure 500 {object} response.Object "Internal Server Error"
+// @Router /me/avatar [delete]
+func (h *ProfileHandler) deleteAvatar(c *gin.Context) {
+	userID := c.Value("userID").(string)
+
+	if err := h.accountService.DeleteAvatar(c, userID); err != nil {
+		switch {
+		case errors.Is(err, store.ErrorNotFound):
+			response.NotFound(c, err)
+		default:
+			response.InternalServerError(c, err)
+		}
+		return
+	}
+
+	response.OK(c, "ok")
+}
@@ -0,0 +1,110 @@
+package http
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/yrss1/todo/internal/service/todo"
+	"github.com/yrss1/todo/pkg/server/response"
+	"time"
+)
+
+type StatsHandler struct {
+	todoService *todo.Service
+}
+
+func NewStatsHandler(s *todo.Service) *StatsHandler {
+	return &StatsHandler{todoService: s}
+}
+
+func (h *StatsHandler) Routes(r *gin.RouterGroup) {
+	api := r.Group("/stats")
+	{
+		api.GET("/", h.summary)
+		api.GET("/estimates", h.estimates)
+	}
+}
+
+// summary godoc
+// @Summary Task statistics
+// @Description Get task counts by status, completions per day or week, average time to complete and overdue count for the current user. Days start at midnight in the user's time zone. The period covers at most 366 days with interval=day and 5 years with interval=week.
+// @Tags stats
+// @Accept  json
+// @Produce  json
+// @Security BearerAuth
+// @Param X-Workspace-ID header string false "Workspace ID, defaults to the first workspace of the user"
+// @Param from query string false "Start date (YYYY-MM-DD), defaults to 30 days ago"
+// @Param to query string false "End date inclusive (YYYY-MM-DD), defaults to today"
+// @Param interval query string false "Completion bucket size" Enums(day, week) default(day)
+// @Success 200 {object} task.StatsResponse "Task statistics"
+// @Failure 400 {object} response.Object "Bad Request"
+// @Failure 500 {object} response.Object "Internal Server Error"
+// @Router /stats [get]
+func (h *StatsHandler) summary(c *gin.Context) {
+	userID := c.Value("userID").(string)
+	workspaceID := c.Value("workspaceID").(string)
+
+	var from, to *time.Time
+	if value := c.Query("from"); value != "" {
+		date, err := time.Parse(time.DateOnly, value)
+		if err != nil {
+			response.BadRequest(c, errors.New("invalid from parameter"), nil)
+			return
+		}
+		from = &date
+	}
+	if value := c.Query("to"); value != "" {
+		date, err := time.Parse(time.DateOnly, value)
+		if err != nil {
+			response.BadRequest(c, errors.New("invalid to parameter"), nil)
+			return
+		}
+		to = &date
+	}
+	if from != nil && to != nil && to.Before(*from) {
+		response.BadRequest(c, errors.New("to must not be before from"), nil)
+		return
+	}
+
+	interval := c.DefaultQuery("interval", "day")
+	if interval != "day" && interval != "week" {
+		response.BadRequest(c, errors.New("invalid interval parameter"), nil)
+		return
+	}
+
+	res, err := h.todoService.GetStatistics(c, userID, workspaceID, from, to, interval)
+	if err != nil {
+		switch {
+		case errors.Is(err, todo.ErrStatsRangeTooLong):
+			response.BadRequest(c, err, nil)
+		default:
+			response.InternalServerError(c, err)
+		}
+		return
+	}
+
+	response.OK(c, res)
+}
+
+// estimates godoc
+// @Summary Estimate rollups
+// @Description Get story point and estimated minute totals for the current user, overall and per status
+// @Tags stats
+// @Accept  json
+// @Produce  json
+// @Security BearerAuth
+// @Param X-Workspace-ID header string false "Workspace ID, defaults to the first workspace of the user"
+// @Success 200 {object} task.RollupResponse "Estimate rollups"
+// @Failure 500 {object} response.Object "Internal Server Error"
+// @Router /stats/estimates [get]
+func (h *StatsHandler) estimates(c *gin.Context) {
+	userID := c.Value("userID").(string)
+	workspaceID := c.Value("workspaceID").(string)
+
+	res, err := h.todoService.GetEstimateRollup(c, userID, workspaceID)
+	if err != nil {
+		response.InternalServerError(c, err)
+		return
+	}
+
+	response.OK(c, res)
+}
@@ -1,15 +1,38 @@
 package http
 
 import (
+	"encoding/json"
 	"errors"
 	"github.com/gin-gonic/gin"
 	"github.com/yrss1/todo/internal/domain/task"
+	"github.com/yrss1/todo/internal/domain/template"
 	"github.com/yrss1/todo/internal/service/todo"
 	"github.com/yrss1/todo/pkg/server/response"
 	"github.com/yrss1/todo/pkg/store"
+	"io"
+	"net/http"
+	"path/filepath"
 	"strconv"
+	"strings"
 )
 
+const (
+	maxTemplateInstances = 100
+	maxImportSize        = 32 << 20
+)
+
+var exportContentTypes = map[string]string{
+	"csv":  "text/csv; charset=utf-8",
+	"json": "application/json; charset=utf-8",
+	"ics":  "text/calendar; charset=utf-8",
+}
+
+var sortFields = map[string]bool{
+	"id":     true,
+	"title":  true,
+	"status": true,
+}
+
 type TaskHandler struct {
 	todoService *todo.Service
 }
@@ -27,6 +50,19 @@ func (h *TaskHandler) Routes(r *gin.RouterGroup) {
 		api.GET("/:id", h.get)
 		api.PUT("/:id", h.update)
 		api.DELETE("/:id", h.delete)
+
+		api.POST("/from-template/:id", h.addFromTemplate)
+		api.POST("/import", h.importTasks)
+	}
+}
+
+// ExportRoutes registers the task export. It writes the response while the
+// tasks are being read, so it has to be registered outside of the request
+// timeout, which buffers the whole response.
+func (h *TaskHandler) ExportRoutes(r *gin.RouterGroup) {
+	api := r.Group("/tasks")
+	{
+		api.GET("/export", h.exportTasks)
 	}
 }
 
@@ -37,6 +73,7 @@ func (h *TaskHandler) Routes(r *gin.RouterGroup) {
 // @Accept  json
 // @Produce  json
 // @Security BearerAuth
+// @Param X-Workspace-ID header string false "Workspace ID, defaults to the first workspace of the user"
 // @Param title query string false "Filter tasks by title"
 // @Param status query string false "Filter tasks by status"
 // @Param sortBy query string false "Field to sort by (e.g., id, title)" Enums(id, title, status)
@@ -49,6 +86,7 @@ func (h *TaskHandler) Routes(r *gin.RouterGroup) {
 // @Router /tasks [get]
 func (h *TaskHandler) list(c *gin.Context) {
 	userID := c.Value("userID").(string)
+	workspaceID := c.Value("workspaceID").(string)
 
 	// Extract query parameters
 	titleFilter := c.Query("title")
@@ -74,7 +112,7 @@ func (h *TaskHandler) list(c *gin.Context) {
 		return
 	}
 
-	res, err := h.todoService.ListTasks(c, userID, titleFilter, statusFilter, sortBy, sortOrder, page, limit)
+	res, err := h.todoService.ListTasks(c, userID, workspaceID, titleFilter, statusFilter, sortBy, sortOrder, page, limit)
 	if err != nil {
 		response.InternalServerError(c, err)
 		return
@@ -90,6 +128,7 @@ func (h *TaskHandler) list(c *gin.Context) {
 // @Accept  json
 // @Produce  json
 // @Security BearerAuth
+// @Param X-Workspace-ID header string false "Workspace ID, defaults to the first workspace of the user"
 // @Param task body task.Request true "Task request"
 // @Success 200 {object} task.Response "Task created successfully"
 // @Failure 400 {object} response.Object "Bad Request"
@@ -97,6 +136,7 @@ func (h *TaskHandler) list(c *gin.Context) {
 // @Router /tasks [post]
 func (h *TaskHandler) add(c *gin.Context) {
 	userID := c.Value("userID").(string)
+	workspaceID := c.Value("workspaceID").(string)
 
 	req := task.Request{}
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -104,6 +144,7 @@ func (h *TaskHandler) add(c *gin.Context) {
 		return
 	}
 	req.UserID = &userID
+	req.WorkspaceID = &workspaceID
 	if err := req.Validate(); err != nil {
 		response.BadRequest(c, err, req)
 		return
@@ -125,6 +166,7 @@ func (h *TaskHandler) add(c *gin.Context) {
 // @Accept  json
 // @Produce  json
 // @Security BearerAuth
+// @Param X-Workspace-ID header string false "Workspace ID, defaults to the first workspace of the user"
 // @Param id path string true "Task ID"
 // @Success 200 {object} task.Response "Task details"
 // @Failure 404 {object} response.Object "Task not found"
@@ -132,9 +174,10 @@ func (h *TaskHandler) add(c *gin.Context) {
 // @Router /tasks/{id} [get]
 func (h *TaskHandler) get(c *gin.Context) {
 	userID := c.Value("userID").(string)
+	workspaceID := c.Value("workspaceID").(string)
 	taskID := c.Param("id")
 
-	res, err := h.todoService.GetTask(c, userID, taskID)
+	res, err := h.todoService.GetTask(c, userID, workspaceID, taskID)
 	if err != nil {
 		switch {
 		case errors.Is(err, store.ErrorNotFound):
@@ -155,6 +198,7 @@ func (h *TaskHandler) get(c *gin.Context) {
 // @Accept  json
 // @Produce  json
 // @Security BearerAuth
+// @Param X-Workspace-ID header string false "Workspace ID, defaults to the first workspace of the user"
 // @Param id path string true "Task ID"
 // @Param task body task.Request true "Task request"
 // @Success 200 {string} string "ok"
@@ -164,6 +208,7 @@ func (h *TaskHandler) get(c *gin.Context) {
 // @Router /tasks/{id} [put]
 func (h *TaskHandler) update(c *gin.Context) {
 	userID := c.Value("userID").(string)
+	workspaceID := c.Value("workspaceID").(string)
 	taskID := c.Param("id")
 	req := task.Request{}
 
@@ -177,7 +222,7 @@ func (h *TaskHandler) update(c *gin.Context) {
 		return
 	}
 
-	if err := h.todoService.UpdateTask(c, userID, taskID, req); err != nil {
+	if err := h.todoService.UpdateTask(c, userID, workspaceID, taskID, req); err != nil {
 		switch {
 		case errors.Is(err, store.ErrorNotFound):
 			response.NotFound(c, err)
@@ -197,6 +242,7 @@ func (h *TaskHandler) update(c *gin.Context) {
 // @Accept  json
 // @Produce  json
 // @Security BearerAuth
+// @Param X-Workspace-ID header string false "Workspace ID, defaults to the first workspace of the user"
 // @Param id path string true "Task ID"
 // @Success 200 {string} string "Task deleted"
 // @Failure 404 {object} response.Object "Task not found"
@@ -204,9 +250,10 @@ func (h *TaskHandler) update(c *gin.Context) {
 // @Router /tasks/{id} [delete]
 func (h *TaskHandler) delete(c *gin.Context) {
 	userID := c.Value("userID").(string)
+	workspaceID := c.Value("workspaceID").(string)
 	taskID := c.Param("id")
 
-	if err := h.todoService.DeleteTask(c, userID, taskID); err != nil {
+	if err := h.todoService.DeleteTask(c, userID, workspaceID, taskID); err != nil {
 		switch {
 		case errors.Is(err, store.ErrorNotFound):
 			response.NotFound(c, err)
@@ -218,3 +265,180 @@ func (h *TaskHandler) delete(c *gin.Context) {
 
 	response.OK(c, "Task deleted")
 }
+
+// addFromTemplate godoc
+// @Summary Add tasks from a template
+// @Description Create one task per instance from a task template, substituting {{date}}, {{time}}, {{datetime}}, {{index}} and the instance variables. The tasks are created in one transaction; if any instance is invalid, none is created.
+// @Tags tasks
+// @Accept  json
+// @Produce  json
+// @Security BearerAuth
+// @Param X-Workspace-ID header string false "Workspace ID, defaults to the first workspace of the user"
+// @Param id path string true "Template ID"
+// @Param instances body template.InstantiateRequest false "Variables for each task to create"
+// @Success 200 {array} task.Response "Tasks created successfully"
+// @Failure 400 {object} response.Object "Bad Request"
+// @Failure 404 {object} response.Object "Template not found"
+// @Failure 500 {object} response.Object "Internal Server Error"
+// @Router /tasks/from-template/{id} [post]
+func (h *TaskHandler) addFromTemplate(c *gin.Context) {
+	userID := c.Value("userID").(string)
+	workspaceID := c.Value("workspaceID").(string)
+	templateID := c.Param("id")
+
+	req := template.InstantiateRequest{}
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			response.BadRequest(c, err, req)
+			return
+		}
+	}
+	if len(req.Instances) > maxTemplateInstances {
+		response.BadRequest(c, errors.New("too many instances"), nil)
+		return
+	}
+
+	res, err := h.todoService.CreateTasksFromTemplate(c, userID, workspaceID, templateID, req)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrorNotFound):
+			response.NotFound(c, err)
+		case errors.Is(err, todo.ErrInvalidInstance):
+			response.BadRequest(c, err, nil)
+		default:
+			response.InternalServerError(c, err)
+		}
+		return
+	}
+
+	response.OK(c, res)
+}
+
+// importTasks godoc
+// @Summary Import tasks
+// @Description Import tasks from a CSV or JSON upload in one transaction. Every row is validated first; if any row is invalid nothing is imported and the errors are reported per row.
+// @Tags tasks
+// @Accept  multipart/form-data,text/csv,application/json
+// @Produce  json
+// @Security BearerAuth
+// @Param X-Workspace-ID header string false "Workspace ID, defaults to the first workspace of the user"
+// @Param file formData file false "CSV or JSON file; the raw request body is used when omitted"
+// @Param format query string false "Input format, detected from the file name or content type when omitted" Enums(csv, json)
+// @Param mapping query string false "JSON object mapping task fields to source columns, e.g. {\"title\":\"Name\"}"
+// @Param dry_run query bool false "Only validate the rows without importing them"
+// @Success 200 {object} task.ImportResponse "Import report"
+// @Failure 400 {object} response.Object "Bad Request"
+// @Failure 500 {object} response.Object "Internal Server Error"
+// @Router /tasks/import [post]
+func (h *TaskHandler) importTasks(c *gin.Context) {
+	userID := c.Value("userID").(string)
+	workspaceID := c.Value("workspaceID").(string)
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxImportSize)
+
+	var (
+		body     io.Reader = c.Request.Body
+		format             = strings.ToLower(c.Query("format"))
+		filename string
+	)
+
+	if strings.HasPrefix(c.ContentType(), "multipart/form-data") {
+		fileHeader, err := c.FormFile("file")
+		if err != nil {
+			response.BadRequest(c, err, nil)
+			return
+		}
+		file, err := fileHeader.Open()
+		if err != nil {
+			response.BadRequest(c, err, nil)
+			return
+		}
+		defer file.Close()
+
+		body, filename = file, fileHeader.Filename
+	}
+
+	if format == "" {
+		switch {
+		case strings.EqualFold(filepath.Ext(filename), ".csv"), c.ContentType() == "text/csv":
+			format = "csv"
+		case strings.EqualFold(filepath.Ext(filename), ".json"), c.ContentType() == "application/json":
+			format = "json"
+		}
+	}
+
+	mapping := map[string]string{}
+	if value := c.Query("mapping"); value != "" {
+		if err := json.Unmarshal([]byte(value), &mapping); err != nil {
+			response.BadRequest(c, errors.New("invalid mapping parameter"), nil)
+			return
+		}
+	}
+
+	dryRun, _ := strconv.ParseBool(c.DefaultQuery("dry_run", "false"))
+
+	res, err := h.todoService.ImportTasks(c, userID, workspaceID, format, body, mapping, dryRun)
+	if err != nil {
+		switch {
+		case errors.Is(err, todo.ErrInvalidImport):
+			response.BadRequest(c, err, res)
+		case errors.Is(err, todo.ErrMalformedImport), errors.Is(err, todo.ErrUnknownImportFormat):
+			response.BadRequest(c, err, nil)
+		default:
+			response.InternalServerError(c, err)
+		}
+		return
+	}
+
+	response.OK(c, res)
+}
+
+// exportTasks godoc
+// @Summary Export tasks
+// @Description Export all tasks of the current user matching the same filters as the task list as CSV, JSON or iCalendar VTODO entries
+// @Tags tasks
+// @Produce  text/csv,application/json,text/calendar
+// @Security BearerAuth
+// @Param X-Workspace-ID header string false "Workspace ID, defaults to the first workspace of the user"
+// @Param format query string false "Export format" Enums(csv, json, ics) default(json)
+// @Param title query string false "Filter tasks by title"
+// @Param status query string false "Filter tasks by status"
+// @Param sortBy query string false "Field to sort by (e.g., id, title)" Enums(id, title, status)
+// @Param sortOrder query string false "Sort order (asc or desc)" Enums(asc, desc)
+// @Success 200 {file} file "Exported tasks"
+// @Failure 400 {object} response.Object "Bad Request"
+// @Failure 500 {object} response.Object "Internal Server Error"
+// @Router /tasks/export [get]
+func (h *TaskHandler) exportTasks(c *gin.Context) {
+	userID := c.Value("userID").(string)
+	workspaceID := c.Value("workspaceID").(string)
+
+	format := c.DefaultQuery("format", "json")
+	contentType, ok := exportContentTypes[format]
+	if !ok {
+		response.BadRequest(c, todo.ErrUnknownExportFormat, nil)
+		return
+	}
+
+	titleFilter := c.Query("title")
+	statusFilter := c.Query("status")
+	sortBy := c.DefaultQuery("sortBy", "id")
+	sortOrder := c.DefaultQuery("sortOrder", "asc")
+
+	if !sortFields[sortBy] {
+		response.BadRequest(c, errors.New("invalid sortBy parameter"), nil)
+		return
+	}
+	if sortOrder != "asc" && sortOrder != "desc" {
+		response.BadRequest(c, errors.New("invalid sortOrder parameter"), nil)
+		return
+	}
+
+	c.Header("Content-Type", contentType)
+	c.Header("Content-Disposition", `attachment; filename="tasks.`+format+`"`)
+	c.Status(http.StatusOK)
+
+	if err := h.todoService.ExportTasks(c, c.Writer, format, userID, workspaceID, titleFilter, statusFilter, sortBy, sortOrder); err != nil {
+		c.Error(err)
+	}
+}
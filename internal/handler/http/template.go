@@ -0,0 +1,199 @@
+package http
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/yrss1/todo/internal/domain/template"
+	"github.com/yrss1/todo/internal/service/todo"
+	"github.com/yrss1/todo/pkg/server/response"
+	"github.com/yrss1/todo/pkg/store"
+)
+
+type TemplateHandler struct {
+	todoService *todo.Service
+}
+
+func NewTemplateHandler(s *todo.Service) *TemplateHandler {
+	return &TemplateHandler{todoService: s}
+}
+
+func (h *TemplateHandler) Routes(r *gin.RouterGroup) {
+	api := r.Group("/templates")
+	{
+		api.GET("/", h.list)
+		api.POST("/", h.add)
+
+		api.GET("/:id", h.get)
+		api.PUT("/:id", h.update)
+		api.DELETE("/:id", h.delete)
+	}
+}
+
+// list godoc
+// @Summary List task templates
+// @Description Get all task templates of the current user
+// @Tags templates
+// @Accept  json
+// @Produce  json
+// @Security BearerAuth
+// @Param X-Workspace-ID header string false "Workspace ID, defaults to the first workspace of the user"
+// @Success 200 {array} template.Response "List of templates"
+// @Failure 500 {object} response.Object "Internal Server Error"
+// @Router /templates [get]
+func (h *TemplateHandler) list(c *gin.Context) {
+	userID := c.Value("userID").(string)
+	workspaceID := c.Value("workspaceID").(string)
+
+	res, err := h.todoService.ListTemplates(c, userID, workspaceID)
+	if err != nil {
+		response.InternalServerError(c, err)
+		return
+	}
+
+	response.OK(c, res)
+}
+
+// add godoc
+// @Summary Add a task template
+// @Description Add a new task template for the current user
+// @Tags templates
+// @Accept  json
+// @Produce  json
+// @Security BearerAuth
+// @Param X-Workspace-ID header string false "Workspace ID, defaults to the first workspace of the user"
+// @Param template body template.Request true "Template request"
+// @Success 200 {object} template.Response "Template created successfully"
+// @Failure 400 {object} response.Object "Bad Request"
+// @Failure 500 {object} response.Object "Internal Server Error"
+// @Router /templates [post]
+func (h *TemplateHandler) add(c *gin.Context) {
+	userID := c.Value("userID").(string)
+	workspaceID := c.Value("workspaceID").(string)
+
+	req := template.Request{}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err, req)
+		return
+	}
+	req.UserID = &userID
+	req.WorkspaceID = &workspaceID
+	if err := req.Validate(); err != nil {
+		response.BadRequest(c, err, req)
+		return
+	}
+
+	res, err := h.todoService.CreateTemplate(c, req)
+	if err != nil {
+		response.InternalServerError(c, err)
+		return
+	}
+
+	response.OK(c, res)
+}
+
+// get godoc
+// @Summary Get a task template
+// @Description Get task template by ID for the current user
+// @Tags templates
+// @Accept  json
+// @Produce  json
+// @Security BearerAuth
+// @Param X-Workspace-ID header string false "Workspace ID, defaults to the first workspace of the user"
+// @Param id path string true "Template ID"
+// @Success 200 {object} template.Response "Template details"
+// @Failure 404 {object} response.Object "Template not found"
+// @Failure 500 {object} response.Object "Internal Server Error"
+// @Router /templates/{id} [get]
+func (h *TemplateHandler) get(c *gin.Context) {
+	userID := c.Value("userID").(string)
+	workspaceID := c.Value("workspaceID").(string)
+	templateID := c.Param("id")
+
+	res, err := h.todoService.GetTemplate(c, userID, workspaceID, templateID)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrorNotFound):
+			response.NotFound(c, err)
+		default:
+			response.InternalServerError(c, err)
+		}
+		return
+	}
+
+	response.OK(c, res)
+}
+
+// update godoc
+// @Summary Update a task template
+// @Description Update task template by ID for the current user
+// @Tags templates
+// @Accept  json
+// @Produce  json
+// @Security BearerAuth
+// @Param X-Workspace-ID header string false "Workspace ID, defaults to the first workspace of the user"
+// @Param id path string true "Template ID"
+// @Param template body template.Request true "Template request"
+// @Success 200 {string} string "ok"
+// @Failure 400 {object} response.Object "Bad Request"
+// @Failure 404 {object} response.Object "Template not found"
+// @Failure 500 {object} response.Object "Internal Server Error"
+// @Router /templates/{id} [put]
+func (h *TemplateHandler) update(c *gin.Context) {
+	userID := c.Value("userID").(string)
+	workspaceID := c.Value("workspaceID").(string)
+	templateID := c.Param("id")
+	req := template.Request{}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err, req)
+		return
+	}
+
+	if err := req.IsEmpty("update"); err != nil {
+		response.BadRequest(c, err, req)
+		return
+	}
+
+	if err := h.todoService.UpdateTemplate(c, userID, workspaceID, templateID, req); err != nil {
+		switch {
+		case errors.Is(err, store.ErrorNotFound):
+			response.NotFound(c, err)
+		default:
+			response.InternalServerError(c, err)
+		}
+		return
+	}
+
+	response.OK(c, "ok")
+}
+
+// delete godoc
+// @Summary Delete a task template
+// @Description Delete task template by ID for the current user
+// @Tags templates
+// @Accept  json
+// @Produce  json
+// @Security BearerAuth
+// @Param X-Workspace-ID header string false "Workspace ID, defaults to the first workspace of the user"
+// @Param id path string true "Template ID"
+// @Success 200 {string} string "Template deleted"
+// @Failure 404 {object} response.Object "Template not found"
+// @Failure 500 {object} response.Object "Internal Server Error"
+// @Router /templates/{id} [delete]
+func (h *TemplateHandler) delete(c *gin.Context) {
+	userID := c.Value("userID").(string)
+	workspaceID := c.Value("workspaceID").(string)
+	templateID := c.Param("id")
+
+	if err := h.todoService.DeleteTemplate(c, userID, workspaceID, templateID); err != nil {
+		switch {
+		case errors.Is(err, store.ErrorNotFound):
+			response.NotFound(c, err)
+		default:
+			response.InternalServerError(c, err)
+		}
+		return
+	}
+
+	response.OK(c, "Template deleted")
+}
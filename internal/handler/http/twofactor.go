@@ -0,0 +1,212 @@
+package http
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/yrss1/todo/internal/domain/twofactor"
+	"github.com/yrss1/todo/internal/service/auth"
+	"github.com/yrss1/todo/pkg/server/response"
+)
+
+type TwoFactorChallengeResponse struct {
+	TwoFactorRequired bool   `json:"two_factor_required"`
+	ChallengeToken    string `json:"challenge_token"`
+	ExpiresIn         int64  `json:"expires_in"`
+}
+
+// TwoFactorRoutes registers the enrollment routes of the authenticated user.
+func (h *AuthHandler) TwoFactorRoutes(r *gin.RouterGroup) {
+	api := r.Group("/me/2fa")
+	{
+		api.POST("/enroll", h.enrollTwoFactor)
+		api.POST("/confirm", h.confirmTwoFactor)
+		api.POST("/recovery-codes", h.regenerateRecoveryCodes)
+		api.DELETE("/", h.disableTwoFactor)
+	}
+}
+
+// loginTwoFactor godoc
+// @Summary Complete a two-factor login
+// @Description Exchange the challenge token from /auth/login and a TOTP or recovery code for access and refresh tokens, or for a password change challenge when the user must choose a new password
+// @Tags auth
+// @Accept  json
+// @Produce  json
+// @Param request body twofactor.ChallengeRequest true "Challenge token and code"
+// @Success 200 {object} TokenResponse "Access and refresh tokens"
+// @Failure 400 {object} response.Object "Bad Request"
+// @Failure 401 {object} response.Object "Invalid code"
+// @Failure 429 {object} response.Object "Too many failed attempts, see the Retry-After header"
+// @Failure 500 {object} response.Object "Internal Server Error"
+// @Router /auth/login/2fa [post]
+func (h *AuthHandler) loginTwoFactor(c *gin.Context) {
+	req := twofactor.ChallengeRequest{}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err, nil)
+		return
+	}
+	if err := req.Validate(); err != nil {
+		response.BadRequest(c, err, nil)
+		return
+	}
+
+	id, err := h.authService.CompleteTwoFactorLogin(c, req, c.ClientIP())
+	if err != nil {
+		var locked *auth.LockedError
+		switch {
+		case errors.As(err, &locked):
+			tooManyAttempts(c, locked)
+		case errors.Is(err, auth.ErrInvalidVerificationToken):
+			response.BadRequest(c, err, nil)
+		case errors.Is(err, auth.ErrInvalidTwoFactorCode):
+			response.Unauthorized(c, err)
+		default:
+			response.InternalServerError(c, err)
+		}
+		return
+	}
+
+	h.finishLogin(c, id)
+}
+
+// enrollTwoFactor godoc
+// @Summary Start two-factor enrollment
+// @Description Create a TOTP secret for the current user. Add it to an authenticator app through the otpauth URI and confirm it with a code.
+// @Tags me
+// @Produce  json
+// @Security BearerAuth
+// @Success 200 {object} twofactor.EnrollmentResponse "Secret and otpauth URI"
+// @Failure 400 {object} response.Object "Two-factor authentication is already enabled"
+// @Failure 500 {object} response.Object "Internal Server Error"
+// @Router /me/2fa/enroll [post]
+func (h *AuthHandler) enrollTwoFactor(c *gin.Context) {
+	userID := c.Value("userID").(string)
+
+	res, err := h.authService.EnrollTwoFactor(c, userID)
+	if err != nil {
+		h.twoFactorError(c, err)
+		return
+	}
+
+	response.OK(c, res)
+}
+
+// confirmTwoFactor godoc
+// @Summary Confirm two-factor enrollment
+// @Description Enable two-factor authentication with a code from the authenticator app. The returned recovery codes are shown only once.
+// @Tags me
+// @Accept  json
+// @Produce  json
+// @Security BearerAuth
+// @Param request body twofactor.CodeRequest true "TOTP code"
+// @Success 200 {object} twofactor.RecoveryCodesResponse "Recovery codes"
+// @Failure 400 {object} response.Object "Bad Request"
+// @Failure 401 {object} response.Object "Invalid code"
+// @Failure 429 {object} response.Object "Too many failed attempts, see the Retry-After header"
+// @Failure 500 {object} response.Object "Internal Server Error"
+// @Router /me/2fa/confirm [post]
+func (h *AuthHandler) confirmTwoFactor(c *gin.Context) {
+	userID := c.Value("userID").(string)
+
+	req := twofactor.CodeRequest{}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err, nil)
+		return
+	}
+	if err := req.Validate(); err != nil {
+		response.BadRequest(c, err, nil)
+		return
+	}
+
+	codes, err := h.authService.ConfirmTwoFactor(c, userID, *req.Code)
+	if err != nil {
+		h.twoFactorError(c, err)
+		return
+	}
+
+	response.OK(c, twofactor.RecoveryCodesResponse{RecoveryCodes: codes})
+}
+
+// regenerateRecoveryCodes godoc
+// @Summary Regenerate recovery codes
+// @Description Replace all recovery codes of the current user. Requires a TOTP code.
+// @Tags me
+// @Accept  json
+// @Produce  json
+// @Security BearerAuth
+// @Param request body twofactor.CodeRequest true "TOTP code"
+// @Success 200 {object} twofactor.RecoveryCodesResponse "Recovery codes"
+// @Failure 400 {object} response.Object "Bad Request"
+// @Failure 401 {object} response.Object "Invalid code"
+// @Failure 429 {object} response.Object "Too many failed attempts, see the Retry-After header"
+// @Failure 500 {object} response.Object "Internal Server Error"
+// @Router /me/2fa/recovery-codes [post]
+func (h *AuthHandler) regenerateRecoveryCodes(c *gin.Context) {
+	userID := c.Value("userID").(string)
+
+	req := twofactor.CodeRequest{}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err, nil)
+		return
+	}
+	if err := req.Validate(); err != nil {
+		response.BadRequest(c, err, nil)
+		return
+	}
+
+	codes, err := h.authService.RegenerateRecoveryCodes(c, userID, *req.Code)
+	if err != nil {
+		h.twoFactorError(c, err)
+		return
+	}
+
+	response.OK(c, twofactor.RecoveryCodesResponse{RecoveryCodes: codes})
+}
+
+// disableTwoFactor godoc
+// @Summary Disable two-factor authentication
+// @Description Remove the TOTP secret and recovery codes of the current user. Requires a TOTP or recovery code.
+// @Tags me
+// @Accept  json
+// @Produce  json
+// @Security BearerAuth
+// @Param request body twofactor.CodeRequest true "TOTP or recovery code"
+// @Success 200 {string} string "Two-factor authentication disabled"
+// @Failure 400 {object} response.Object "Bad Request"
+// @Failure 401 {object} response.Object "Invalid code"
+// @Failure 429 {object} response.Object "Too many failed attempts, see the Retry-After header"
+// @Failure 500 {object} response.Object "Internal Server Error"
+// @Router /me/2fa [delete]
+func (h *AuthHandler) disableTwoFactor(c *gin.Context) {
+	userID := c.Value("userID").(string)
+
+	req := twofactor.CodeRequest{}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err, nil)
+		return
+	}
+	if err := req.Validate(); err != nil {
+		response.BadRequest(c, err, nil)
+		return
+	}
+
+	if err := h.authService.DisableTwoFactor(c, userID, *req.Code); err != nil {
+		h.twoFactorError(c, err)
+		return
+	}
+
+	response.OK(c, "Two-factor authentication disabled")
+}
+
+func (h *AuthHandler) twoFactorError(c *gin.Context, err error) {
+	var locked *auth.LockedError
+	switch {
+	case errors.As(err, &locked):
+		tooManyAttempts(c, locked)
+	case errors.Is(err, auth.ErrTwoFactorEnabled), errors.Is(err, auth.ErrTwoFactorNotEnabled):
+		response.BadRequest(c, err, nil)
+	case errors.Is(err, auth.ErrInvalidTwoFactorCode):
+		response.Unauthorized(c, err)
+	default:
+		response.InternalServerError(c, err)
+	}
+}
@@ -5,11 +5,17 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/yrss1/todo/internal/domain/user"
 	"github.com/yrss1/todo/internal/service/account"
-	"github.com/yrss1/todo/pkg/helpers"
+	"github.com/yrss1/todo/pkg/password"
 	"github.com/yrss1/todo/pkg/server/response"
 	"github.com/yrss1/todo/pkg/store"
+	"strconv"
+	"strings"
 )
 
+type TemporaryPasswordResponse struct {
+	TemporaryPassword string `json:"temporary_password"`
+}
+
 type UserHandler struct {
 	accountService *account.Service
 }
@@ -18,8 +24,8 @@ func NewUserHandler(s *account.Service) *UserHandler {
 	return &UserHandler{accountService: s}
 }
 
-func (h *UserHandler) Routes(r *gin.RouterGroup) {
-	api := r.Group("/users")
+func (h *UserHandler) Routes(r *gin.RouterGroup, admin gin.HandlerFunc) {
+	api := r.Group("/users", admin)
 	{
 		api.GET("/", h.list)
 		api.POST("/", h.add)
@@ -28,19 +34,32 @@ func (h *UserHandler) Routes(r *gin.RouterGroup) {
 		api.PUT("/:id", h.update)
 		api.DELETE("/:id", h.delete)
 
+		api.POST("/:id/temporary-password", h.setTemporaryPassword)
+		api.POST("/:id/require-password-change", h.requirePasswordChange)
+
 		api.GET("/search", h.search)
 		api.GET("/email", h.getByEmail)
 	}
 }
 
+// DirectoryRoutes registers the member search for users who are not admins.
+// r has to resolve the workspace of the request.
+func (h *UserHandler) DirectoryRoutes(r *gin.RouterGroup) {
+	api := r.Group("/directory")
+	{
+		api.GET("/", h.searchDirectory)
+	}
+}
+
 // list godoc
 // @Summary List users
-// @Description Get all users
+// @Description Get all users. Requires the admin role.
 // @Tags users
 // @Accept  json
 // @Produce  json
 // @Security BearerAuth
 // @Success 200 {array} user.Response "List of users"
+// @Failure 403 {object} response.Object "Forbidden"
 // @Failure 500 {object} response.Object "Internal Server Error"
 // @Router /users [get]
 func (h *UserHandler) list(c *gin.Context) {
@@ -55,7 +74,7 @@ func (h *UserHandler) list(c *gin.Context) {
 
 // add godoc
 // @Summary Add a user
-// @Description Add a new user
+// @Description Add a new user. Requires the admin role.
 // @Tags users
 // @Accept  json
 // @Produce  json
@@ -63,6 +82,8 @@ func (h *UserHandler) list(c *gin.Context) {
 // @Param user body user.Request true "User request"
 // @Success 200 {object} user.Response "User created successfully"
 // @Failure 400 {object} response.Object "Bad Request"
+// @Failure 403 {object} response.Object "Forbidden"
+// @Failure 409 {object} response.Object "Email address is already in use"
 // @Failure 500 {object} response.Object "Internal Server Error"
 // @Router /users [post]
 func (h *UserHandler) add(c *gin.Context) {
@@ -78,7 +99,14 @@ func (h *UserHandler) add(c *gin.Context) {
 
 	res, err := h.accountService.CreateUser(c, req)
 	if err != nil {
-		response.InternalServerError(c, err)
+		switch {
+		case errors.Is(err, password.ErrWeakPassword):
+			response.BadRequest(c, err, nil)
+		case errors.Is(err, user.ErrEmailTaken):
+			response.Conflict(c, err)
+		default:
+			response.InternalServerError(c, err)
+		}
 		return
 	}
 
@@ -87,7 +115,7 @@ func (h *UserHandler) add(c *gin.Context) {
 
 // get godoc
 // @Summary Get a user
-// @Description Get user by ID
+// @Description Get user by ID. Requires the admin role.
 // @Tags users
 // @Accept  json
 // @Produce  json
@@ -95,6 +123,7 @@ func (h *UserHandler) add(c *gin.Context) {
 // @Param id path string true "User ID"
 // @Success 200 {object} user.Response "User details"
 // @Failure 404 {object} response.Object "User not found"
+// @Failure 403 {object} response.Object "Forbidden"
 // @Failure 500 {object} response.Object "Internal Server Error"
 // @Router /users/{id} [get]
 func (h *UserHandler) get(c *gin.Context) {
@@ -116,7 +145,7 @@ func (h *UserHandler) get(c *gin.Context) {
 
 // update godoc
 // @Summary Update a user
-// @Description Update user by ID
+// @Description Update user by ID, including the role. A new password is hashed and signs the user out everywhere. A new email is only applied once it is confirmed through the link sent to it. Requires the admin role.
 // @Tags users
 // @Accept  json
 // @Produce  json
@@ -126,6 +155,8 @@ func (h *UserHandler) get(c *gin.Context) {
 // @Success 200 {string} string "ok"
 // @Failure 400 {object} response.Object "Bad Request"
 // @Failure 404 {object} response.Object "User not found"
+// @Failure 403 {object} response.Object "Forbidden"
+// @Failure 409 {object} response.Object "Email address is already in use"
 // @Failure 500 {object} response.Object "Internal Server Error"
 // @Router /users/{id} [put]
 func (h *UserHandler) update(c *gin.Context) {
@@ -144,6 +175,10 @@ func (h *UserHandler) update(c *gin.Context) {
 
 	if err := h.accountService.UpdateUser(c, id, req); err != nil {
 		switch {
+		case errors.Is(err, password.ErrWeakPassword):
+			response.BadRequest(c, err, nil)
+		case errors.Is(err, user.ErrEmailTaken):
+			response.Conflict(c, err)
 		case errors.Is(err, store.ErrorNotFound):
 			response.NotFound(c, err)
 		default:
@@ -157,7 +192,7 @@ func (h *UserHandler) update(c *gin.Context) {
 
 // delete godoc
 // @Summary Delete a user
-// @Description Delete user by ID
+// @Description Delete user by ID. Requires the admin role.
 // @Tags users
 // @Accept  json
 // @Produce  json
@@ -165,6 +200,7 @@ func (h *UserHandler) update(c *gin.Context) {
 // @Param id path string true "User ID"
 // @Success 200 {string} string "User deleted"
 // @Failure 404 {object} response.Object "User not found"
+// @Failure 403 {object} response.Object "Forbidden"
 // @Failure 500 {object} response.Object "Internal Server Error"
 // @Router /users/{id} [delete]
 func (h *UserHandler) delete(c *gin.Context) {
@@ -183,42 +219,162 @@ func (h *UserHandler) delete(c *gin.Context) {
 	response.OK(c, "User deleted")
 }
 
+// setTemporaryPassword godoc
+// @Summary Set a temporary password
+// @Description Replace the user's password with a random one that must be changed on the next login. The user is signed out everywhere. Requires the admin role.
+// @Tags users
+// @Produce  json
+// @Security BearerAuth
+// @Param id path string true "User ID"
+// @Success 200 {object} TemporaryPasswordResponse "Temporary password"
+// @Failure 404 {object} response.Object "User not found"
+// @Failure 403 {object} response.Object "Forbidden"
+// @Failure 500 {object} response.Object "Internal Server Error"
+// @Router /users/{id}/temporary-password [post]
+func (h *UserHandler) setTemporaryPassword(c *gin.Context) {
+	id := c.Param("id")
+
+	temporary, err := h.accountService.SetTemporaryPassword(c, id)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrorNotFound):
+			response.NotFound(c, err)
+		default:
+			response.InternalServerError(c, err)
+		}
+		return
+	}
+
+	response.OK(c, TemporaryPasswordResponse{TemporaryPassword: temporary})
+}
+
+// requirePasswordChange godoc
+// @Summary Force a password change
+// @Description Make the user choose a new password on the next login. The user is signed out everywhere. Requires the admin role.
+// @Tags users
+// @Produce  json
+// @Security BearerAuth
+// @Param id path string true "User ID"
+// @Success 200 {string} string "ok"
+// @Failure 404 {object} response.Object "User not found"
+// @Failure 403 {object} response.Object "Forbidden"
+// @Failure 500 {object} response.Object "Internal Server Error"
+// @Router /users/{id}/require-password-change [post]
+func (h *UserHandler) requirePasswordChange(c *gin.Context) {
+	id := c.Param("id")
+
+	if err := h.accountService.RequirePasswordChange(c, id); err != nil {
+		switch {
+		case errors.Is(err, store.ErrorNotFound):
+			response.NotFound(c, err)
+		default:
+			response.InternalServerError(c, err)
+		}
+		return
+	}
+
+	response.OK(c, "ok")
+}
+
 // search godoc
 // @Summary Search users
-// @Description Search users by name or email
+// @Description Search the user directory by case-insensitive partial name or email, one page at a time. The total number of matches is returned in the X-Total-Count header. Requires the admin role.
 // @Tags users
 // @Accept  json
 // @Produce  json
 // @Security BearerAuth
-// @Param name query string false "Name"
-// @Param email query string false "Email"
+// @Param q query string false "Matches name or email"
+// @Param name query string false "Matches name"
+// @Param email query string false "Matches email"
+// @Param role query string false "Role" Enums(user, admin)
+// @Param sortBy query string false "Field to sort by; relevance ranks prefix matches of q first" Enums(relevance, name, email, created_at)
+// @Param sortOrder query string false "Sort order (asc or desc)" Enums(asc, desc)
+// @Param page query int false "Page number for pagination" default(1)
+// @Param limit query int false "Number of users per page, at most 100" default(20)
 // @Success 200 {array} user.Response "List of users matching the search criteria"
+// @Header 200 {integer} X-Total-Count "Number of matches on all pages"
 // @Failure 400 {object} response.Object "Bad Request"
+// @Failure 403 {object} response.Object "Forbidden"
 // @Failure 500 {object} response.Object "Internal Server Error"
 // @Router /users/search [get]
 func (h *UserHandler) search(c *gin.Context) {
-	req := user.Request{
-		Name:  helpers.GetStringPtr(c.Query("name")),
-		Email: helpers.GetStringPtr(c.Query("email")),
+	req := user.SearchRequest{
+		Query:     strings.TrimSpace(c.Query("q")),
+		Name:      strings.TrimSpace(c.Query("name")),
+		Email:     strings.TrimSpace(c.Query("email")),
+		Role:      c.Query("role"),
+		SortBy:    c.Query("sortBy"),
+		SortOrder: c.Query("sortOrder"),
 	}
+	req.Page, _ = strconv.Atoi(c.Query("page"))
+	req.Limit, _ = strconv.Atoi(c.Query("limit"))
 
-	if err := req.IsEmpty("search"); err != nil {
-		response.BadRequest(c, errors.New("incorrect query"), nil)
+	if err := req.Validate(); err != nil {
+		response.BadRequest(c, err, nil)
+		return
+	}
+
+	res, total, err := h.accountService.SearchUser(c, req)
+	if err != nil {
+		response.InternalServerError(c, err)
+		return
+	}
+
+	c.Header("X-Total-Count", strconv.Itoa(total))
+	response.OK(c, res)
+}
+
+// searchDirectory godoc
+// @Summary Search workspace members
+// @Description Search the members of the current workspace by case-insensitive partial name or email, e.g. to pick someone to invite or assign. Only the ID, name and email are returned. The total number of matches is returned in the X-Total-Count header.
+// @Tags users
+// @Produce  json
+// @Security BearerAuth
+// @Param X-Workspace-ID header string false "Workspace ID, defaults to the first workspace of the user"
+// @Param q query string false "Matches name or email"
+// @Param name query string false "Matches name"
+// @Param email query string false "Matches email"
+// @Param sortBy query string false "Field to sort by; relevance ranks prefix matches of q first" Enums(relevance, name, email, created_at)
+// @Param sortOrder query string false "Sort order (asc or desc)" Enums(asc, desc)
+// @Param page query int false "Page number for pagination" default(1)
+// @Param limit query int false "Number of users per page, at most 100" default(20)
+// @Success 200 {array} user.DirectoryResponse "Members matching the search criteria"
+// @Header 200 {integer} X-Total-Count "Number of matches on all pages"
+// @Failure 400 {object} response.Object "Bad Request"
+// @Failure 403 {object} response.Object "Forbidden"
+// @Failure 500 {object} response.Object "Internal Server Error"
+// @Router /directory [get]
+func (h *UserHandler) searchDirectory(c *gin.Context) {
+	workspaceID := c.Value("workspaceID").(string)
+
+	req := user.SearchRequest{
+		Query:     strings.TrimSpace(c.Query("q")),
+		Name:      strings.TrimSpace(c.Query("name")),
+		Email:     strings.TrimSpace(c.Query("email")),
+		SortBy:    c.Query("sortBy"),
+		SortOrder: c.Query("sortOrder"),
+	}
+	req.Page, _ = strconv.Atoi(c.Query("page"))
+	req.Limit, _ = strconv.Atoi(c.Query("limit"))
+
+	if err := req.Validate(); err != nil {
+		response.BadRequest(c, err, nil)
 		return
 	}
 
-	res, err := h.accountService.SearchUser(c, req)
+	res, total, err := h.accountService.SearchWorkspaceMembers(c, workspaceID, req)
 	if err != nil {
 		response.InternalServerError(c, err)
 		return
 	}
 
+	c.Header("X-Total-Count", strconv.Itoa(total))
 	response.OK(c, res)
 }
 
 // getByEmail godoc
 // @Summary Get user by email
-// @Description Get user details by email
+// @Description Get user details by email. Requires the admin role.
 // @Tags users
 // @Accept  json
 // @Produce  json
@@ -226,6 +382,7 @@ func (h *UserHandler) search(c *gin.Context) {
 // @Param email query string true "User Email"
 // @Success 200 {object} user.Response "User details"
 // @Failure 404 {object} response.Object "User not found"
+// @Failure 403 {object} response.Object "Forbidden"
 // @Failure 500 {object} response.Object "Internal Server Error"
 // @Router /users/email [get]
 func (h *UserHandler) getByEmail(c *gin.Context) {
@@ -0,0 +1,546 @@
+package http
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/yrss1/todo/internal/domain/user"
+	"github.com/yrss1/todo/internal/domain/workspace"
+	workspaceService "github.com/yrss1/todo/internal/service/workspace"
+	"github.com/yrss1/todo/pkg/password"
+	"github.com/yrss1/todo/pkg/server/response"
+	"github.com/yrss1/todo/pkg/store"
+)
+
+type WorkspaceHandler struct {
+	workspaceService *workspaceService.Service
+}
+
+func NewWorkspaceHandler(s *workspaceService.Service) *WorkspaceHandler {
+	return &WorkspaceHandler{workspaceService: s}
+}
+
+func (h *WorkspaceHandler) Routes(r *gin.RouterGroup) {
+	api := r.Group("/workspaces")
+	{
+		api.GET("/", h.list)
+		api.POST("/", h.add)
+
+		api.GET("/:id", h.get)
+		api.PATCH("/:id", h.update)
+		api.DELETE("/:id", h.delete)
+
+		api.GET("/:id/members", h.listMembers)
+		api.POST("/:id/members", h.addMember)
+		api.PATCH("/:id/members/:userID", h.updateMember)
+		api.DELETE("/:id/members/:userID", h.removeMember)
+
+		api.GET("/:id/invitations", h.listInvitations)
+		api.POST("/:id/invitations", h.invite)
+		api.POST("/:id/invitations/:invitationID/resend", h.resendInvitation)
+		api.DELETE("/:id/invitations/:invitationID", h.revokeInvitation)
+	}
+}
+
+// InvitationRoutes registers the routes used by invited people, who may not
+// have an account yet. Accepting runs through login, which identifies the
+// user when the request is authenticated.
+func (h *WorkspaceHandler) InvitationRoutes(r *gin.RouterGroup, login ...gin.HandlerFunc) {
+	api := r.Group("/invitations")
+	{
+		api.GET("/", h.previewInvitation)
+		api.POST("/accept", append(login, h.acceptInvitation)...)
+	}
+}
+
+// Middleware resolves the workspace of the request from the X-Workspace-ID
+// header, or the default workspace of the user without it, and stores its
+// ID as "workspaceID". Users cannot act in workspaces they are not a member
+// of.
+func (h *WorkspaceHandler) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := c.Value("userID").(string)
+
+		data, err := h.workspaceService.ResolveWorkspace(c, c.GetHeader(workspace.Header), userID)
+		if err != nil {
+			switch {
+			case errors.Is(err, store.ErrorNotFound):
+				response.Forbidden(c, errors.New("not a member of the workspace"))
+			default:
+				response.InternalServerError(c, err)
+			}
+			c.Abort()
+			return
+		}
+
+		c.Set("workspaceID", data.ID)
+		c.Set("workspaceRole", data.Role)
+		c.Header(workspace.Header, data.ID)
+		c.Next()
+	}
+}
+
+// list godoc
+// @Summary List workspaces
+// @Description List the workspaces the authenticated user is a member of, with the user's role in each. The first one is used when a request has no X-Workspace-ID header.
+// @Tags workspaces
+// @Produce  json
+// @Security BearerAuth
+// @Success 200 {array} workspace.Response "Workspaces"
+// @Failure 500 {object} response.Object "Internal Server Error"
+// @Router /workspaces [get]
+func (h *WorkspaceHandler) list(c *gin.Context) {
+	userID := c.Value("userID").(string)
+
+	res, err := h.workspaceService.ListWorkspaces(c, userID)
+	if err != nil {
+		response.InternalServerError(c, err)
+		return
+	}
+
+	response.OK(c, res)
+}
+
+// add godoc
+// @Summary Create a workspace
+// @Description Create a workspace owned by the authenticated user
+// @Tags workspaces
+// @Accept  json
+// @Produce  json
+// @Security BearerAuth
+// @Param request body workspace.Request true "Workspace name"
+// @Success 200 {object} workspace.Response "Created workspace"
+// @Failure 400 {object} response.Object "Bad Request"
+// @Failure 500 {object} response.Object "Internal Server Error"
+// @Router /workspaces [post]
+func (h *WorkspaceHandler) add(c *gin.Context) {
+	userID := c.Value("userID").(string)
+
+	req := workspace.Request{}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err, nil)
+		return
+	}
+	if err := req.Validate(); err != nil {
+		response.BadRequest(c, err, nil)
+		return
+	}
+
+	res, err := h.workspaceService.CreateWorkspace(c, userID, req)
+	if err != nil {
+		response.InternalServerError(c, err)
+		return
+	}
+
+	response.OK(c, res)
+}
+
+// get godoc
+// @Summary Get a workspace
+// @Description Get a workspace the authenticated user is a member of
+// @Tags workspaces
+// @Produce  json
+// @Security BearerAuth
+// @Param id path string true "Workspace ID"
+// @Success 200 {object} workspace.Response "Workspace"
+// @Failure 404 {object} response.Object "Workspace not found"
+// @Failure 500 {object} response.Object "Internal Server Error"
+// @Router /workspaces/{id} [get]
+func (h *WorkspaceHandler) get(c *gin.Context) {
+	userID := c.Value("userID").(string)
+	id := c.Param("id")
+
+	res, err := h.workspaceService.GetWorkspace(c, id, userID)
+	if err != nil {
+		h.error(c, err)
+		return
+	}
+
+	response.OK(c, res)
+}
+
+// update godoc
+// @Summary Rename a workspace
+// @Description Rename a workspace. Requires the owner or admin role in it.
+// @Tags workspaces
+// @Accept  json
+// @Produce  json
+// @Security BearerAuth
+// @Param id path string true "Workspace ID"
+// @Param request body workspace.Request true "Workspace name"
+// @Success 200 {string} string "ok"
+// @Failure 400 {object} response.Object "Bad Request"
+// @Failure 403 {object} response.Object "Forbidden"
+// @Failure 404 {object} response.Object "Workspace not found"
+// @Failure 500 {object} response.Object "Internal Server Error"
+// @Router /workspaces/{id} [patch]
+func (h *WorkspaceHandler) update(c *gin.Context) {
+	userID := c.Value("userID").(string)
+	id := c.Param("id")
+
+	req := workspace.Request{}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err, nil)
+		return
+	}
+	if err := req.Validate(); err != nil {
+		response.BadRequest(c, err, nil)
+		return
+	}
+
+	if err := h.workspaceService.UpdateWorkspace(c, id, userID, req); err != nil {
+		h.error(c, err)
+		return
+	}
+
+	response.OK(c, "ok")
+}
+
+// delete godoc
+// @Summary Delete a workspace
+// @Description Delete a workspace with all of its tasks and templates. Requires the owner role in it.
+// @Tags workspaces
+// @Security BearerAuth
+// @Param id path string true "Workspace ID"
+// @Success 200 {string} string "ok"
+// @Failure 403 {object} response.Object "Forbidden"
+// @Failure 404 {object} response.Object "Workspace not found"
+// @Failure 500 {object} response.Object "Internal Server Error"
+// @Router /workspaces/{id} [delete]
+func (h *WorkspaceHandler) delete(c *gin.Context) {
+	userID := c.Value("userID").(string)
+	id := c.Param("id")
+
+	if err := h.workspaceService.DeleteWorkspace(c, id, userID); err != nil {
+		h.error(c, err)
+		return
+	}
+
+	response.OK(c, "ok")
+}
+
+// listMembers godoc
+// @Summary List workspace members
+// @Description List the members of a workspace the authenticated user belongs to
+// @Tags workspaces
+// @Produce  json
+// @Security BearerAuth
+// @Param id path string true "Workspace ID"
+// @Success 200 {array} workspace.MemberResponse "Members"
+// @Failure 404 {object} response.Object "Workspace not found"
+// @Failure 500 {object} response.Object "Internal Server Error"
+// @Router /workspaces/{id}/members [get]
+func (h *WorkspaceHandler) listMembers(c *gin.Context) {
+	userID := c.Value("userID").(string)
+	id := c.Param("id")
+
+	res, err := h.workspaceService.ListMembers(c, id, userID)
+	if err != nil {
+		h.error(c, err)
+		return
+	}
+
+	response.OK(c, res)
+}
+
+// addMember godoc
+// @Summary Add a workspace member
+// @Description Add a registered user to the workspace by email. Requires the owner or admin role; only owners can add owners.
+// @Tags workspaces
+// @Accept  json
+// @Produce  json
+// @Security BearerAuth
+// @Param id path string true "Workspace ID"
+// @Param request body workspace.MemberRequest true "Email and role"
+// @Success 200 {object} workspace.MemberResponse "Added member"
+// @Failure 400 {object} response.Object "Bad Request"
+// @Failure 403 {object} response.Object "Forbidden"
+// @Failure 404 {object} response.Object "Workspace or user not found"
+// @Failure 409 {object} response.Object "User is already a member"
+// @Failure 500 {object} response.Object "Internal Server Error"
+// @Router /workspaces/{id}/members [post]
+func (h *WorkspaceHandler) addMember(c *gin.Context) {
+	userID := c.Value("userID").(string)
+	id := c.Param("id")
+
+	req := workspace.MemberRequest{}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err, nil)
+		return
+	}
+	if err := req.Validate(); err != nil {
+		response.BadRequest(c, err, nil)
+		return
+	}
+
+	res, err := h.workspaceService.AddMember(c, id, userID, req)
+	if err != nil {
+		h.error(c, err)
+		return
+	}
+
+	response.OK(c, res)
+}
+
+// updateMember godoc
+// @Summary Change a member's role
+// @Description Change the role of a workspace member. Requires the owner or admin role; admins cannot change owners or make anyone an owner.
+// @Tags workspaces
+// @Accept  json
+// @Produce  json
+// @Security BearerAuth
+// @Param id path string true "Workspace ID"
+// @Param userID path string true "User ID of the member"
+// @Param request body workspace.RoleRequest true "Role"
+// @Success 200 {string} string "ok"
+// @Failure 400 {object} response.Object "Bad Request"
+// @Failure 403 {object} response.Object "Forbidden"
+// @Failure 404 {object} response.Object "Workspace or member not found"
+// @Failure 409 {object} response.Object "The workspace would have no owner"
+// @Failure 500 {object} response.Object "Internal Server Error"
+// @Router /workspaces/{id}/members/{userID} [patch]
+func (h *WorkspaceHandler) updateMember(c *gin.Context) {
+	userID := c.Value("userID").(string)
+	id := c.Param("id")
+	memberID := c.Param("userID")
+
+	req := workspace.RoleRequest{}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err, nil)
+		return
+	}
+	if err := req.Validate(); err != nil {
+		response.BadRequest(c, err, nil)
+		return
+	}
+
+	if err := h.workspaceService.UpdateMember(c, id, userID, memberID, req); err != nil {
+		h.error(c, err)
+		return
+	}
+
+	response.OK(c, "ok")
+}
+
+// removeMember godoc
+// @Summary Remove a workspace member
+// @Description Remove a member from the workspace, or leave it by passing your own user ID. The member's tasks stay in the workspace. Requires the owner or admin role to remove others.
+// @Tags workspaces
+// @Security BearerAuth
+// @Param id path string true "Workspace ID"
+// @Param userID path string true "User ID of the member"
+// @Success 200 {string} string "ok"
+// @Failure 403 {object} response.Object "Forbidden"
+// @Failure 404 {object} response.Object "Workspace or member not found"
+// @Failure 409 {object} response.Object "The workspace would have no owner"
+// @Failure 500 {object} response.Object "Internal Server Error"
+// @Router /workspaces/{id}/members/{userID} [delete]
+func (h *WorkspaceHandler) removeMember(c *gin.Context) {
+	userID := c.Value("userID").(string)
+	id := c.Param("id")
+	memberID := c.Param("userID")
+
+	if err := h.workspaceService.RemoveMember(c, id, userID, memberID); err != nil {
+		h.error(c, err)
+		return
+	}
+
+	response.OK(c, "ok")
+}
+
+// listInvitations godoc
+// @Summary List pending invitations
+// @Description List the invitations of a workspace that were neither accepted nor revoked, including expired ones. Requires the owner or admin role.
+// @Tags workspaces
+// @Produce  json
+// @Security BearerAuth
+// @Param id path string true "Workspace ID"
+// @Success 200 {array} workspace.InvitationResponse "Invitations"
+// @Failure 403 {object} response.Object "Forbidden"
+// @Failure 404 {object} response.Object "Workspace not found"
+// @Failure 500 {object} response.Object "Internal Server Error"
+// @Router /workspaces/{id}/invitations [get]
+func (h *WorkspaceHandler) listInvitations(c *gin.Context) {
+	userID := c.Value("userID").(string)
+	id := c.Param("id")
+
+	res, err := h.workspaceService.ListInvitations(c, id, userID)
+	if err != nil {
+		h.error(c, err)
+		return
+	}
+
+	response.OK(c, res)
+}
+
+// invite godoc
+// @Summary Invite someone to a workspace
+// @Description Email a link to join the workspace with a preset role. The address does not need an account. Inviting the same address again replaces the earlier invitation. Requires the owner or admin role; only owners can invite owners.
+// @Tags workspaces
+// @Accept  json
+// @Produce  json
+// @Security BearerAuth
+// @Param id path string true "Workspace ID"
+// @Param request body workspace.InvitationRequest true "Email and role"
+// @Success 200 {object} workspace.InvitationResponse "Invitation"
+// @Failure 400 {object} response.Object "Bad Request"
+// @Failure 403 {object} response.Object "Forbidden"
+// @Failure 404 {object} response.Object "Workspace not found"
+// @Failure 409 {object} response.Object "User is already a member"
+// @Failure 500 {object} response.Object "Internal Server Error"
+// @Router /workspaces/{id}/invitations [post]
+func (h *WorkspaceHandler) invite(c *gin.Context) {
+	userID := c.Value("userID").(string)
+	id := c.Param("id")
+
+	req := workspace.InvitationRequest{}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err, nil)
+		return
+	}
+	if err := req.Validate(); err != nil {
+		response.BadRequest(c, err, nil)
+		return
+	}
+
+	res, err := h.workspaceService.Invite(c, id, userID, req)
+	if err != nil {
+		h.error(c, err)
+		return
+	}
+
+	response.OK(c, res)
+}
+
+// resendInvitation godoc
+// @Summary Resend an invitation
+// @Description Email a new link for a pending invitation. The previous link stops working and the invitation is valid for another week. Requires the owner or admin role.
+// @Tags workspaces
+// @Produce  json
+// @Security BearerAuth
+// @Param id path string true "Workspace ID"
+// @Param invitationID path string true "Invitation ID"
+// @Success 200 {object} workspace.InvitationResponse "Invitation"
+// @Failure 403 {object} response.Object "Forbidden"
+// @Failure 404 {object} response.Object "Workspace or invitation not found"
+// @Failure 500 {object} response.Object "Internal Server Error"
+// @Router /workspaces/{id}/invitations/{invitationID}/resend [post]
+func (h *WorkspaceHandler) resendInvitation(c *gin.Context) {
+	userID := c.Value("userID").(string)
+	id := c.Param("id")
+	invitationID := c.Param("invitationID")
+
+	res, err := h.workspaceService.ResendInvitation(c, id, userID, invitationID)
+	if err != nil {
+		h.error(c, err)
+		return
+	}
+
+	response.OK(c, res)
+}
+
+// revokeInvitation godoc
+// @Summary Revoke an invitation
+// @Description Invalidate a pending invitation. Requires the owner or admin role.
+// @Tags workspaces
+// @Security BearerAuth
+// @Param id path string true "Workspace ID"
+// @Param invitationID path string true "Invitation ID"
+// @Success 200 {string} string "ok"
+// @Failure 403 {object} response.Object "Forbidden"
+// @Failure 404 {object} response.Object "Workspace or invitation not found"
+// @Failure 500 {object} response.Object "Internal Server Error"
+// @Router /workspaces/{id}/invitations/{invitationID} [delete]
+func (h *WorkspaceHandler) revokeInvitation(c *gin.Context) {
+	userID := c.Value("userID").(string)
+	id := c.Param("id")
+	invitationID := c.Param("invitationID")
+
+	if err := h.workspaceService.RevokeInvitation(c, id, userID, invitationID); err != nil {
+		h.error(c, err)
+		return
+	}
+
+	response.OK(c, "ok")
+}
+
+// previewInvitation godoc
+// @Summary Preview an invitation
+// @Description Describe the workspace, invited address, role and expiry of the invitation of a token.
+// @Tags invitations
+// @Produce  json
+// @Param token query string true "Token from the invitation link"
+// @Success 200 {object} workspace.InvitationPreviewResponse "Invitation"
+// @Failure 400 {object} response.Object "Invalid or expired invitation"
+// @Failure 500 {object} response.Object "Internal Server Error"
+// @Router /invitations [get]
+func (h *WorkspaceHandler) previewInvitation(c *gin.Context) {
+	token := c.Query("token")
+	if token == "" {
+		response.BadRequest(c, errors.New("token: cannot be blank"), nil)
+		return
+	}
+
+	res, err := h.workspaceService.PreviewInvitation(c, token)
+	if err != nil {
+		h.error(c, err)
+		return
+	}
+
+	response.OK(c, res)
+}
+
+// acceptInvitation godoc
+// @Summary Accept an invitation
+// @Description Join the workspace of an invitation. When an account with the invited address exists, the request must be authenticated as that account. Otherwise it is sent without authentication, and an account is created with the given name and password. The invited address counts as verified. Log in afterwards to get tokens for a new account.
+// @Tags invitations
+// @Accept  json
+// @Produce  json
+// @Security BearerAuth
+// @Param request body workspace.AcceptInvitationRequest true "Token, and name and password for a new account"
+// @Success 200 {object} workspace.AcceptInvitationResponse "Joined workspace"
+// @Failure 400 {object} response.Object "Invalid or expired invitation, or missing account details"
+// @Failure 401 {object} response.Object "The invited account has to log in first"
+// @Failure 403 {object} response.Object "Logged in as another account"
+// @Failure 409 {object} response.Object "Invitation was already accepted"
+// @Failure 500 {object} response.Object "Internal Server Error"
+// @Router /invitations/accept [post]
+func (h *WorkspaceHandler) acceptInvitation(c *gin.Context) {
+	req := workspace.AcceptInvitationRequest{}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err, nil)
+		return
+	}
+	if err := req.Validate(); err != nil {
+		response.BadRequest(c, err, nil)
+		return
+	}
+
+	userID, _ := c.Value("userID").(string)
+
+	res, err := h.workspaceService.AcceptInvitation(c, userID, req)
+	if err != nil {
+		h.error(c, err)
+		return
+	}
+
+	response.OK(c, res)
+}
+
+func (h *WorkspaceHandler) error(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, store.ErrorNotFound):
+		response.NotFound(c, err)
+	case errors.Is(err, workspaceService.ErrForbidden), errors.Is(err, workspaceService.ErrOtherAccount):
+		response.Forbidden(c, err)
+	case errors.Is(err, workspaceService.ErrLoginRequired):
+		response.Unauthorized(c, err)
+	case errors.Is(err, workspaceService.ErrLastOwner), errors.Is(err, workspaceService.ErrAlreadyMember),
+		errors.Is(err, workspaceService.ErrInvitationAccepted), errors.Is(err, user.ErrEmailTaken):
+		response.Conflict(c, err)
+	case errors.Is(err, workspaceService.ErrInvalidInvitation), errors.Is(err, workspaceService.ErrAccountRequired),
+		errors.Is(err, password.ErrWeakPassword):
+		response.BadRequest(c, err, nil)
+	default:
+		response.InternalServerError(c, err)
+	}
+}
@@ -0,0 +1,97 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/jmoiron/sqlx"
+	"github.com/yrss1/todo/internal/domain/accesstoken"
+	"github.com/yrss1/todo/pkg/store"
+)
+
+type AccessTokenRepository struct {
+	db *sqlx.DB
+}
+
+func NewAccessTokenRepository(db *sqlx.DB) *AccessTokenRepository {
+	return &AccessTokenRepository{db: db}
+}
+
+// List returns the tokens of the user that were not revoked, including
+// expired ones so their owner can see why a script stopped working.
+func (r *AccessTokenRepository) List(ctx context.Context, userID string) (dest []accesstoken.Entity, err error) {
+	query := `
+		SELECT id, user_id, name, token_hash, scopes, expires_at, last_used_at, revoked_at, created_at
+		FROM personal_access_tokens
+		WHERE user_id = $1 AND revoked_at IS NULL
+		ORDER BY created_at DESC`
+
+	args := []any{userID}
+
+	err = r.db.SelectContext(ctx, &dest, query, args...)
+
+	return
+}
+
+func (r *AccessTokenRepository) Add(ctx context.Context, data accesstoken.Entity) (dest accesstoken.Entity, err error) {
+	query := `
+		INSERT INTO personal_access_tokens (user_id, name, token_hash, scopes, expires_at)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, user_id, name, token_hash, scopes, expires_at, last_used_at, revoked_at, created_at`
+
+	args := []any{data.UserID, data.Name, data.TokenHash, data.Scopes, data.ExpiresAt}
+
+	err = r.db.GetContext(ctx, &dest, query, args...)
+
+	return
+}
+
+func (r *AccessTokenRepository) GetByHash(ctx context.Context, tokenHash string) (dest accesstoken.Entity, err error) {
+	query := `
+		SELECT id, user_id, name, token_hash, scopes, expires_at, last_used_at, revoked_at, created_at
+		FROM personal_access_tokens
+		WHERE token_hash = $1`
+
+	args := []any{tokenHash}
+
+	if err = r.db.GetContext(ctx, &dest, query, args...); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			err = store.ErrorNotFound
+		}
+	}
+
+	return
+}
+
+// Touch records that the token was used. Updates are limited to one per
+// minute so busy scripts do not write on every request.
+func (r *AccessTokenRepository) Touch(ctx context.Context, id string) (err error) {
+	query := `
+		UPDATE personal_access_tokens
+		SET last_used_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND (last_used_at IS NULL OR last_used_at < CURRENT_TIMESTAMP - INTERVAL '1 minute')`
+
+	args := []any{id}
+
+	_, err = r.db.ExecContext(ctx, query, args...)
+
+	return
+}
+
+func (r *AccessTokenRepository) Revoke(ctx context.Context, userID string, id string) (err error) {
+	query := `
+		UPDATE personal_access_tokens
+		SET revoked_at = CURRENT_TIMESTAMP
+		WHERE user_id = $1 AND id = $2 AND revoked_at IS NULL
+		RETURNING id`
+
+	args := []any{userID, id}
+
+	if err = r.db.QueryRowContext(ctx, query, args...).Scan(&id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			err = store.ErrorNotFound
+		}
+	}
+
+	return
+}
@@ -0,0 +1,118 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"github.com/jmoiron/sqlx"
+	"github.com/yrss1/todo/internal/domain/audit"
+	"strings"
+)
+
+const auditColumns = `id, created_at, action, actor_id, target_id, ip, user_agent, request_id, details`
+
+type AuditRepository struct {
+	db *sqlx.DB
+}
+
+func NewAuditRepository(db *sqlx.DB) *AuditRepository {
+	return &AuditRepository{db: db}
+}
+
+func (r *AuditRepository) Add(ctx context.Context, data audit.Entity) (err error) {
+	query := `
+		INSERT INTO audit_events (action, actor_id, target_id, ip, user_agent, request_id, details)
+		VALUES ($1, $2, $3, $4, $5, $6, COALESCE($7::jsonb, '{}'))`
+
+	args := []any{data.Action, data.ActorID, data.TargetID, data.IP, data.UserAgent, data.RequestID, nullJSON(data.Details)}
+
+	_, err = r.db.ExecContext(ctx, query, args...)
+
+	return
+}
+
+func (r *AuditRepository) List(ctx context.Context, filter audit.Filter) (dest []audit.Entity, total int, err error) {
+	where, args := r.buildFilter(filter)
+
+	if err = r.db.GetContext(ctx, &total, "SELECT COUNT(*) FROM audit_events"+where, args...); err != nil {
+		return
+	}
+
+	args = append(args, filter.Limit, (filter.Page-1)*filter.Limit)
+	query := fmt.Sprintf("SELECT %s FROM audit_events%s ORDER BY created_at DESC, id DESC LIMIT $%d OFFSET $%d",
+		auditColumns, where, len(args)-1, len(args))
+
+	err = r.db.SelectContext(ctx, &dest, query, args...)
+
+	return
+}
+
+func (r *AuditRepository) Stream(ctx context.Context, filter audit.Filter, fn func(audit.Entity) error) (err error) {
+	where, args := r.buildFilter(filter)
+	query := fmt.Sprintf("SELECT %s FROM audit_events%s ORDER BY created_at DESC, id DESC", auditColumns, where)
+
+	rows, err := r.db.QueryxContext(ctx, query, args...)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var dest audit.Entity
+		if err = rows.StructScan(&dest); err != nil {
+			return
+		}
+		if err = fn(dest); err != nil {
+			return
+		}
+	}
+
+	return rows.Err()
+}
+
+func (r *AuditRepository) buildFilter(filter audit.Filter) (where string, args []any) {
+	var conds []string
+
+	if filter.Action != "" {
+		args = append(args, filter.Action)
+		conds = append(conds, fmt.Sprintf("action=$%d", len(args)))
+	}
+
+	if filter.ActorID != "" {
+		args = append(args, filter.ActorID)
+		conds = append(conds, fmt.Sprintf("actor_id=$%d", len(args)))
+	}
+
+	if filter.TargetID != "" {
+		args = append(args, filter.TargetID)
+		conds = append(conds, fmt.Sprintf("target_id=$%d", len(args)))
+	}
+
+	if filter.IP != "" {
+		args = append(args, filter.IP)
+		conds = append(conds, fmt.Sprintf("ip=$%d", len(args)))
+	}
+
+	if filter.From != nil {
+		args = append(args, *filter.From)
+		conds = append(conds, fmt.Sprintf("created_at>=$%d", len(args)))
+	}
+
+	if filter.To != nil {
+		args = append(args, *filter.To)
+		conds = append(conds, fmt.Sprintf("created_at<$%d", len(args)))
+	}
+
+	if len(conds) > 0 {
+		where = " WHERE " + strings.Join(conds, " AND ")
+	}
+
+	return
+}
+
+// nullJSON passes empty details as NULL so the column default applies.
+func nullJSON(data []byte) any {
+	if len(data) == 0 {
+		return nil
+	}
+	return string(data)
+}
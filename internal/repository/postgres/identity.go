@@ -0,0 +1,116 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/jmoiron/sqlx"
+	"github.com/yrss1/todo/internal/domain/identity"
+	"github.com/yrss1/todo/pkg/store"
+)
+
+type IdentityRepository struct {
+	db *sqlx.DB
+}
+
+func NewIdentityRepository(db *sqlx.DB) *IdentityRepository {
+	return &IdentityRepository{db: db}
+}
+
+func (r *IdentityRepository) List(ctx context.Context, userID string) (dest []identity.Entity, err error) {
+	query := `
+		SELECT id, user_id, provider, subject, email
+		FROM user_identities
+		WHERE user_id = $1
+		ORDER BY created_at`
+
+	args := []any{userID}
+
+	err = r.db.SelectContext(ctx, &dest, query, args...)
+
+	return
+}
+
+func (r *IdentityRepository) GetBySubject(ctx context.Context, provider, subject string) (dest identity.Entity, err error) {
+	query := `
+		SELECT id, user_id, provider, subject, email
+		FROM user_identities
+		WHERE provider = $1 AND subject = $2`
+
+	args := []any{provider, subject}
+
+	if err = r.db.GetContext(ctx, &dest, query, args...); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			err = store.ErrorNotFound
+		}
+	}
+
+	return
+}
+
+func (r *IdentityRepository) Add(ctx context.Context, data identity.Entity) (id string, err error) {
+	query := `
+		INSERT INTO user_identities (user_id, provider, subject, email, last_login_at) 
+		VALUES ($1, $2, $3, $4, CURRENT_TIMESTAMP) 
+		RETURNING id`
+
+	args := []any{data.UserID, data.Provider, data.Subject, data.Email}
+
+	if err = r.db.QueryRowContext(ctx, query, args...).Scan(&id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			err = store.ErrorNotFound
+		}
+	}
+
+	return
+}
+
+func (r *IdentityRepository) Touch(ctx context.Context, id string) (err error) {
+	query := `
+		UPDATE user_identities
+		SET last_login_at = CURRENT_TIMESTAMP
+		WHERE id = $1`
+
+	args := []any{id}
+
+	_, err = r.db.ExecContext(ctx, query, args...)
+
+	return
+}
+
+// AddState stores a pending login and drops the expired ones, which keeps
+// the table from growing with abandoned logins.
+func (r *IdentityRepository) AddState(ctx context.Context, data identity.State) (err error) {
+	if _, err = r.db.ExecContext(ctx, `DELETE FROM oidc_login_states WHERE expires_at < CURRENT_TIMESTAMP`); err != nil {
+		return
+	}
+
+	query := `
+		INSERT INTO oidc_login_states (state_hash, code_verifier, nonce, expires_at)
+		VALUES ($1, $2, $3, $4)`
+
+	args := []any{data.StateHash, data.CodeVerifier, data.Nonce, data.ExpiresAt}
+
+	_, err = r.db.ExecContext(ctx, query, args...)
+
+	return
+}
+
+// ConsumeState deletes and returns an unexpired pending login, so each
+// state can complete one login only.
+func (r *IdentityRepository) ConsumeState(ctx context.Context, stateHash string) (dest identity.State, err error) {
+	query := `
+		DELETE FROM oidc_login_states
+		WHERE state_hash = $1 AND expires_at > CURRENT_TIMESTAMP
+		RETURNING state_hash, code_verifier, nonce, expires_at`
+
+	args := []any{stateHash}
+
+	if err = r.db.GetContext(ctx, &dest, query, args...); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			err = store.ErrorNotFound
+		}
+	}
+
+	return
+}
@@ -0,0 +1,82 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/jmoiron/sqlx"
+	"github.com/yrss1/todo/internal/domain/lockout"
+	"github.com/yrss1/todo/pkg/store"
+	"time"
+)
+
+type LockoutRepository struct {
+	db *sqlx.DB
+}
+
+func NewLockoutRepository(db *sqlx.DB) *LockoutRepository {
+	return &LockoutRepository{db: db}
+}
+
+func (r *LockoutRepository) Get(ctx context.Context, scope, key string) (dest lockout.Entity, err error) {
+	query := `
+		SELECT scope, key, failures, last_failed_at, locked_until
+		FROM login_attempts
+		WHERE scope = $1 AND key = $2`
+
+	args := []any{scope, key}
+
+	if err = r.db.GetContext(ctx, &dest, query, args...); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			err = store.ErrorNotFound
+		}
+	}
+
+	return
+}
+
+// RecordFailure increments the failure counter and returns the new state.
+// The counter starts over when the previous failure is older than window.
+func (r *LockoutRepository) RecordFailure(ctx context.Context, scope, key string, window time.Duration) (dest lockout.Entity, err error) {
+	query := `
+		INSERT INTO login_attempts (scope, key, failures, last_failed_at)
+		VALUES ($1, $2, 1, CURRENT_TIMESTAMP)
+		ON CONFLICT (scope, key) DO UPDATE
+		SET failures = CASE
+				WHEN login_attempts.last_failed_at < CURRENT_TIMESTAMP - make_interval(secs => $3) THEN 1
+				ELSE login_attempts.failures + 1
+			END,
+			last_failed_at = CURRENT_TIMESTAMP
+		RETURNING scope, key, failures, last_failed_at, locked_until`
+
+	args := []any{scope, key, window.Seconds()}
+
+	err = r.db.GetContext(ctx, &dest, query, args...)
+
+	return
+}
+
+func (r *LockoutRepository) Lock(ctx context.Context, scope, key string, until time.Time) (err error) {
+	query := `
+		UPDATE login_attempts
+		SET locked_until = $3
+		WHERE scope = $1 AND key = $2`
+
+	args := []any{scope, key, until}
+
+	_, err = r.db.ExecContext(ctx, query, args...)
+
+	return
+}
+
+func (r *LockoutRepository) Reset(ctx context.Context, scope, key string) (err error) {
+	query := `
+		DELETE FROM login_attempts
+		WHERE scope = $1 AND key = $2`
+
+	args := []any{scope, key}
+
+	_, err = r.db.ExecContext(ctx, query, args...)
+
+	return
+}
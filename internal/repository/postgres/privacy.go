@@ -0,0 +1,201 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/jmoiron/sqlx"
+	"github.com/yrss1/todo/internal/domain/privacy"
+	"github.com/yrss1/todo/pkg/store"
+	"time"
+)
+
+type PrivacyRepository struct {
+	db *sqlx.DB
+}
+
+func NewPrivacyRepository(db *sqlx.DB) *PrivacyRepository {
+	return &PrivacyRepository{db: db}
+}
+
+func (r *PrivacyRepository) GetDeletion(ctx context.Context, userID string) (dest privacy.Deletion, err error) {
+	query := `
+		SELECT user_id, mode, requested_at, scheduled_for
+		FROM account_deletions
+		WHERE user_id = $1`
+
+	args := []any{userID}
+
+	if err = r.db.GetContext(ctx, &dest, query, args...); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			err = store.ErrorNotFound
+		}
+	}
+
+	return
+}
+
+// ScheduleDeletion stores the deletion, replacing an earlier one of the
+// same user.
+func (r *PrivacyRepository) ScheduleDeletion(ctx context.Context, data privacy.Deletion) (dest privacy.Deletion, err error) {
+	query := `
+		INSERT INTO account_deletions (user_id, mode, scheduled_for)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (user_id) DO UPDATE
+		SET mode = EXCLUDED.mode, requested_at = CURRENT_TIMESTAMP, scheduled_for = EXCLUDED.scheduled_for
+		RETURNING user_id, mode, requested_at, scheduled_for`
+
+	args := []any{data.UserID, data.Mode, data.ScheduledFor}
+
+	err = r.db.GetContext(ctx, &dest, query, args...)
+
+	return
+}
+
+func (r *PrivacyRepository) CancelDeletion(ctx context.Context, userID string) (err error) {
+	query := `
+		DELETE FROM account_deletions
+		WHERE user_id = $1
+		RETURNING user_id`
+
+	args := []any{userID}
+
+	if err = r.db.QueryRowContext(ctx, query, args...).Scan(&userID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			err = store.ErrorNotFound
+		}
+	}
+
+	return
+}
+
+func (r *PrivacyRepository) DueDeletions(ctx context.Context, now time.Time) (dest []privacy.Deletion, err error) {
+	query := `
+		SELECT user_id, mode, requested_at, scheduled_for
+		FROM account_deletions
+		WHERE scheduled_for <= $1
+		ORDER BY scheduled_for`
+
+	args := []any{now}
+
+	err = r.db.SelectContext(ctx, &dest, query, args...)
+
+	return
+}
+
+const exportColumns = `id, user_id, status, error, octet_length(archive) AS size, requested_at, completed_at, expires_at`
+
+func (r *PrivacyRepository) AddExport(ctx context.Context, userID string) (dest privacy.Export, err error) {
+	query := `
+		INSERT INTO data_exports (user_id)
+		VALUES ($1)
+		RETURNING ` + exportColumns
+
+	args := []any{userID}
+
+	err = r.db.GetContext(ctx, &dest, query, args...)
+
+	return
+}
+
+func (r *PrivacyRepository) ListExports(ctx context.Context, userID string) (dest []privacy.Export, err error) {
+	query := `
+		SELECT ` + exportColumns + `
+		FROM data_exports
+		WHERE user_id = $1
+		ORDER BY requested_at DESC`
+
+	args := []any{userID}
+
+	err = r.db.SelectContext(ctx, &dest, query, args...)
+
+	return
+}
+
+// GetArchive returns the archive of a finished export that has not expired.
+func (r *PrivacyRepository) GetArchive(ctx context.Context, userID string, id string) (archive []byte, err error) {
+	query := `
+		SELECT archive
+		FROM data_exports
+		WHERE id = $1 AND user_id = $2 AND status = 'ready' AND expires_at > CURRENT_TIMESTAMP`
+
+	args := []any{id, userID}
+
+	if err = r.db.GetContext(ctx, &archive, query, args...); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			err = store.ErrorNotFound
+		}
+	}
+
+	return
+}
+
+// ClaimExport marks the oldest pending export as running and returns it.
+// Exports left running for longer than staleAfter, e.g. by a crashed
+// instance, are picked up again. Concurrent callers never get the same one.
+func (r *PrivacyRepository) ClaimExport(ctx context.Context, staleAfter time.Duration) (dest privacy.Export, err error) {
+	query := `
+		UPDATE data_exports
+		SET status = 'running', started_at = CURRENT_TIMESTAMP
+		WHERE id = (
+			SELECT id
+			FROM data_exports
+			WHERE status = 'pending'
+			   OR (status = 'running' AND started_at < CURRENT_TIMESTAMP - $1 * interval '1 second')
+			ORDER BY requested_at
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING ` + exportColumns
+
+	args := []any{staleAfter.Seconds()}
+
+	if err = r.db.GetContext(ctx, &dest, query, args...); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			err = store.ErrorNotFound
+		}
+	}
+
+	return
+}
+
+func (r *PrivacyRepository) CompleteExport(ctx context.Context, id string, archive []byte, expiresAt time.Time) (err error) {
+	query := `
+		UPDATE data_exports
+		SET status = 'ready', archive = $2, error = NULL, completed_at = CURRENT_TIMESTAMP, expires_at = $3
+		WHERE id = $1`
+
+	args := []any{id, archive, expiresAt}
+
+	_, err = r.db.ExecContext(ctx, query, args...)
+
+	return
+}
+
+func (r *PrivacyRepository) FailExport(ctx context.Context, id string, reason string) (err error) {
+	query := `
+		UPDATE data_exports
+		SET status = 'failed', error = $2, completed_at = CURRENT_TIMESTAMP
+		WHERE id = $1`
+
+	args := []any{id, reason}
+
+	_, err = r.db.ExecContext(ctx, query, args...)
+
+	return
+}
+
+// DeleteExpiredExports drops archives past their expiry, and failed
+// exports once they are a day old.
+func (r *PrivacyRepository) DeleteExpiredExports(ctx context.Context, now time.Time) (err error) {
+	query := `
+		DELETE FROM data_exports
+		WHERE (status = 'ready' AND expires_at <= $1)
+		   OR (status = 'failed' AND completed_at <= $1 - interval '1 day')`
+
+	args := []any{now}
+
+	_, err = r.db.ExecContext(ctx, query, args...)
+
+	return
+}
@@ -0,0 +1,86 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/jmoiron/sqlx"
+	"github.com/yrss1/todo/internal/domain/session"
+	"github.com/yrss1/todo/pkg/store"
+)
+
+type SessionRepository struct {
+	db *sqlx.DB
+}
+
+func NewSessionRepository(db *sqlx.DB) *SessionRepository {
+	return &SessionRepository{db: db}
+}
+
+// List returns the sessions of the user that were not revoked and still
+// have a refresh token that has not expired.
+func (r *SessionRepository) List(ctx context.Context, userID string) (dest []session.Entity, err error) {
+	query := `
+		SELECT s.id, s.user_id, s.family_id, s.user_agent, s.ip, s.created_at, s.last_seen_at, s.revoked_at
+		FROM sessions s
+		WHERE s.user_id = $1
+		  AND s.revoked_at IS NULL
+		  AND EXISTS (
+		      SELECT 1 FROM refresh_tokens rt
+		      WHERE rt.family_id = s.family_id AND rt.revoked_at IS NULL AND rt.expires_at > CURRENT_TIMESTAMP
+		  )
+		ORDER BY s.last_seen_at DESC`
+
+	args := []any{userID}
+
+	err = r.db.SelectContext(ctx, &dest, query, args...)
+
+	return
+}
+
+func (r *SessionRepository) Save(ctx context.Context, data session.Entity) (id string, err error) {
+	query := `
+		INSERT INTO sessions (user_id, family_id, user_agent, ip)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (family_id) DO UPDATE
+		SET user_agent = EXCLUDED.user_agent, ip = EXCLUDED.ip, last_seen_at = CURRENT_TIMESTAMP
+		RETURNING id`
+
+	args := []any{data.UserID, data.FamilyID, data.UserAgent, data.IP}
+
+	err = r.db.QueryRowContext(ctx, query, args...).Scan(&id)
+
+	return
+}
+
+func (r *SessionRepository) Get(ctx context.Context, id string) (dest session.Entity, err error) {
+	query := `
+		SELECT id, user_id, family_id, user_agent, ip, created_at, last_seen_at, revoked_at
+		FROM sessions
+		WHERE id = $1`
+
+	args := []any{id}
+
+	if err = r.db.GetContext(ctx, &dest, query, args...); err != nil {
+		if errors.Is(err, sql.ErrNoRows) || store.IsInvalidInput(err) {
+			err = store.ErrorNotFound
+		}
+	}
+
+	return
+}
+
+// Touch records activity on the session. Like access tokens, updates are
+// limited to one per minute.
+func (r *SessionRepository) Touch(ctx context.Context, id string, client session.Client) (err error) {
+	query := `
+		UPDATE sessions
+		SET last_seen_at = CURRENT_TIMESTAMP, user_agent = $2, ip = $3
+		WHERE id = $1 AND revoked_at IS NULL AND last_seen_at < CURRENT_TIMESTAMP - INTERVAL '1 minute'`
+
+	args := []any{id, client.UserAgent, client.IP}
+
+	_, err = r.db.ExecContext(ctx, query, args...)
+
+	return
+}
@@ -10,6 +10,7 @@ import (
 	"github.com/yrss1/todo/pkg/store"
 	"strconv"
 	"strings"
+	"time"
 )
 
 type TaskRepository struct {
@@ -20,17 +21,17 @@ func NewTaskRepository(db *sqlx.DB) *TaskRepository {
 	return &TaskRepository{db: db}
 }
 
-func (r *TaskRepository) List(ctx context.Context, userID, titleFilter, statusFilter, sortBy, sortOrder string, page, limit int) ([]task.Entity, error) {
+func (r *TaskRepository) List(ctx context.Context, userID, workspaceID, titleFilter, statusFilter, sortBy, sortOrder string, page, limit int) ([]task.Entity, error) {
 	var (
 		baseQuery strings.Builder
 		args      []interface{}
 		dest      []task.Entity
-		paramIdx  = 1
+		paramIdx  = 2
 		offset    = (page - 1) * limit
 	)
 
-	baseQuery.WriteString(`SELECT id, title, description, status FROM tasks WHERE user_id = $1`)
-	args = append(args, userID)
+	baseQuery.WriteString(`SELECT id, title, description, status, story_points, estimated_minutes, due_date, completed_at, tags FROM tasks WHERE user_id = $1 AND workspace_id = $2`)
+	args = append(args, userID, workspaceID)
 
 	if titleFilter != "" {
 		baseQuery.WriteString(fmt.Sprintf(` AND title ILIKE $%d`, paramIdx+1))
@@ -57,15 +58,17 @@ func (r *TaskRepository) List(ctx context.Context, userID, titleFilter, statusFi
 	return dest, err
 }
 
-func (r *TaskRepository) Add(ctx context.Context, data task.Entity) (id string, err error) {
-	query := `
-		INSERT INTO tasks (user_id, title, description, status) 
-		VALUES ($1, $2, $3, $4) 
+const insertTaskQuery = `
+		INSERT INTO tasks (user_id, workspace_id, title, description, status, story_points, estimated_minutes, due_date, tags, completed_at) 
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, COALESCE($9::text[], '{}'), CASE WHEN $5::varchar = 'done' THEN CURRENT_TIMESTAMP END) 
 		RETURNING id`
 
-	args := []any{data.UserID, data.Title, data.Description, data.Status}
+func insertTaskArgs(data task.Entity) []any {
+	return []any{data.UserID, data.WorkspaceID, data.Title, data.Description, data.Status, data.StoryPoints, data.EstimatedMinutes, data.DueDate, data.Tags}
+}
 
-	if err = r.db.QueryRowContext(ctx, query, args...).Scan(&id); err != nil {
+func (r *TaskRepository) Add(ctx context.Context, data task.Entity) (id string, err error) {
+	if err = r.db.QueryRowContext(ctx, insertTaskQuery, insertTaskArgs(data)...).Scan(&id); err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			err = store.ErrorNotFound
 		}
@@ -74,13 +77,46 @@ func (r *TaskRepository) Add(ctx context.Context, data task.Entity) (id string,
 	return
 }
 
-func (r *TaskRepository) Get(ctx context.Context, userID string, taskID string) (dest task.Entity, err error) {
+// AddMany inserts all tasks in a single transaction, so either every task
+// is stored or none of them is.
+func (r *TaskRepository) AddMany(ctx context.Context, data []task.Entity) (ids []string, err error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	stmt, err := tx.PreparexContext(ctx, insertTaskQuery)
+	if err != nil {
+		return
+	}
+	defer stmt.Close()
+
+	ids = make([]string, 0, len(data))
+	for _, object := range data {
+		var id string
+		if err = stmt.QueryRowContext(ctx, insertTaskArgs(object)...).Scan(&id); err != nil {
+			return
+		}
+		ids = append(ids, id)
+	}
+
+	err = tx.Commit()
+
+	return
+}
+
+func (r *TaskRepository) Get(ctx context.Context, userID, workspaceID string, taskID string) (dest task.Entity, err error) {
 	query := `
-	   SELECT id, title, description, status
+	   SELECT id, title, description, status, story_points, estimated_minutes, due_date, completed_at, tags
 	   FROM tasks
-	   WHERE id = $1 AND user_id = $2`
+	   WHERE id = $1 AND user_id = $2 AND workspace_id = $3`
 
-	args := []any{taskID, userID}
+	args := []any{taskID, userID, workspaceID}
 
 	if err = r.db.GetContext(ctx, &dest, query, args...); err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
@@ -90,18 +126,19 @@ func (r *TaskRepository) Get(ctx context.Context, userID string, taskID string)
 	return
 }
 
-func (r *TaskRepository) Update(ctx context.Context, userID string, taskID string, data task.Entity) (err error) {
+func (r *TaskRepository) Update(ctx context.Context, userID, workspaceID string, taskID string, data task.Entity) (err error) {
 	sets, args := r.prepareArgs(data)
 
 	if len(args) > 0 {
-		args = append(args, taskID, userID)
+		args = append(args, taskID, userID, workspaceID)
 		sets = append(sets, "updated_at=CURRENT_TIMESTAMP")
 
 		query := fmt.Sprintf(
-			"UPDATE tasks SET %s WHERE id=$%d AND user_id=$%d RETURNING id",
+			"UPDATE tasks SET %s WHERE id=$%d AND user_id=$%d AND workspace_id=$%d RETURNING id",
 			strings.Join(sets, ", "),
-			len(args)-1, // Позиция taskID
-			len(args),   // Позиция userID
+			len(args)-2, // Позиция taskID
+			len(args)-1, // Позиция userID
+			len(args),   // Позиция workspaceID
 		)
 
 		if err = r.db.QueryRowContext(ctx, query, args...).Scan(&taskID); err != nil {
@@ -114,13 +151,13 @@ func (r *TaskRepository) Update(ctx context.Context, userID string, taskID strin
 	return
 }
 
-func (r *TaskRepository) Delete(ctx context.Context, userID string, taskID string) (err error) {
+func (r *TaskRepository) Delete(ctx context.Context, userID, workspaceID string, taskID string) (err error) {
 	query := `
         DELETE FROM tasks
-        WHERE id = $1 AND user_id = $2
+        WHERE id = $1 AND user_id = $2 AND workspace_id = $3
         RETURNING id`
 
-	args := []any{taskID, userID}
+	args := []any{taskID, userID, workspaceID}
 
 	if err = r.db.QueryRowContext(ctx, query, args...).Scan(&taskID); err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
@@ -131,6 +168,99 @@ func (r *TaskRepository) Delete(ctx context.Context, userID string, taskID strin
 	return
 }
 
+// Stream runs the same filtered query as List without pagination and calls
+// fn for every row as it is read, so the result is never held in memory. An
+// empty workspaceID streams the tasks of the user in every workspace.
+func (r *TaskRepository) Stream(ctx context.Context, userID, workspaceID, titleFilter, statusFilter, sortBy, sortOrder string, fn func(task.Entity) error) (err error) {
+	query, args := r.buildQuery(userID, workspaceID, titleFilter, statusFilter, sortBy, sortOrder)
+
+	rows, err := r.db.QueryxContext(ctx, query, args...)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var dest task.Entity
+		if err = rows.StructScan(&dest); err != nil {
+			return
+		}
+		if err = fn(dest); err != nil {
+			return
+		}
+	}
+
+	return rows.Err()
+}
+
+func (r *TaskRepository) Rollup(ctx context.Context, userID, workspaceID string) (dest []task.RollupEntity, err error) {
+	query := `
+		SELECT COALESCE(status, '') AS key,
+		       COUNT(*) AS tasks,
+		       COUNT(*) FILTER (WHERE story_points IS NOT NULL OR estimated_minutes IS NOT NULL) AS estimated,
+		       COALESCE(SUM(story_points), 0) AS story_points,
+		       COALESCE(SUM(estimated_minutes), 0) AS estimated_minutes
+		FROM tasks
+		WHERE user_id = $1 AND workspace_id = $2
+		GROUP BY status
+		ORDER BY status`
+
+	args := []any{userID, workspaceID}
+
+	err = r.db.SelectContext(ctx, &dest, query, args...)
+
+	return
+}
+
+// Stats counts the tasks of the user in the workspace. Completions are
+// bucketed into days or weeks of the given time zone, so a bucket starts at
+// local midnight.
+func (r *TaskRepository) Stats(ctx context.Context, userID, workspaceID string, from, to time.Time, interval, timeZone string) (dest task.StatsEntity, err error) {
+	query := `
+		SELECT COALESCE(status, '') AS status, COUNT(*) AS tasks
+		FROM tasks
+		WHERE user_id = $1 AND workspace_id = $2
+		GROUP BY status
+		ORDER BY status`
+
+	if err = r.db.SelectContext(ctx, &dest.ByStatus, query, userID, workspaceID); err != nil {
+		return
+	}
+
+	query = `
+		SELECT p.period, COUNT(t.id) AS tasks
+		FROM generate_series(date_trunc($2, $3::timestamptz AT TIME ZONE $5), ($4::timestamptz AT TIME ZONE $5) - interval '1 microsecond', ('1 ' || $2)::interval) AS p(period)
+		LEFT JOIN tasks t
+		       ON t.user_id = $1
+		      AND t.workspace_id = $6
+		      AND t.completed_at >= GREATEST(p.period AT TIME ZONE $5, $3::timestamptz)
+		      AND t.completed_at < LEAST((p.period + ('1 ' || $2)::interval) AT TIME ZONE $5, $4::timestamptz)
+		GROUP BY p.period
+		ORDER BY p.period`
+
+	if err = r.db.SelectContext(ctx, &dest.Completed, query, userID, interval, from, to, timeZone, workspaceID); err != nil {
+		return
+	}
+
+	query = `
+		SELECT AVG(EXTRACT(EPOCH FROM completed_at - created_at))
+		FROM tasks
+		WHERE user_id = $1 AND workspace_id = $4 AND completed_at >= $2 AND completed_at < $3`
+
+	if err = r.db.GetContext(ctx, &dest.AverageCompletion, query, userID, from, to, workspaceID); err != nil {
+		return
+	}
+
+	query = `
+		SELECT COUNT(*)
+		FROM tasks
+		WHERE user_id = $1 AND workspace_id = $2 AND status <> 'done' AND due_date < CURRENT_TIMESTAMP`
+
+	err = r.db.GetContext(ctx, &dest.Overdue, query, userID, workspaceID)
+
+	return
+}
+
 func (r *TaskRepository) prepareArgs(data task.Entity) (sets []string, args []any) {
 	if data.Title != nil {
 		args = append(args, data.Title)
@@ -145,21 +275,49 @@ func (r *TaskRepository) prepareArgs(data task.Entity) (sets []string, args []an
 	if data.Status != nil {
 		args = append(args, data.Status)
 		sets = append(sets, fmt.Sprintf("status=$%d", len(args)))
+		sets = append(sets, fmt.Sprintf("completed_at=CASE WHEN $%d::varchar = 'done' THEN COALESCE(completed_at, CURRENT_TIMESTAMP) END", len(args)))
+	}
+
+	if data.StoryPoints != nil {
+		args = append(args, data.StoryPoints)
+		sets = append(sets, fmt.Sprintf("story_points=$%d", len(args)))
+	}
+
+	if data.EstimatedMinutes != nil {
+		args = append(args, data.EstimatedMinutes)
+		sets = append(sets, fmt.Sprintf("estimated_minutes=$%d", len(args)))
+	}
+
+	if data.DueDate != nil {
+		args = append(args, data.DueDate)
+		sets = append(sets, fmt.Sprintf("due_date=$%d", len(args)))
+	}
+
+	if data.Tags != nil {
+		args = append(args, data.Tags)
+		sets = append(sets, fmt.Sprintf("tags=$%d", len(args)))
 	}
 
 	return
 }
 
-func (r *TaskRepository) buildQuery(userID, titleFilter, statusFilter, sortBy, sortOrder string) (string, []interface{}) {
+func (r *TaskRepository) buildQuery(userID, workspaceID, titleFilter, statusFilter, sortBy, sortOrder string) (string, []interface{}) {
 	var queryBuilder strings.Builder
 	queryBuilder.WriteString(`
-        SELECT id, title, description, status
+        SELECT id, title, description, status, story_points, estimated_minutes, due_date, completed_at, tags
         FROM tasks
         WHERE user_id = $1`)
 
 	args := []interface{}{userID}
 
 	paramIdx := 2
+	if workspaceID != "" {
+		queryBuilder.WriteString(` AND workspace_id = $`)
+		queryBuilder.WriteString(strconv.Itoa(paramIdx))
+		args = append(args, workspaceID)
+		paramIdx++
+	}
+
 	if titleFilter != "" {
 		queryBuilder.WriteString(` AND title ILIKE $`)
 		queryBuilder.WriteString(strconv.Itoa(paramIdx))
@@ -0,0 +1,151 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/jmoiron/sqlx"
+	"github.com/yrss1/todo/internal/domain/template"
+	"github.com/yrss1/todo/pkg/store"
+	"strings"
+)
+
+type TemplateRepository struct {
+	db *sqlx.DB
+}
+
+func NewTemplateRepository(db *sqlx.DB) *TemplateRepository {
+	return &TemplateRepository{db: db}
+}
+
+// List returns the templates of the user in the workspace, or in every
+// workspace when workspaceID is empty.
+func (r *TemplateRepository) List(ctx context.Context, userID, workspaceID string) (dest []template.Entity, err error) {
+	query := `
+		SELECT id, name, title, description, status, checklist, tags
+		FROM task_templates
+		WHERE user_id = $1`
+
+	args := []any{userID}
+
+	if workspaceID != "" {
+		query += ` AND workspace_id = $2`
+		args = append(args, workspaceID)
+	}
+	query += ` ORDER BY name`
+
+	err = r.db.SelectContext(ctx, &dest, query, args...)
+
+	return
+}
+
+func (r *TemplateRepository) Add(ctx context.Context, data template.Entity) (id string, err error) {
+	query := `
+		INSERT INTO task_templates (user_id, workspace_id, name, title, description, status, checklist, tags) 
+		VALUES ($1, $2, $3, $4, $5, $6, COALESCE($7::text[], '{}'), COALESCE($8::text[], '{}')) 
+		RETURNING id`
+
+	args := []any{data.UserID, data.WorkspaceID, data.Name, data.Title, data.Description, data.Status, data.Checklist, data.Tags}
+
+	if err = r.db.QueryRowContext(ctx, query, args...).Scan(&id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			err = store.ErrorNotFound
+		}
+	}
+
+	return
+}
+
+func (r *TemplateRepository) Get(ctx context.Context, userID, workspaceID string, templateID string) (dest template.Entity, err error) {
+	query := `
+		SELECT id, name, title, description, status, checklist, tags
+		FROM task_templates
+		WHERE id = $1 AND user_id = $2 AND workspace_id = $3`
+
+	args := []any{templateID, userID, workspaceID}
+
+	if err = r.db.GetContext(ctx, &dest, query, args...); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			err = store.ErrorNotFound
+		}
+	}
+
+	return
+}
+
+func (r *TemplateRepository) Update(ctx context.Context, userID, workspaceID string, templateID string, data template.Entity) (err error) {
+	sets, args := r.prepareArgs(data)
+
+	if len(args) > 0 {
+		args = append(args, templateID, userID, workspaceID)
+		sets = append(sets, "updated_at=CURRENT_TIMESTAMP")
+
+		query := fmt.Sprintf(
+			"UPDATE task_templates SET %s WHERE id=$%d AND user_id=$%d AND workspace_id=$%d RETURNING id",
+			strings.Join(sets, ", "),
+			len(args)-2,
+			len(args)-1,
+			len(args),
+		)
+
+		if err = r.db.QueryRowContext(ctx, query, args...).Scan(&templateID); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				err = store.ErrorNotFound
+			}
+		}
+	}
+
+	return
+}
+
+func (r *TemplateRepository) Delete(ctx context.Context, userID, workspaceID string, templateID string) (err error) {
+	query := `
+		DELETE FROM task_templates
+		WHERE id = $1 AND user_id = $2 AND workspace_id = $3
+		RETURNING id`
+
+	args := []any{templateID, userID, workspaceID}
+
+	if err = r.db.QueryRowContext(ctx, query, args...).Scan(&templateID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			err = store.ErrorNotFound
+		}
+	}
+
+	return
+}
+
+func (r *TemplateRepository) prepareArgs(data template.Entity) (sets []string, args []any) {
+	if data.Name != nil {
+		args = append(args, data.Name)
+		sets = append(sets, fmt.Sprintf("name=$%d", len(args)))
+	}
+
+	if data.Title != nil {
+		args = append(args, data.Title)
+		sets = append(sets, fmt.Sprintf("title=$%d", len(args)))
+	}
+
+	if data.Description != nil {
+		args = append(args, data.Description)
+		sets = append(sets, fmt.Sprintf("description=$%d", len(args)))
+	}
+
+	if data.Status != nil {
+		args = append(args, data.Status)
+		sets = append(sets, fmt.Sprintf("status=$%d", len(args)))
+	}
+
+	if data.Checklist != nil {
+		args = append(args, data.Checklist)
+		sets = append(sets, fmt.Sprintf("checklist=$%d", len(args)))
+	}
+
+	if data.Tags != nil {
+		args = append(args, data.Tags)
+		sets = append(sets, fmt.Sprintf("tags=$%d", len(args)))
+	}
+
+	return
+}
@@ -0,0 +1,159 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/jmoiron/sqlx"
+	"github.com/yrss1/todo/internal/domain/token"
+	"github.com/yrss1/todo/pkg/store"
+	"time"
+)
+
+type TokenRepository struct {
+	db *sqlx.DB
+}
+
+func NewTokenRepository(db *sqlx.DB) *TokenRepository {
+	return &TokenRepository{db: db}
+}
+
+func (r *TokenRepository) Add(ctx context.Context, data token.Entity) (id string, err error) {
+	query := `
+		INSERT INTO refresh_tokens (user_id, family_id, token_hash, expires_at) 
+		VALUES ($1, $2, $3, $4) 
+		RETURNING id`
+
+	args := []any{data.UserID, data.FamilyID, data.TokenHash, data.ExpiresAt}
+
+	if err = r.db.QueryRowContext(ctx, query, args...).Scan(&id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			err = store.ErrorNotFound
+		}
+	}
+
+	return
+}
+
+func (r *TokenRepository) GetByHash(ctx context.Context, tokenHash string) (dest token.Entity, err error) {
+	query := `
+		SELECT id, user_id, family_id, token_hash, expires_at, used_at, revoked_at
+		FROM refresh_tokens
+		WHERE token_hash = $1`
+
+	args := []any{tokenHash}
+
+	if err = r.db.GetContext(ctx, &dest, query, args...); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			err = store.ErrorNotFound
+		}
+	}
+
+	return
+}
+
+// MarkUsed flags the token as rotated. It returns store.ErrorNotFound when
+// the token was already used or revoked, which callers treat as reuse.
+func (r *TokenRepository) MarkUsed(ctx context.Context, id string) (err error) {
+	query := `
+		UPDATE refresh_tokens
+		SET used_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND used_at IS NULL AND revoked_at IS NULL
+		RETURNING id`
+
+	args := []any{id}
+
+	if err = r.db.QueryRowContext(ctx, query, args...).Scan(&id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			err = store.ErrorNotFound
+		}
+	}
+
+	return
+}
+
+// RevokeFamily revokes the refresh tokens of the family and ends its
+// session, so access tokens issued for it stop working as well.
+func (r *TokenRepository) RevokeFamily(ctx context.Context, familyID string) (err error) {
+	query := `
+		WITH ended AS (
+			UPDATE sessions
+			SET revoked_at = CURRENT_TIMESTAMP
+			WHERE family_id = $1 AND revoked_at IS NULL
+		)
+		UPDATE refresh_tokens
+		SET revoked_at = CURRENT_TIMESTAMP
+		WHERE family_id = $1 AND revoked_at IS NULL`
+
+	args := []any{familyID}
+
+	_, err = r.db.ExecContext(ctx, query, args...)
+
+	return
+}
+
+func (r *TokenRepository) RevokeByUser(ctx context.Context, userID string) (err error) {
+	query := `
+		WITH ended AS (
+			UPDATE sessions
+			SET revoked_at = CURRENT_TIMESTAMP
+			WHERE user_id = $1 AND revoked_at IS NULL
+		)
+		UPDATE refresh_tokens
+		SET revoked_at = CURRENT_TIMESTAMP
+		WHERE user_id = $1 AND revoked_at IS NULL`
+
+	args := []any{userID}
+
+	_, err = r.db.ExecContext(ctx, query, args...)
+
+	return
+}
+
+// RevokeOtherFamilies revokes every family of the user except familyID,
+// together with their sessions.
+func (r *TokenRepository) RevokeOtherFamilies(ctx context.Context, userID, familyID string) (err error) {
+	query := `
+		WITH ended AS (
+			UPDATE sessions
+			SET revoked_at = CURRENT_TIMESTAMP
+			WHERE user_id = $1 AND family_id <> $2 AND revoked_at IS NULL
+		)
+		UPDATE refresh_tokens
+		SET revoked_at = CURRENT_TIMESTAMP
+		WHERE user_id = $1 AND family_id <> $2 AND revoked_at IS NULL`
+
+	args := []any{userID, familyID}
+
+	_, err = r.db.ExecContext(ctx, query, args...)
+
+	return
+}
+
+func (r *TokenRepository) RevokeAccess(ctx context.Context, jti string, expiresAt time.Time) (err error) {
+	query := `
+		INSERT INTO revoked_tokens (jti, expires_at)
+		VALUES ($1, $2)
+		ON CONFLICT (jti) DO NOTHING`
+
+	args := []any{jti, expiresAt}
+
+	if _, err = r.db.ExecContext(ctx, query, args...); err != nil {
+		return
+	}
+
+	// Entries are only needed until the token would have expired anyway.
+	_, err = r.db.ExecContext(ctx, `DELETE FROM revoked_tokens WHERE expires_at < CURRENT_TIMESTAMP`)
+
+	return
+}
+
+func (r *TokenRepository) IsAccessRevoked(ctx context.Context, jti string) (revoked bool, err error) {
+	query := `SELECT EXISTS (SELECT 1 FROM revoked_tokens WHERE jti = $1)`
+
+	args := []any{jti}
+
+	err = r.db.GetContext(ctx, &revoked, query, args...)
+
+	return
+}
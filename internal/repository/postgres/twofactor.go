@@ -0,0 +1,159 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/jmoiron/sqlx"
+	"github.com/yrss1/todo/internal/domain/twofactor"
+	"github.com/yrss1/todo/pkg/store"
+)
+
+type TwoFactorRepository struct {
+	db *sqlx.DB
+}
+
+func NewTwoFactorRepository(db *sqlx.DB) *TwoFactorRepository {
+	return &TwoFactorRepository{db: db}
+}
+
+func (r *TwoFactorRepository) Get(ctx context.Context, userID string) (dest twofactor.Entity, err error) {
+	query := `
+		SELECT user_id, secret, confirmed_at, last_used_step
+		FROM user_totp
+		WHERE user_id = $1`
+
+	args := []any{userID}
+
+	if err = r.db.GetContext(ctx, &dest, query, args...); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			err = store.ErrorNotFound
+		}
+	}
+
+	return
+}
+
+// Save stores a new, unconfirmed secret for the user, replacing an earlier
+// enrollment that was never confirmed.
+func (r *TwoFactorRepository) Save(ctx context.Context, data twofactor.Entity) (err error) {
+	query := `
+		INSERT INTO user_totp (user_id, secret)
+		VALUES ($1, $2)
+		ON CONFLICT (user_id) DO UPDATE
+		SET secret = EXCLUDED.secret, confirmed_at = NULL, last_used_step = 0, created_at = CURRENT_TIMESTAMP`
+
+	args := []any{data.UserID, data.Secret}
+
+	_, err = r.db.ExecContext(ctx, query, args...)
+
+	return
+}
+
+func (r *TwoFactorRepository) Confirm(ctx context.Context, userID string) (err error) {
+	query := `
+		UPDATE user_totp
+		SET confirmed_at = CURRENT_TIMESTAMP
+		WHERE user_id = $1
+		RETURNING user_id`
+
+	args := []any{userID}
+
+	if err = r.db.QueryRowContext(ctx, query, args...).Scan(&userID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			err = store.ErrorNotFound
+		}
+	}
+
+	return
+}
+
+// UseStep records the time step of an accepted code. It returns
+// store.ErrorNotFound when that step or a later one was used already, so a
+// code cannot be replayed.
+func (r *TwoFactorRepository) UseStep(ctx context.Context, userID string, step int64) (err error) {
+	query := `
+		UPDATE user_totp
+		SET last_used_step = $2
+		WHERE user_id = $1 AND last_used_step < $2
+		RETURNING user_id`
+
+	args := []any{userID, step}
+
+	if err = r.db.QueryRowContext(ctx, query, args...).Scan(&userID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			err = store.ErrorNotFound
+		}
+	}
+
+	return
+}
+
+func (r *TwoFactorRepository) Delete(ctx context.Context, userID string) (err error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	if _, err = tx.ExecContext(ctx, `DELETE FROM recovery_codes WHERE user_id = $1`, userID); err != nil {
+		return
+	}
+	if _, err = tx.ExecContext(ctx, `DELETE FROM user_totp WHERE user_id = $1`, userID); err != nil {
+		return
+	}
+
+	err = tx.Commit()
+
+	return
+}
+
+func (r *TwoFactorRepository) ReplaceRecoveryCodes(ctx context.Context, userID string, codeHashes []string) (err error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	if _, err = tx.ExecContext(ctx, `DELETE FROM recovery_codes WHERE user_id = $1`, userID); err != nil {
+		return
+	}
+
+	for _, hash := range codeHashes {
+		if _, err = tx.ExecContext(ctx, `INSERT INTO recovery_codes (user_id, code_hash) VALUES ($1, $2)`, userID, hash); err != nil {
+			return
+		}
+	}
+
+	err = tx.Commit()
+
+	return
+}
+
+// UseRecoveryCode marks an unused recovery code as used. It returns
+// store.ErrorNotFound for unknown or already used codes.
+func (r *TwoFactorRepository) UseRecoveryCode(ctx context.Context, userID string, codeHash string) (err error) {
+	query := `
+		UPDATE recovery_codes
+		SET used_at = CURRENT_TIMESTAMP
+		WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL
+		RETURNING user_id`
+
+	args := []any{userID, codeHash}
+
+	if err = r.db.QueryRowContext(ctx, query, args...).Scan(&userID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			err = store.ErrorNotFound
+		}
+	}
+
+	return
+}
@@ -21,8 +21,9 @@ func NewUserRepository(db *sqlx.DB) *UserRepository {
 
 func (r *UserRepository) List(ctx context.Context) (dest []user.Entity, err error) {
 	query := `
-		SELECT id, name, email 
+		SELECT id, name, email, role 
 		FROM users
+		WHERE anonymized_at IS NULL
 		ORDER BY id`
 
 	err = r.db.SelectContext(ctx, &dest, query)
@@ -32,15 +33,18 @@ func (r *UserRepository) List(ctx context.Context) (dest []user.Entity, err erro
 
 func (r *UserRepository) Add(ctx context.Context, data user.Entity) (id string, err error) {
 	query := `
-		INSERT INTO users (name, email, password) 
-		VALUES ($1, $2, $3) 
+		INSERT INTO users (name, email, password, role, email_verified_at) 
+		VALUES ($1, lower(btrim($2)), $3, COALESCE($4, 'user'), $5) 
 		RETURNING id`
 
-	args := []any{data.Name, data.Email, data.Password}
+	args := []any{data.Name, data.Email, data.Password, data.Role, data.EmailVerifiedAt}
 
 	if err = r.db.QueryRowContext(ctx, query, args...).Scan(&id); err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
 			err = store.ErrorNotFound
+		case store.IsUniqueViolation(err):
+			err = store.ErrorConflict
 		}
 	}
 
@@ -49,7 +53,8 @@ func (r *UserRepository) Add(ctx context.Context, data user.Entity) (id string,
 
 func (r *UserRepository) Get(ctx context.Context, id string) (dest user.Entity, err error) {
 	query := `
-		SELECT id, name, email 
+		SELECT id, name, email, password, role, pending_email, email_verified_at, password_change_required,
+		       time_zone, locale, date_format, default_task_view
 		FROM users
 		WHERE id=$1`
 
@@ -74,8 +79,11 @@ func (r *UserRepository) Update(ctx context.Context, id string, data user.Entity
 		query := fmt.Sprintf("UPDATE users SET %s WHERE id=$%d RETURNING id", strings.Join(sets, ", "), len(args))
 
 		if err = r.db.QueryRowContext(ctx, query, args...).Scan(&id); err != nil {
-			if errors.Is(err, sql.ErrNoRows) {
+			switch {
+			case errors.Is(err, sql.ErrNoRows):
 				err = store.ErrorNotFound
+			case store.IsUniqueViolation(err):
+				err = store.ErrorConflict
 			}
 		}
 	}
@@ -83,36 +91,219 @@ func (r *UserRepository) Update(ctx context.Context, id string, data user.Entity
 	return
 }
 
+// anonymizeQueries strip a user of personal data. The row stays so that
+// references to it, such as tasks, remain intact, but it can no longer log
+// in: the password is not a valid hash and every other credential is gone.
+var anonymizeQueries = []string{
+	`DELETE FROM login_attempts WHERE scope = 'account' AND key = (SELECT lower(email) FROM users WHERE id = $1)`,
+	`DELETE FROM user_avatars WHERE user_id = $1`,
+	`DELETE FROM user_identities WHERE user_id = $1`,
+	`DELETE FROM user_totp WHERE user_id = $1`,
+	`DELETE FROM recovery_codes WHERE user_id = $1`,
+	`DELETE FROM personal_access_tokens WHERE user_id = $1`,
+	`DELETE FROM user_tokens WHERE user_id = $1`,
+	`DELETE FROM refresh_tokens WHERE user_id = $1`,
+	`DELETE FROM sessions WHERE user_id = $1`,
+	`DELETE FROM data_exports WHERE user_id = $1`,
+}
+
+// Anonymize replaces the personal data of the user in one transaction.
+func (r *UserRepository) Anonymize(ctx context.Context, id string) (err error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	for _, query := range anonymizeQueries {
+		if _, err = tx.ExecContext(ctx, query, id); err != nil {
+			return
+		}
+	}
+
+	query := `
+		UPDATE users
+		SET name = 'Deleted user',
+		    email = 'deleted-' || id || '@invalid',
+		    pending_email = NULL,
+		    password = '!',
+		    role = 'user',
+		    email_verified_at = NULL,
+		    password_change_required = FALSE,
+		    time_zone = DEFAULT,
+		    locale = DEFAULT,
+		    date_format = DEFAULT,
+		    default_task_view = DEFAULT,
+		    anonymized_at = CURRENT_TIMESTAMP,
+		    updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1
+		RETURNING id`
+
+	if err = tx.QueryRowContext(ctx, query, id).Scan(&id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			err = store.ErrorNotFound
+		}
+		return
+	}
+
+	err = tx.Commit()
+
+	return
+}
+
+// ChangeEmail switches the user to a confirmed new address and clears the
+// pending change.
+func (r *UserRepository) ChangeEmail(ctx context.Context, id string, email string) (err error) {
+	query := `
+		UPDATE users
+		SET email = lower(btrim($2)), pending_email = NULL, email_verified_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1
+		RETURNING id`
+
+	args := []any{id, email}
+
+	if err = r.db.QueryRowContext(ctx, query, args...).Scan(&id); err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			err = store.ErrorNotFound
+		case store.IsUniqueViolation(err):
+			err = store.ErrorConflict
+		}
+	}
+
+	return
+}
+
+// leaveWorkspacesQueries run before a user is deleted. Workspaces only the
+// user belongs to are deleted with their tasks, and in workspaces the user
+// owned alone the longest-standing admin, or else member, becomes owner.
+var leaveWorkspacesQueries = []string{
+	`DELETE FROM workspaces w
+	 WHERE w.id IN (SELECT workspace_id FROM workspace_members WHERE user_id = $1)
+	   AND NOT EXISTS (SELECT 1 FROM workspace_members m WHERE m.workspace_id = w.id AND m.user_id <> $1)`,
+	`UPDATE workspace_members m
+	 SET role = 'owner'
+	 FROM (
+	     SELECT DISTINCT ON (workspace_id) workspace_id, user_id
+	     FROM workspace_members
+	     WHERE user_id <> $1
+	       AND workspace_id IN (SELECT workspace_id FROM workspace_members WHERE user_id = $1 AND role = 'owner')
+	       AND workspace_id NOT IN (SELECT workspace_id FROM workspace_members WHERE user_id <> $1 AND role = 'owner')
+	     ORDER BY workspace_id, role = 'admin' DESC, created_at
+	 ) heir
+	 WHERE m.workspace_id = heir.workspace_id AND m.user_id = heir.user_id`,
+}
+
 func (r *UserRepository) Delete(ctx context.Context, id string) (err error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	for _, query := range leaveWorkspacesQueries {
+		if _, err = tx.ExecContext(ctx, query, id); err != nil {
+			return
+		}
+	}
+
 	query := `
 		DELETE FROM users
 		WHERE id=$1
 		RETURNING id`
 
-	args := []any{id}
-
-	if err = r.db.QueryRowContext(ctx, query, args...).Scan(&id); err != nil {
+	if err = tx.QueryRowContext(ctx, query, id).Scan(&id); err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			err = store.ErrorNotFound
 		}
+		return
 	}
 
+	err = tx.Commit()
+
 	return
 }
 
-func (r *UserRepository) Search(ctx context.Context, data user.Entity) (dest []user.Entity, err error) {
-	query := "SELECT id, name, email FROM users WHERE 1=1"
+// userSortColumns maps the sort options of a search to columns. Relevance
+// is handled separately since it depends on the query.
+var userSortColumns = map[string]string{
+	"name":       "name",
+	"email":      "email",
+	"created_at": "created_at",
+}
 
-	sets, args := r.prepareArgs(data)
-	if len(sets) > 0 {
-		query += " AND " + strings.Join(sets, " AND ")
+// Search returns one page of users matching req and the number of matches
+// on all pages. Only name, email and role are ever compared.
+func (r *UserRepository) Search(ctx context.Context, req user.SearchRequest) (dest []user.Entity, total int, err error) {
+	var (
+		conds = []string{"anonymized_at IS NULL"}
+		args  []any
+	)
+
+	if req.Query != "" {
+		args = append(args, "%"+escapeLike(req.Query)+"%")
+		conds = append(conds, fmt.Sprintf("(name ILIKE $%d OR email ILIKE $%d)", len(args), len(args)))
+	}
+
+	if req.Name != "" {
+		args = append(args, "%"+escapeLike(req.Name)+"%")
+		conds = append(conds, fmt.Sprintf("name ILIKE $%d", len(args)))
+	}
+
+	if req.Email != "" {
+		args = append(args, "%"+escapeLike(req.Email)+"%")
+		conds = append(conds, fmt.Sprintf("email ILIKE $%d", len(args)))
+	}
+
+	if req.Role != "" {
+		args = append(args, req.Role)
+		conds = append(conds, fmt.Sprintf("role=$%d", len(args)))
+	}
+
+	if req.WorkspaceID != "" {
+		args = append(args, req.WorkspaceID)
+		conds = append(conds, fmt.Sprintf("id IN (SELECT user_id FROM workspace_members WHERE workspace_id=$%d)", len(args)))
+	}
+
+	where := " WHERE " + strings.Join(conds, " AND ")
+
+	if err = r.db.GetContext(ctx, &total, "SELECT COUNT(*) FROM users"+where, args...); err != nil {
+		return
+	}
+
+	order := "name, id"
+	if column, ok := userSortColumns[req.SortBy]; ok {
+		order = fmt.Sprintf("%s %s, id", column, req.SortOrder)
+	} else if req.Query != "" {
+		// Prefix matches first, the way a picker is typed into, then the
+		// closest trigram matches.
+		args = append(args, escapeLike(req.Query)+"%", req.Query)
+		order = fmt.Sprintf("(name ILIKE $%d OR email ILIKE $%d) DESC, GREATEST(similarity(name, $%d), similarity(email, $%d)) DESC, name, id",
+			len(args)-1, len(args)-1, len(args), len(args))
 	}
 
+	args = append(args, req.Limit, (req.Page-1)*req.Limit)
+	query := fmt.Sprintf("SELECT id, name, email, role FROM users%s ORDER BY %s LIMIT $%d OFFSET $%d",
+		where, order, len(args)-1, len(args))
+
 	err = r.db.SelectContext(ctx, &dest, query, args...)
 
 	return
 }
 
+// escapeLike makes s match literally inside a LIKE pattern.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
+
 func (r *UserRepository) prepareArgs(data user.Entity) (sets []string, args []any) {
 	if data.Name != nil {
 		args = append(args, data.Name)
@@ -121,7 +312,7 @@ func (r *UserRepository) prepareArgs(data user.Entity) (sets []string, args []an
 
 	if data.Email != nil {
 		args = append(args, data.Email)
-		sets = append(sets, fmt.Sprintf("email=$%d", len(args)))
+		sets = append(sets, fmt.Sprintf("email=lower(btrim($%d))", len(args)))
 	}
 
 	if data.Password != nil {
@@ -129,11 +320,55 @@ func (r *UserRepository) prepareArgs(data user.Entity) (sets []string, args []an
 		sets = append(sets, fmt.Sprintf("password=$%d", len(args)))
 	}
 
+	if data.Role != nil {
+		args = append(args, data.Role)
+		sets = append(sets, fmt.Sprintf("role=$%d", len(args)))
+	}
+
+	if data.PendingEmail != nil {
+		args = append(args, data.PendingEmail)
+		sets = append(sets, fmt.Sprintf("pending_email=lower(btrim($%d))", len(args)))
+	}
+
+	if data.EmailVerifiedAt != nil {
+		args = append(args, data.EmailVerifiedAt)
+		sets = append(sets, fmt.Sprintf("email_verified_at=$%d", len(args)))
+	}
+
+	if data.PasswordChangeRequired != nil {
+		args = append(args, data.PasswordChangeRequired)
+		sets = append(sets, fmt.Sprintf("password_change_required=$%d", len(args)))
+	}
+
+	if data.TimeZone != nil {
+		args = append(args, data.TimeZone)
+		sets = append(sets, fmt.Sprintf("time_zone=$%d", len(args)))
+	}
+
+	if data.Locale != nil {
+		args = append(args, data.Locale)
+		sets = append(sets, fmt.Sprintf("locale=$%d", len(args)))
+	}
+
+	if data.DateFormat != nil {
+		args = append(args, data.DateFormat)
+		sets = append(sets, fmt.Sprintf("date_format=$%d", len(args)))
+	}
+
+	if data.DefaultTaskView != nil {
+		args = append(args, data.DefaultTaskView)
+		sets = append(sets, fmt.Sprintf("default_task_view=$%d", len(args)))
+	}
+
 	return
 }
 
 func (r *UserRepository) GetByEmail(ctx context.Context, email string) (dest user.Entity, err error) {
-	query := `SELECT id, name, email, password from users where email=$1`
+	query := `
+		SELECT id, name, email, password, role, pending_email, email_verified_at, password_change_required,
+		       time_zone, locale, date_format, default_task_view
+		FROM users
+		WHERE lower(email)=lower(btrim($1))`
 
 	args := []any{email}
 
@@ -145,3 +380,51 @@ func (r *UserRepository) GetByEmail(ctx context.Context, email string) (dest use
 
 	return
 }
+
+func (r *UserRepository) GetAvatar(ctx context.Context, id string) (dest user.Avatar, err error) {
+	query := `
+		SELECT user_id, content_type, data, updated_at
+		FROM user_avatars
+		WHERE user_id=$1`
+
+	args := []any{id}
+
+	if err = r.db.GetContext(ctx, &dest, query, args...); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			err = store.ErrorNotFound
+		}
+	}
+
+	return
+}
+
+func (r *UserRepository) SaveAvatar(ctx context.Context, data user.Avatar) (err error) {
+	query := `
+		INSERT INTO user_avatars (user_id, content_type, data)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (user_id) DO UPDATE
+		SET content_type=EXCLUDED.content_type, data=EXCLUDED.data, updated_at=CURRENT_TIMESTAMP`
+
+	args := []any{data.UserID, data.ContentType, data.Data}
+
+	_, err = r.db.ExecContext(ctx, query, args...)
+
+	return
+}
+
+func (r *UserRepository) DeleteAvatar(ctx context.Context, id string) (err error) {
+	query := `
+		DELETE FROM user_avatars
+		WHERE user_id=$1
+		RETURNING user_id`
+
+	args := []any{id}
+
+	if err = r.db.QueryRowContext(ctx, query, args...).Scan(&id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			err = store.ErrorNotFound
+		}
+	}
+
+	return
+}
@@ -0,0 +1,108 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/jmoiron/sqlx"
+	"github.com/yrss1/todo/internal/domain/verification"
+	"github.com/yrss1/todo/pkg/store"
+)
+
+type VerificationRepository struct {
+	db *sqlx.DB
+}
+
+func NewVerificationRepository(db *sqlx.DB) *VerificationRepository {
+	return &VerificationRepository{db: db}
+}
+
+func (r *VerificationRepository) Add(ctx context.Context, data verification.Entity) (id string, err error) {
+	query := `
+		INSERT INTO user_tokens (user_id, purpose, token_hash, expires_at) 
+		VALUES ($1, $2, $3, $4) 
+		RETURNING id`
+
+	args := []any{data.UserID, data.Purpose, data.TokenHash, data.ExpiresAt}
+
+	if err = r.db.QueryRowContext(ctx, query, args...).Scan(&id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			err = store.ErrorNotFound
+		}
+	}
+
+	return
+}
+
+// Get returns an unused, unexpired token without using it up.
+func (r *VerificationRepository) Get(ctx context.Context, purpose string, tokenHash string) (dest verification.Entity, err error) {
+	query := `
+		SELECT id, user_id, purpose, token_hash, expires_at, used_at, attempts
+		FROM user_tokens
+		WHERE purpose = $1 AND token_hash = $2 AND used_at IS NULL AND expires_at > CURRENT_TIMESTAMP`
+
+	args := []any{purpose, tokenHash}
+
+	if err = r.db.GetContext(ctx, &dest, query, args...); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			err = store.ErrorNotFound
+		}
+	}
+
+	return
+}
+
+// Consume marks an unused, unexpired token as used and returns it. Any other
+// token yields store.ErrorNotFound, so each token works exactly once.
+func (r *VerificationRepository) Consume(ctx context.Context, purpose string, tokenHash string) (dest verification.Entity, err error) {
+	query := `
+		UPDATE user_tokens
+		SET used_at = CURRENT_TIMESTAMP
+		WHERE purpose = $1 AND token_hash = $2 AND used_at IS NULL AND expires_at > CURRENT_TIMESTAMP
+		RETURNING id, user_id, purpose, token_hash, expires_at, used_at, attempts`
+
+	args := []any{purpose, tokenHash}
+
+	if err = r.db.GetContext(ctx, &dest, query, args...); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			err = store.ErrorNotFound
+		}
+	}
+
+	return
+}
+
+// Attempt counts a try of an unused, unexpired token and returns it. Once
+// the token was tried maxAttempts times it yields store.ErrorNotFound like an
+// unknown one, so a token can only be guessed against a limited number of
+// times.
+func (r *VerificationRepository) Attempt(ctx context.Context, purpose string, tokenHash string, maxAttempts int) (dest verification.Entity, err error) {
+	query := `
+		UPDATE user_tokens
+		SET attempts = attempts + 1
+		WHERE purpose = $1 AND token_hash = $2 AND used_at IS NULL AND expires_at > CURRENT_TIMESTAMP AND attempts < $3
+		RETURNING id, user_id, purpose, token_hash, expires_at, used_at, attempts`
+
+	args := []any{purpose, tokenHash, maxAttempts}
+
+	if err = r.db.GetContext(ctx, &dest, query, args...); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			err = store.ErrorNotFound
+		}
+	}
+
+	return
+}
+
+func (r *VerificationRepository) Invalidate(ctx context.Context, userID string, purpose string) (err error) {
+	query := `
+		UPDATE user_tokens
+		SET used_at = CURRENT_TIMESTAMP
+		WHERE user_id = $1 AND purpose = $2 AND used_at IS NULL`
+
+	args := []any{userID, purpose}
+
+	_, err = r.db.ExecContext(ctx, query, args...)
+
+	return
+}
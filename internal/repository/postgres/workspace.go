@@ -0,0 +1,455 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/jmoiron/sqlx"
+	"github.com/yrss1/todo/internal/domain/workspace"
+	"github.com/yrss1/todo/pkg/store"
+	"time"
+)
+
+type WorkspaceRepository struct {
+	db *sqlx.DB
+}
+
+func NewWorkspaceRepository(db *sqlx.DB) *WorkspaceRepository {
+	return &WorkspaceRepository{db: db}
+}
+
+// List returns the workspaces the user is a member of, oldest membership
+// first.
+func (r *WorkspaceRepository) List(ctx context.Context, userID string) (dest []workspace.Entity, err error) {
+	query := `
+		SELECT w.id, w.name, w.created_at, m.role
+		FROM workspaces w
+		JOIN workspace_members m ON m.workspace_id = w.id
+		WHERE m.user_id = $1
+		ORDER BY m.created_at, w.id`
+
+	args := []any{userID}
+
+	err = r.db.SelectContext(ctx, &dest, query, args...)
+
+	return
+}
+
+// Add creates the workspace and its owner in one transaction.
+func (r *WorkspaceRepository) Add(ctx context.Context, name, ownerID string) (dest workspace.Entity, err error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	query := `
+		INSERT INTO workspaces (name)
+		VALUES ($1)
+		RETURNING id, name, created_at`
+
+	if err = tx.GetContext(ctx, &dest, query, name); err != nil {
+		return
+	}
+
+	query = `
+		INSERT INTO workspace_members (workspace_id, user_id, role)
+		VALUES ($1, $2, $3)`
+
+	if _, err = tx.ExecContext(ctx, query, dest.ID, ownerID, workspace.RoleOwner); err != nil {
+		return
+	}
+	dest.Role = workspace.RoleOwner
+
+	err = tx.Commit()
+
+	return
+}
+
+// AddPersonal creates the personal workspace of ownerID, relying on the
+// unique index on personal workspaces so that concurrent calls create only
+// one, and returns whichever exists afterwards. A personal workspace the
+// owner was removed from belongs to its remaining members; it stops being
+// personal and a new one is created.
+func (r *WorkspaceRepository) AddPersonal(ctx context.Context, name, ownerID string) (dest workspace.Entity, err error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	query := `
+		UPDATE workspaces w
+		SET personal = false
+		WHERE w.owner_id = $1 AND w.personal
+		  AND NOT EXISTS (SELECT 1 FROM workspace_members m WHERE m.workspace_id = w.id AND m.user_id = $1)`
+
+	if _, err = tx.ExecContext(ctx, query, ownerID); err != nil {
+		return
+	}
+
+	query = `
+		INSERT INTO workspaces (name, owner_id, personal)
+		VALUES ($1, $2, true)
+		ON CONFLICT (owner_id) WHERE personal DO NOTHING
+		RETURNING id, name, created_at`
+
+	err = tx.GetContext(ctx, &dest, query, name, ownerID)
+	switch {
+	case err == nil:
+		query = `
+			INSERT INTO workspace_members (workspace_id, user_id, role)
+			VALUES ($1, $2, $3)`
+
+		if _, err = tx.ExecContext(ctx, query, dest.ID, ownerID, workspace.RoleOwner); err != nil {
+			return
+		}
+		dest.Role = workspace.RoleOwner
+	case errors.Is(err, sql.ErrNoRows):
+		// Created concurrently; the owner is a member of it.
+		query = `
+			SELECT w.id, w.name, w.created_at, m.role
+			FROM workspaces w
+			JOIN workspace_members m ON m.workspace_id = w.id AND m.user_id = w.owner_id
+			WHERE w.owner_id = $1 AND w.personal`
+
+		if err = tx.GetContext(ctx, &dest, query, ownerID); err != nil {
+			return
+		}
+	default:
+		return
+	}
+
+	err = tx.Commit()
+
+	return
+}
+
+// Get returns the workspace with the role of userID. Workspaces the user is
+// not a member of are not found.
+func (r *WorkspaceRepository) Get(ctx context.Context, id, userID string) (dest workspace.Entity, err error) {
+	query := `
+		SELECT w.id, w.name, w.created_at, m.role
+		FROM workspaces w
+		JOIN workspace_members m ON m.workspace_id = w.id
+		WHERE w.id = $1 AND m.user_id = $2`
+
+	args := []any{id, userID}
+
+	if err = r.db.GetContext(ctx, &dest, query, args...); err != nil {
+		if errors.Is(err, sql.ErrNoRows) || store.IsInvalidInput(err) {
+			err = store.ErrorNotFound
+		}
+	}
+
+	return
+}
+
+func (r *WorkspaceRepository) Update(ctx context.Context, id, name string) (err error) {
+	query := `
+		UPDATE workspaces
+		SET name = $2, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1
+		RETURNING id`
+
+	args := []any{id, name}
+
+	if err = r.db.QueryRowContext(ctx, query, args...).Scan(&id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			err = store.ErrorNotFound
+		}
+	}
+
+	return
+}
+
+// Delete removes the workspace with all of its tasks, templates and
+// memberships.
+func (r *WorkspaceRepository) Delete(ctx context.Context, id string) (err error) {
+	query := `
+		DELETE FROM workspaces
+		WHERE id = $1
+		RETURNING id`
+
+	args := []any{id}
+
+	if err = r.db.QueryRowContext(ctx, query, args...).Scan(&id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			err = store.ErrorNotFound
+		}
+	}
+
+	return
+}
+
+func (r *WorkspaceRepository) ListMembers(ctx context.Context, id string) (dest []workspace.Member, err error) {
+	query := `
+		SELECT m.workspace_id, m.user_id, m.role, m.created_at, u.name, u.email
+		FROM workspace_members m
+		JOIN users u ON u.id = m.user_id
+		WHERE m.workspace_id = $1
+		ORDER BY m.created_at, u.name`
+
+	args := []any{id}
+
+	err = r.db.SelectContext(ctx, &dest, query, args...)
+
+	return
+}
+
+func (r *WorkspaceRepository) GetMember(ctx context.Context, id, userID string) (dest workspace.Member, err error) {
+	query := `
+		SELECT m.workspace_id, m.user_id, m.role, m.created_at, u.name, u.email
+		FROM workspace_members m
+		JOIN users u ON u.id = m.user_id
+		WHERE m.workspace_id = $1 AND m.user_id = $2`
+
+	args := []any{id, userID}
+
+	if err = r.db.GetContext(ctx, &dest, query, args...); err != nil {
+		if errors.Is(err, sql.ErrNoRows) || store.IsInvalidInput(err) {
+			err = store.ErrorNotFound
+		}
+	}
+
+	return
+}
+
+func (r *WorkspaceRepository) AddMember(ctx context.Context, id, userID, role string) (err error) {
+	query := `
+		INSERT INTO workspace_members (workspace_id, user_id, role)
+		VALUES ($1, $2, $3)`
+
+	args := []any{id, userID, role}
+
+	if _, err = r.db.ExecContext(ctx, query, args...); err != nil {
+		if store.IsUniqueViolation(err) {
+			err = store.ErrorConflict
+		}
+	}
+
+	return
+}
+
+func (r *WorkspaceRepository) UpdateMember(ctx context.Context, id, userID, role string) (err error) {
+	query := `
+		UPDATE workspace_members
+		SET role = $3
+		WHERE workspace_id = $1 AND user_id = $2
+		RETURNING user_id`
+
+	args := []any{id, userID, role}
+
+	if err = r.db.QueryRowContext(ctx, query, args...).Scan(&userID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			err = store.ErrorNotFound
+		}
+	}
+
+	return
+}
+
+// DeleteMember removes the membership. Tasks and templates of the user stay
+// in the workspace and come back if the user is added again.
+func (r *WorkspaceRepository) DeleteMember(ctx context.Context, id, userID string) (err error) {
+	query := `
+		DELETE FROM workspace_members
+		WHERE workspace_id = $1 AND user_id = $2
+		RETURNING user_id`
+
+	args := []any{id, userID}
+
+	if err = r.db.QueryRowContext(ctx, query, args...).Scan(&userID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			err = store.ErrorNotFound
+		}
+	}
+
+	return
+}
+
+func (r *WorkspaceRepository) CountOwners(ctx context.Context, id string) (count int, err error) {
+	query := `
+		SELECT COUNT(*)
+		FROM workspace_members
+		WHERE workspace_id = $1 AND role = $2`
+
+	args := []any{id, workspace.RoleOwner}
+
+	err = r.db.GetContext(ctx, &count, query, args...)
+
+	return
+}
+
+const invitationColumns = `id, workspace_id, email, role, token_hash, invited_by, created_at, sent_at, expires_at, accepted_at, revoked_at`
+
+func (r *WorkspaceRepository) ListInvitations(ctx context.Context, id string) (dest []workspace.Invitation, err error) {
+	query := `
+		SELECT ` + invitationColumns + `
+		FROM workspace_invitations
+		WHERE workspace_id = $1 AND accepted_at IS NULL AND revoked_at IS NULL
+		ORDER BY created_at DESC`
+
+	args := []any{id}
+
+	err = r.db.SelectContext(ctx, &dest, query, args...)
+
+	return
+}
+
+func (r *WorkspaceRepository) AddInvitation(ctx context.Context, data workspace.Invitation) (dest workspace.Invitation, err error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	query := `
+		UPDATE workspace_invitations
+		SET revoked_at = CURRENT_TIMESTAMP
+		WHERE workspace_id = $1 AND lower(email) = lower($2) AND accepted_at IS NULL AND revoked_at IS NULL`
+
+	if _, err = tx.ExecContext(ctx, query, data.WorkspaceID, data.Email); err != nil {
+		return
+	}
+
+	query = `
+		INSERT INTO workspace_invitations (workspace_id, email, role, token_hash, invited_by, expires_at)
+		VALUES ($1, lower(btrim($2)), $3, $4, $5, $6)
+		RETURNING ` + invitationColumns
+
+	args := []any{data.WorkspaceID, data.Email, data.Role, data.TokenHash, data.InvitedBy, data.ExpiresAt}
+
+	if err = tx.GetContext(ctx, &dest, query, args...); err != nil {
+		return
+	}
+
+	err = tx.Commit()
+
+	return
+}
+
+func (r *WorkspaceRepository) GetInvitation(ctx context.Context, id, invitationID string) (dest workspace.Invitation, err error) {
+	query := `
+		SELECT ` + invitationColumns + `
+		FROM workspace_invitations
+		WHERE workspace_id = $1 AND id = $2 AND accepted_at IS NULL AND revoked_at IS NULL`
+
+	args := []any{id, invitationID}
+
+	if err = r.db.GetContext(ctx, &dest, query, args...); err != nil {
+		if errors.Is(err, sql.ErrNoRows) || store.IsInvalidInput(err) {
+			err = store.ErrorNotFound
+		}
+	}
+
+	return
+}
+
+func (r *WorkspaceRepository) GetInvitationByHash(ctx context.Context, tokenHash string) (dest workspace.Invitation, err error) {
+	query := `
+		SELECT i.id, i.workspace_id, i.email, i.role, i.token_hash, i.invited_by, i.created_at, i.sent_at,
+		       i.expires_at, i.accepted_at, i.revoked_at, w.name AS workspace_name
+		FROM workspace_invitations i
+		JOIN workspaces w ON w.id = i.workspace_id
+		WHERE i.token_hash = $1 AND i.accepted_at IS NULL AND i.revoked_at IS NULL AND i.expires_at > CURRENT_TIMESTAMP`
+
+	args := []any{tokenHash}
+
+	if err = r.db.GetContext(ctx, &dest, query, args...); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			err = store.ErrorNotFound
+		}
+	}
+
+	return
+}
+
+// RenewInvitation replaces the token of an open invitation, which also
+// invalidates the link sent before, and restarts its expiry.
+func (r *WorkspaceRepository) RenewInvitation(ctx context.Context, id, invitationID, tokenHash string, expiresAt time.Time) (dest workspace.Invitation, err error) {
+	query := `
+		UPDATE workspace_invitations
+		SET token_hash = $3, expires_at = $4, sent_at = CURRENT_TIMESTAMP
+		WHERE workspace_id = $1 AND id = $2 AND accepted_at IS NULL AND revoked_at IS NULL
+		RETURNING ` + invitationColumns
+
+	args := []any{id, invitationID, tokenHash, expiresAt}
+
+	if err = r.db.GetContext(ctx, &dest, query, args...); err != nil {
+		if errors.Is(err, sql.ErrNoRows) || store.IsInvalidInput(err) {
+			err = store.ErrorNotFound
+		}
+	}
+
+	return
+}
+
+func (r *WorkspaceRepository) RevokeInvitation(ctx context.Context, id, invitationID string) (err error) {
+	query := `
+		UPDATE workspace_invitations
+		SET revoked_at = CURRENT_TIMESTAMP
+		WHERE workspace_id = $1 AND id = $2 AND accepted_at IS NULL AND revoked_at IS NULL
+		RETURNING id`
+
+	args := []any{id, invitationID}
+
+	if err = r.db.QueryRowContext(ctx, query, args...).Scan(&invitationID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) || store.IsInvalidInput(err) {
+			err = store.ErrorNotFound
+		}
+	}
+
+	return
+}
+
+func (r *WorkspaceRepository) AcceptInvitation(ctx context.Context, invitationID, userID string) (err error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	query := `
+		UPDATE workspace_invitations
+		SET accepted_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND accepted_at IS NULL AND revoked_at IS NULL AND expires_at > CURRENT_TIMESTAMP
+		RETURNING workspace_id, role`
+
+	var id, role string
+	if err = tx.QueryRowContext(ctx, query, invitationID).Scan(&id, &role); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			err = store.ErrorNotFound
+		}
+		return
+	}
+
+	query = `
+		INSERT INTO workspace_members (workspace_id, user_id, role)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (workspace_id, user_id) DO NOTHING`
+
+	if _, err = tx.ExecContext(ctx, query, id, userID, role); err != nil {
+		return
+	}
+
+	err = tx.Commit()
+
+	return
+}
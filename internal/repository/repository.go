@@ -1,8 +1,19 @@
 package repository
 
 import (
+	"github.com/yrss1/todo/internal/domain/accesstoken"
+	"github.com/yrss1/todo/internal/domain/audit"
+	"github.com/yrss1/todo/internal/domain/identity"
+	"github.com/yrss1/todo/internal/domain/lockout"
+	"github.com/yrss1/todo/internal/domain/privacy"
+	"github.com/yrss1/todo/internal/domain/session"
 	"github.com/yrss1/todo/internal/domain/task"
+	"github.com/yrss1/todo/internal/domain/template"
+	"github.com/yrss1/todo/internal/domain/token"
+	"github.com/yrss1/todo/internal/domain/twofactor"
 	"github.com/yrss1/todo/internal/domain/user"
+	"github.com/yrss1/todo/internal/domain/verification"
+	"github.com/yrss1/todo/internal/domain/workspace"
 	"github.com/yrss1/todo/internal/repository/postgres"
 	"github.com/yrss1/todo/pkg/store"
 )
@@ -12,8 +23,20 @@ type Configuration func(r *Repository) error
 type Repository struct {
 	postgres store.SQLX
 
-	User user.Repository
-	Task task.Repository
+	User     user.Repository
+	Task     task.Repository
+	Template template.Repository
+	Token    token.Repository
+	Session  session.Repository
+
+	Verification verification.Repository
+	Lockout      lockout.Repository
+	TwoFactor    twofactor.Repository
+	AccessToken  accesstoken.Repository
+	Identity     identity.Repository
+	Privacy      privacy.Repository
+	Workspace    workspace.Repository
+	Audit        audit.Repository
 }
 
 func New(configs ...Configuration) (s *Repository, err error) {
@@ -41,6 +64,17 @@ func WithPostgresStore(dbName string) Configuration {
 
 		r.User = postgres.NewUserRepository(r.postgres.Client)
 		r.Task = postgres.NewTaskRepository(r.postgres.Client)
+		r.Template = postgres.NewTemplateRepository(r.postgres.Client)
+		r.Token = postgres.NewTokenRepository(r.postgres.Client)
+		r.Session = postgres.NewSessionRepository(r.postgres.Client)
+		r.Verification = postgres.NewVerificationRepository(r.postgres.Client)
+		r.Lockout = postgres.NewLockoutRepository(r.postgres.Client)
+		r.TwoFactor = postgres.NewTwoFactorRepository(r.postgres.Client)
+		r.AccessToken = postgres.NewAccessTokenRepository(r.postgres.Client)
+		r.Identity = postgres.NewIdentityRepository(r.postgres.Client)
+		r.Privacy = postgres.NewPrivacyRepository(r.postgres.Client)
+		r.Workspace = postgres.NewWorkspaceRepository(r.postgres.Client)
+		r.Audit = postgres.NewAuditRepository(r.postgres.Client)
 
 		return
 	}
@@ -0,0 +1,150 @@
+package account
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/yrss1/todo/internal/domain/user"
+	"github.com/yrss1/todo/internal/domain/verification"
+	"github.com/yrss1/todo/pkg/helpers"
+	"github.com/yrss1/todo/pkg/log"
+	"github.com/yrss1/todo/pkg/mail"
+	"github.com/yrss1/todo/pkg/store"
+	"go.uber.org/zap"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const emailChangeTTL = 24 * time.Hour
+
+var ErrInvalidEmailChangeToken = errors.New("invalid or expired token")
+
+// RequestEmailChange mails a confirmation link to the new address. The
+// account keeps its current email until ConfirmEmailChange, so a typo
+// cannot lock the user out.
+func (s *Service) RequestEmailChange(ctx context.Context, id, email string) (err error) {
+	logger := log.LoggerFromContext(ctx).Named("RequestEmailChange").With(zap.String("id", id))
+
+	email = user.NormalizeEmail(email)
+
+	data, err := s.userRepository.Get(ctx, id)
+	if err != nil {
+		if !errors.Is(err, store.ErrorNotFound) {
+			logger.Error("failed to get by id", zap.Error(err))
+		}
+		return
+	}
+	if *data.Email == email {
+		return
+	}
+
+	switch _, err = s.userRepository.GetByEmail(ctx, email); {
+	case err == nil:
+		return user.ErrEmailTaken
+	case !errors.Is(err, store.ErrorNotFound):
+		logger.Error("failed to get by email", zap.Error(err))
+		return
+	}
+
+	if err = s.userRepository.Update(ctx, id, user.Entity{PendingEmail: &email}); err != nil {
+		logger.Error("failed to update by id", zap.Error(err))
+		return
+	}
+
+	if err = s.verificationRepository.Invalidate(ctx, id, verification.PurposeEmailChange); err != nil {
+		logger.Error("failed to invalidate pending tokens", zap.Error(err))
+		return
+	}
+
+	token, err := helpers.GenerateToken(32)
+	if err != nil {
+		logger.Error("failed to generate token", zap.Error(err))
+		return
+	}
+
+	entity := verification.Entity{
+		UserID:    id,
+		Purpose:   verification.PurposeEmailChange,
+		TokenHash: helpers.HashToken(token),
+		ExpiresAt: time.Now().Add(emailChangeTTL),
+	}
+
+	if _, err = s.verificationRepository.Add(ctx, entity); err != nil {
+		logger.Error("failed to store token", zap.Error(err))
+		return
+	}
+
+	link := strings.TrimSuffix(s.publicURL, "/") + "/confirm-email?token=" + url.QueryEscape(token)
+
+	msg := mail.Message{
+		To:      email,
+		Subject: "Confirm your new email address",
+		Body: fmt.Sprintf("Hi %s,\n\nPlease confirm that this is your new email address by opening the link below. It expires in %s.\n\n%s\n\n"+
+			"Until then you keep using %s to log in.\n", *data.Name, helpers.FormatDuration(emailChangeTTL), link, *data.Email),
+	}
+
+	if err = s.mailer.Send(ctx, msg); err != nil {
+		logger.Error("failed to send confirmation email", zap.Error(err))
+		return
+	}
+
+	return
+}
+
+// ConfirmEmailChange switches the user to the pending address with a token
+// from RequestEmailChange, and tells the old address about the change.
+func (s *Service) ConfirmEmailChange(ctx context.Context, id, token string) (err error) {
+	logger := log.LoggerFromContext(ctx).Named("ConfirmEmailChange").With(zap.String("id", id))
+
+	hash := helpers.HashToken(token)
+
+	challenge, err := s.verificationRepository.Get(ctx, verification.PurposeEmailChange, hash)
+	if err != nil {
+		if errors.Is(err, store.ErrorNotFound) {
+			err = ErrInvalidEmailChangeToken
+			return
+		}
+		logger.Error("failed to get token", zap.Error(err))
+		return
+	}
+	if challenge.UserID != id {
+		logger.Warn("token belongs to another user")
+		return ErrInvalidEmailChangeToken
+	}
+
+	data, err := s.userRepository.Get(ctx, id)
+	if err != nil {
+		logger.Error("failed to get by id", zap.Error(err))
+		return
+	}
+	if data.PendingEmail == nil {
+		return ErrInvalidEmailChangeToken
+	}
+
+	if err = s.userRepository.ChangeEmail(ctx, id, *data.PendingEmail); err != nil {
+		if errors.Is(err, store.ErrorConflict) {
+			return user.ErrEmailTaken
+		}
+		logger.Error("failed to change email", zap.Error(err))
+		return
+	}
+
+	if _, err = s.verificationRepository.Consume(ctx, verification.PurposeEmailChange, hash); err != nil {
+		logger.Error("failed to consume token", zap.Error(err))
+		return
+	}
+
+	msg := mail.Message{
+		To:      *data.Email,
+		Subject: "Your email address was changed",
+		Body: fmt.Sprintf("Hi %s,\n\nThe email address of your account was changed to %s.\n\n"+
+			"If you did not do this, reset your password and contact support.\n", *data.Name, *data.PendingEmail),
+	}
+
+	if mailErr := s.mailer.Send(ctx, msg); mailErr != nil {
+		logger.Warn("failed to notify old address", zap.Error(mailErr))
+	}
+
+	return
+}
@@ -0,0 +1,25 @@
+package account
+
+import (
+	"context"
+	"github.com/yrss1/todo/internal/domain/user"
+)
+
+// ChangePassword replaces the password of the user after checking the old
+// one. Every session except sessionID, the one making the change, is signed
+// out so other devices have to log in again.
+func (s *Service) ChangePassword(ctx context.Context, id, sessionID string, req user.PasswordRequest) (err error) {
+	return s.credentials.ChangePassword(ctx, id, sessionID, *req.OldPassword, *req.NewPassword)
+}
+
+// SetTemporaryPassword replaces the password of the user with a random one
+// that must be changed on the next login, and returns it.
+func (s *Service) SetTemporaryPassword(ctx context.Context, id string) (temporary string, err error) {
+	return s.credentials.SetTemporaryPassword(ctx, id)
+}
+
+// RequirePasswordChange makes the user choose a new password on the next
+// login.
+func (s *Service) RequirePasswordChange(ctx context.Context, id string) (err error) {
+	return s.credentials.RequirePasswordChange(ctx, id)
+}
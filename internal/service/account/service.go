@@ -1,11 +1,25 @@
 package account
 
-import "github.com/yrss1/todo/internal/domain/user"
+import (
+	"github.com/yrss1/todo/internal/domain/token"
+	"github.com/yrss1/todo/internal/domain/user"
+	"github.com/yrss1/todo/internal/domain/verification"
+	auditService "github.com/yrss1/todo/internal/service/audit"
+	"github.com/yrss1/todo/internal/service/credentials"
+	"github.com/yrss1/todo/pkg/mail"
+)
 
 type Configuration func(s *Service) error
 
 type Service struct {
-	userRepository user.Repository
+	userRepository         user.Repository
+	tokenRepository        token.Repository
+	verificationRepository verification.Repository
+
+	credentials *credentials.Service
+	audit       *auditService.Service
+	mailer      mail.Mailer
+	publicURL   string
 }
 
 func New(configs ...Configuration) (s *Service, err error) {
@@ -26,3 +40,42 @@ func WithUserRepository(userRepository user.Repository) Configuration {
 		return nil
 	}
 }
+
+func WithTokenRepository(tokenRepository token.Repository) Configuration {
+	return func(s *Service) error {
+		s.tokenRepository = tokenRepository
+		return nil
+	}
+}
+
+func WithCredentials(credentials *credentials.Service) Configuration {
+	return func(s *Service) error {
+		s.credentials = credentials
+		return nil
+	}
+}
+
+func WithVerificationRepository(verificationRepository verification.Repository) Configuration {
+	return func(s *Service) error {
+		s.verificationRepository = verificationRepository
+		return nil
+	}
+}
+
+// WithMailer sends the confirmation links of email changes. publicURL is
+// the base of the links.
+func WithMailer(mailer mail.Mailer, publicURL string) Configuration {
+	return func(s *Service) error {
+		s.mailer = mailer
+		s.publicURL = publicURL
+		return nil
+	}
+}
+
+// WithAudit records role changes and deletions in the audit trail.
+func WithAudit(audit *auditService.Service) Configuration {
+	return func(s *Service) error {
+		s.audit = audit
+		return nil
+	}
+}
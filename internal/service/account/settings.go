@@ -0,0 +1,110 @@
+package account
+
+import (
+	"context"
+	"errors"
+	"github.com/yrss1/todo/internal/domain/user"
+	"github.com/yrss1/todo/pkg/log"
+	"github.com/yrss1/todo/pkg/store"
+	"go.uber.org/zap"
+	"net/http"
+	"slices"
+)
+
+// MaxAvatarSize is the largest avatar image accepted, in bytes.
+const MaxAvatarSize = 1 << 20
+
+var ErrUnsupportedAvatar = errors.New("avatar must be a PNG, JPEG, GIF or WebP image")
+
+var avatarContentTypes = []string{"image/png", "image/jpeg", "image/gif", "image/webp"}
+
+func (s *Service) GetSettings(ctx context.Context, id string) (res user.SettingsResponse, err error) {
+	logger := log.LoggerFromContext(ctx).Named("GetSettings").With(zap.String("id", id))
+
+	data, err := s.userRepository.Get(ctx, id)
+	if err != nil {
+		logger.Error("failed to get by id", zap.Error(err))
+		return
+	}
+
+	res = user.ParseSettingsFromEntity(data)
+
+	avatar, err := s.userRepository.GetAvatar(ctx, id)
+	switch {
+	case err == nil:
+		res.AvatarUpdatedAt = &avatar.UpdatedAt
+	case errors.Is(err, store.ErrorNotFound):
+		err = nil
+	default:
+		logger.Error("failed to get avatar", zap.Error(err))
+	}
+
+	return
+}
+
+func (s *Service) UpdateSettings(ctx context.Context, id string, req user.SettingsRequest) (err error) {
+	logger := log.LoggerFromContext(ctx).Named("UpdateSettings").With(zap.String("id", id))
+
+	data := user.Entity{
+		TimeZone:        req.TimeZone,
+		Locale:          req.Locale,
+		DateFormat:      req.DateFormat,
+		DefaultTaskView: req.DefaultTaskView,
+	}
+
+	err = s.userRepository.Update(ctx, id, data)
+	if err != nil && !errors.Is(err, store.ErrorNotFound) {
+		logger.Error("failed to update by id", zap.Error(err))
+		return
+	}
+
+	return
+}
+
+func (s *Service) GetAvatar(ctx context.Context, id string) (res user.Avatar, err error) {
+	logger := log.LoggerFromContext(ctx).Named("GetAvatar").With(zap.String("id", id))
+
+	res, err = s.userRepository.GetAvatar(ctx, id)
+	if err != nil && !errors.Is(err, store.ErrorNotFound) {
+		logger.Error("failed to get avatar", zap.Error(err))
+		return
+	}
+
+	return
+}
+
+// SetAvatar stores image as the avatar of the user. The content type is
+// sniffed from the image itself rather than trusted from the upload.
+func (s *Service) SetAvatar(ctx context.Context, id string, image []byte) (err error) {
+	logger := log.LoggerFromContext(ctx).Named("SetAvatar").With(zap.String("id", id))
+
+	contentType := http.DetectContentType(image)
+	if !slices.Contains(avatarContentTypes, contentType) {
+		return ErrUnsupportedAvatar
+	}
+
+	data := user.Avatar{
+		UserID:      id,
+		ContentType: contentType,
+		Data:        image,
+	}
+
+	if err = s.userRepository.SaveAvatar(ctx, data); err != nil {
+		logger.Error("failed to save avatar", zap.Error(err))
+		return
+	}
+
+	return
+}
+
+func (s *Service) DeleteAvatar(ctx context.Context, id string) (err error) {
+	logger := log.LoggerFromContext(ctx).Named("DeleteAvatar").With(zap.String("id", id))
+
+	err = s.userRepository.DeleteAvatar(ctx, id)
+	if err != nil && !errors.Is(err, store.ErrorNotFound) {
+		logger.Error("failed to delete avatar", zap.Error(err))
+		return
+	}
+
+	return
+}
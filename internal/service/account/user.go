@@ -3,6 +3,7 @@ package account
 import (
 	"context"
 	"errors"
+	"github.com/yrss1/todo/internal/domain/audit"
 	"github.com/yrss1/todo/internal/domain/user"
 	"github.com/yrss1/todo/pkg/log"
 	"github.com/yrss1/todo/pkg/store"
@@ -27,13 +28,23 @@ func (s *Service) CreateUser(ctx context.Context, req user.Request) (res user.Re
 	logger := log.LoggerFromContext(ctx).Named("CreateUser")
 
 	data := user.Entity{
-		Name:     req.Name,
-		Email:    req.Email,
-		Password: req.Password,
+		Name:  req.Name,
+		Email: req.Email,
+		Role:  req.Role,
 	}
 
+	hashedPassword, err := s.credentials.Hash(*req.Password, *req.Email)
+	if err != nil {
+		return
+	}
+	data.Password = &hashedPassword
+
 	data.ID, err = s.userRepository.Add(ctx, data)
 	if err != nil {
+		if errors.Is(err, store.ErrorConflict) {
+			err = user.ErrEmailTaken
+			return
+		}
 		logger.Error("failed to create", zap.Error(err))
 		return
 	}
@@ -61,15 +72,53 @@ func (s *Service) UpdateUser(ctx context.Context, id string, req user.Request) (
 	logger := log.LoggerFromContext(ctx).Named("UpdateUser").With(zap.String("id", id))
 
 	data := user.Entity{
-		Name:     req.Name,
-		Email:    req.Email,
-		Password: req.Password,
+		Name: req.Name,
+		Role: req.Role,
 	}
 
-	err = s.userRepository.Update(ctx, id, data)
-	if err != nil && !errors.Is(err, store.ErrorNotFound) {
-		logger.Error("failed to update by id", zap.Error(err))
-		return
+	// The previous role goes into the audit trail.
+	var previousRole string
+	if req.Role != nil {
+		current, err := s.userRepository.Get(ctx, id)
+		if err != nil {
+			if !errors.Is(err, store.ErrorNotFound) {
+				logger.Error("failed to get by id", zap.Error(err))
+			}
+			return err
+		}
+		if current.Role != nil {
+			previousRole = *current.Role
+		}
+	}
+
+	if data.Name != nil || data.Role != nil {
+		err = s.userRepository.Update(ctx, id, data)
+		if err != nil {
+			if !errors.Is(err, store.ErrorNotFound) {
+				logger.Error("failed to update by id", zap.Error(err))
+			}
+			return
+		}
+	}
+
+	if req.Role != nil && *req.Role != previousRole {
+		s.audit.Record(ctx, audit.ActionRoleChanged, "", id, map[string]any{"from": previousRole, "to": *req.Role})
+	}
+
+	// A new email only takes effect once the owner of the address confirms
+	// it.
+	if req.Email != nil {
+		if err = s.RequestEmailChange(ctx, id, *req.Email); err != nil {
+			return
+		}
+	}
+
+	// The password goes through the credentials service so it is hashed and
+	// checked against the policy like every other password.
+	if req.Password != nil {
+		if err = s.credentials.SetPassword(ctx, id, *req.Password, false); err != nil {
+			return
+		}
 	}
 
 	return
@@ -79,35 +128,53 @@ func (s *Service) DeleteUser(ctx context.Context, id string) (err error) {
 	logger := log.LoggerFromContext(ctx).Named("DeleteUser").With(zap.String("id", id))
 
 	err = s.userRepository.Delete(ctx, id)
-	if err != nil && !errors.Is(err, store.ErrorNotFound) {
-		logger.Error("failed to delete by id", zap.Error(err))
+	if err != nil {
+		if !errors.Is(err, store.ErrorNotFound) {
+			logger.Error("failed to delete by id", zap.Error(err))
+		}
 		return
 	}
+	s.audit.Record(ctx, audit.ActionUserDeleted, "", id, nil)
 
 	return
 }
 
-func (s *Service) SearchUser(ctx context.Context, req user.Request) (res []user.Response, err error) {
-	logger := log.LoggerFromContext(ctx).Named("SearchUser")
+func (s *Service) SearchUser(ctx context.Context, req user.SearchRequest) (res []user.Response, total int, err error) {
+	logger := log.LoggerFromContext(ctx).Named("SearchUser").With(
+		zap.String("query", req.Query),
+		zap.Int("page", req.Page),
+		zap.Int("limit", req.Limit))
 
-	if req.Name != nil {
-		logger = logger.With(zap.String("name", *(req.Name)))
-	}
-	if req.Email != nil {
-		logger = logger.With(zap.String("email", *(req.Email)))
+	data, total, err := s.userRepository.Search(ctx, req)
+	if err != nil {
+		logger.Error("failed to search users", zap.Error(err))
+		return
 	}
 
-	searchData := user.Entity{
-		Name:  req.Name,
-		Email: req.Email,
-	}
-	data, err := s.userRepository.Search(ctx, searchData)
+	res = user.ParseFromEntities(data)
+
+	return
+}
+
+// SearchWorkspaceMembers searches the members of a workspace, for users who
+// are not admins. Only the ID, name and email of each match are returned.
+func (s *Service) SearchWorkspaceMembers(ctx context.Context, workspaceID string, req user.SearchRequest) (res []user.DirectoryResponse, total int, err error) {
+	logger := log.LoggerFromContext(ctx).Named("SearchWorkspaceMembers").With(
+		zap.String("workspaceID", workspaceID),
+		zap.String("query", req.Query),
+		zap.Int("page", req.Page),
+		zap.Int("limit", req.Limit))
+
+	req.WorkspaceID = workspaceID
+	req.Role = ""
+
+	data, total, err := s.userRepository.Search(ctx, req)
 	if err != nil {
 		logger.Error("failed to search users", zap.Error(err))
 		return
 	}
 
-	res = user.ParseFromEntities(data)
+	res = user.ParseFromDirectoryEntities(data)
 
 	return
 }
@@ -0,0 +1,156 @@
+package audit
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"github.com/yrss1/todo/internal/domain/audit"
+	"github.com/yrss1/todo/pkg/helpers"
+	"github.com/yrss1/todo/pkg/log"
+	"go.uber.org/zap"
+	"io"
+	"maps"
+	"strconv"
+	"time"
+)
+
+const (
+	// maxUserAgent is the length of audit_events.user_agent.
+	maxUserAgent = 512
+
+	// exportFlushRows is how many events are written between flushes of
+	// the response.
+	exportFlushRows = 100
+)
+
+var exportFields = []string{"id", "created_at", "action", "actor_id", "target_id", "ip", "user_agent", "request_id", "details"}
+
+// Record appends an event to the audit trail. The IP address, user agent
+// and request ID come from the source in ctx, and so does the actor unless
+// actorID is given. Events during an impersonation name the impersonated
+// user in the on_behalf_of detail. Failures are logged; the action being
+// recorded has already happened.
+func (s *Service) Record(ctx context.Context, action, actorID, targetID string, details map[string]any) {
+	logger := log.LoggerFromContext(ctx).Named("Record").With(zap.String("action", action))
+
+	src := audit.SourceFromContext(ctx)
+	if actorID == "" {
+		actorID = src.ActorID
+	}
+
+	data := audit.Entity{
+		Action:    action,
+		ActorID:   optional(actorID),
+		TargetID:  optional(targetID),
+		IP:        src.IP,
+		UserAgent: src.UserAgent,
+		RequestID: src.RequestID,
+	}
+	if runes := []rune(data.UserAgent); len(runes) > maxUserAgent {
+		data.UserAgent = string(runes[:maxUserAgent])
+	}
+
+	if src.OnBehalfOf != "" {
+		details = maps.Clone(details)
+		if details == nil {
+			details = map[string]any{}
+		}
+		details["on_behalf_of"] = src.OnBehalfOf
+	}
+
+	if len(details) > 0 {
+		var err error
+		if data.Details, err = json.Marshal(details); err != nil {
+			logger.Error("failed to encode details", zap.Error(err))
+		}
+	}
+
+	// The event is written even when the request was cancelled, for example
+	// by a client that gave up after a failed login.
+	if err := s.auditRepository.Add(context.WithoutCancel(ctx), data); err != nil {
+		logger.Error("failed to record audit event", zap.Error(err),
+			zap.Stringp("actorID", data.ActorID), zap.Stringp("targetID", data.TargetID), zap.String("requestID", data.RequestID))
+	}
+}
+
+// ListEvents returns one page of the events matching filter, newest first,
+// and the number of matches on all pages.
+func (s *Service) ListEvents(ctx context.Context, filter audit.Filter) (res []audit.Response, total int, err error) {
+	logger := log.LoggerFromContext(ctx).Named("ListEvents")
+
+	data, total, err := s.auditRepository.List(ctx, filter)
+	if err != nil {
+		logger.Error("failed to select", zap.Error(err))
+		return
+	}
+	res = audit.ParseFromEntities(data)
+
+	return
+}
+
+// ExportEvents writes every event matching filter to w as CSV while the
+// rows are being read from the repository.
+func (s *Service) ExportEvents(ctx context.Context, w io.Writer, filter audit.Filter) (err error) {
+	logger := log.LoggerFromContext(ctx).Named("ExportEvents")
+
+	writer := csv.NewWriter(w)
+	if err = writer.Write(exportFields); err != nil {
+		return
+	}
+
+	var rows int
+	err = s.auditRepository.Stream(ctx, filter, func(data audit.Entity) error {
+		res := audit.ParseFromEntity(data)
+
+		record := []string{
+			strconv.FormatInt(res.ID, 10),
+			res.CreatedAt.UTC().Format(time.RFC3339),
+			res.Action,
+			value(res.ActorID),
+			value(res.TargetID),
+			res.IP,
+			res.UserAgent,
+			res.RequestID,
+			string(res.Details),
+		}
+		for i := range record {
+			record[i] = helpers.EscapeCSVCell(record[i])
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+
+		if rows++; rows%exportFlushRows == 0 {
+			writer.Flush()
+			helpers.Flush(w)
+		}
+
+		return writer.Error()
+	})
+	if err != nil {
+		logger.Error("failed to export", zap.Error(err))
+		return
+	}
+
+	writer.Flush()
+	if err = writer.Error(); err != nil {
+		return
+	}
+	helpers.Flush(w)
+
+	return
+}
+
+func optional(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+func value(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
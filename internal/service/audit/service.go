@@ -0,0 +1,32 @@
+package audit
+
+import (
+	"github.com/yrss1/todo/internal/domain/audit"
+)
+
+type Configuration func(s *Service) error
+
+// Service keeps the security audit trail: who did what to which account,
+// from where and in which request.
+type Service struct {
+	auditRepository audit.Repository
+}
+
+func New(configs ...Configuration) (s *Service, err error) {
+	s = &Service{}
+
+	for _, cfg := range configs {
+		if err = cfg(s); err != nil {
+			return
+		}
+	}
+
+	return
+}
+
+func WithAuditRepository(auditRepository audit.Repository) Configuration {
+	return func(s *Service) error {
+		s.auditRepository = auditRepository
+		return nil
+	}
+}
@@ -0,0 +1,125 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"github.com/yrss1/todo/internal/domain/accesstoken"
+	"github.com/yrss1/todo/internal/domain/user"
+	"github.com/yrss1/todo/pkg/helpers"
+	"github.com/yrss1/todo/pkg/log"
+	"github.com/yrss1/todo/pkg/store"
+	"go.uber.org/zap"
+	"slices"
+	"strings"
+)
+
+var (
+	ErrInvalidAccessToken = errors.New("invalid access token")
+	ErrScopeNotAllowed    = errors.New("scope users:admin requires the admin role")
+)
+
+func (s *Service) ListAccessTokens(ctx context.Context, userID string) (res []accesstoken.Response, err error) {
+	logger := log.LoggerFromContext(ctx).Named("ListAccessTokens").With(zap.String("userID", userID))
+
+	data, err := s.accessTokenRepository.List(ctx, userID)
+	if err != nil {
+		logger.Error("failed to select", zap.Error(err))
+		return
+	}
+	res = accesstoken.ParseFromEntities(data)
+
+	return
+}
+
+// CreateAccessToken issues a personal access token for scripts. The token is
+// returned only here; the users:admin scope is limited to admins.
+func (s *Service) CreateAccessToken(ctx context.Context, userID, role string, req accesstoken.Request) (res accesstoken.CreateResponse, err error) {
+	logger := log.LoggerFromContext(ctx).Named("CreateAccessToken").With(zap.String("userID", userID))
+
+	if slices.Contains(req.Scopes, accesstoken.ScopeUsersAdmin) && role != user.RoleAdmin {
+		err = ErrScopeNotAllowed
+		return
+	}
+
+	secret, err := helpers.GenerateToken(32)
+	if err != nil {
+		logger.Error("failed to generate token", zap.Error(err))
+		return
+	}
+	token := accesstoken.Prefix + secret
+
+	data := accesstoken.Entity{
+		UserID:    userID,
+		Name:      strings.TrimSpace(*req.Name),
+		TokenHash: helpers.HashToken(token),
+		Scopes:    req.Scopes,
+		ExpiresAt: req.ExpiresAt,
+	}
+
+	data, err = s.accessTokenRepository.Add(ctx, data)
+	if err != nil {
+		logger.Error("failed to create", zap.Error(err))
+		return
+	}
+	securityEvent(ctx, "access_token_created", zap.String("userID", userID), zap.String("tokenID", data.ID), zap.Strings("scopes", req.Scopes))
+
+	res = accesstoken.CreateResponse{
+		Response: accesstoken.ParseFromEntity(data),
+		Token:    token,
+	}
+
+	return
+}
+
+func (s *Service) RevokeAccessToken(ctx context.Context, userID, id string) (err error) {
+	logger := log.LoggerFromContext(ctx).Named("RevokeAccessToken").With(zap.String("userID", userID))
+
+	if err = s.accessTokenRepository.Revoke(ctx, userID, id); err != nil {
+		if !errors.Is(err, store.ErrorNotFound) {
+			logger.Error("failed to revoke", zap.Error(err))
+		}
+		return
+	}
+	securityEvent(ctx, "access_token_revoked", zap.String("userID", userID), zap.String("tokenID", id))
+
+	return
+}
+
+// ValidateAccessToken resolves a personal access token to claims for its
+// owner. The claims carry the token's scopes and the owner's current role.
+func (s *Service) ValidateAccessToken(ctx context.Context, token string) (claims *Claims, err error) {
+	logger := log.LoggerFromContext(ctx).Named("ValidateAccessToken")
+
+	data, err := s.accessTokenRepository.GetByHash(ctx, helpers.HashToken(token))
+	if err != nil {
+		if errors.Is(err, store.ErrorNotFound) {
+			return nil, ErrInvalidAccessToken
+		}
+		logger.Error("failed to get access token", zap.Error(err))
+		return nil, err
+	}
+
+	if !data.Active() {
+		return nil, ErrInvalidAccessToken
+	}
+
+	owner, err := s.userRepository.Get(ctx, data.UserID)
+	if err != nil {
+		logger.Error("failed to get owner", zap.Error(err), zap.String("userID", data.UserID))
+		return nil, err
+	}
+
+	if err = s.accessTokenRepository.Touch(ctx, data.ID); err != nil {
+		logger.Warn("failed to update last use", zap.Error(err))
+	}
+
+	claims = &Claims{
+		UserID: data.UserID,
+		Scopes: append([]string{}, data.Scopes...),
+	}
+	if owner.Role != nil {
+		claims.Role = *owner.Role
+	}
+
+	return claims, nil
+}
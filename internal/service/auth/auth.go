@@ -2,22 +2,51 @@ package auth
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"github.com/dgrijalva/jwt-go"
+	"github.com/yrss1/todo/internal/domain/audit"
 	"github.com/yrss1/todo/internal/domain/user"
 	"github.com/yrss1/todo/pkg/helpers"
 	"github.com/yrss1/todo/pkg/log"
+	"github.com/yrss1/todo/pkg/store"
 	"go.uber.org/zap"
-	"golang.org/x/crypto/bcrypt"
 	"time"
 )
 
+var ErrInvalidCredentials = errors.New("invalid email or password")
+
 type Claims struct {
 	UserID string `json:"userID"`
+	Role   string `json:"role,omitempty"`
+
+	// Scopes is only set for personal access tokens. Access tokens from a
+	// login are not limited by scope.
+	Scopes []string `json:"scopes,omitempty"`
+
+	// SessionID is the session the token was issued for. Tokens of a revoked
+	// session are rejected.
+	SessionID string `json:"sid,omitempty"`
+
+	// ImpersonatorID is the admin acting as UserID with an impersonation
+	// token. Everything done with the token is attributed to them.
+	ImpersonatorID string `json:"impersonator,omitempty"`
+
 	jwt.StandardClaims
 }
 
 func (s *Service) Register(ctx context.Context, req user.Request) (id string, err error) {
+	return s.register(ctx, req, false)
+}
+
+// RegisterVerified creates an account for an email address that was already
+// proven, for example by following an invitation link. No verification email
+// is sent.
+func (s *Service) RegisterVerified(ctx context.Context, req user.Request) (id string, err error) {
+	return s.register(ctx, req, true)
+}
+
+func (s *Service) register(ctx context.Context, req user.Request, verified bool) (id string, err error) {
 	logger := log.LoggerFromContext(ctx).Named("Register")
 
 	data := user.Entity{
@@ -25,55 +54,119 @@ func (s *Service) Register(ctx context.Context, req user.Request) (id string, er
 		Email:    req.Email,
 		Password: req.Password,
 	}
+	if verified {
+		now := time.Now()
+		data.EmailVerifiedAt = &now
+	}
 
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(*data.Password), bcrypt.DefaultCost)
+	hashedPassword, err := s.credentials.Hash(*req.Password, *req.Email)
 	if err != nil {
-		logger.Error("failed to generate password hash", zap.Error(err))
 		return
 	}
-	data.Password = helpers.GetStringPtr(string(hashedPassword))
+	data.Password = &hashedPassword
 
 	id, err = s.userRepository.Add(ctx, data)
 	if err != nil {
+		if errors.Is(err, store.ErrorConflict) {
+			err = user.ErrEmailTaken
+			return
+		}
 		logger.Error("failed to create", zap.Error(err))
 		return
 	}
 
+	if verified {
+		return
+	}
+
+	// The account exists at this point; a failed email can be resent later.
+	if mailErr := s.SendEmailVerification(ctx, id); mailErr != nil {
+		logger.Warn("failed to send verification email", zap.Error(mailErr))
+	}
+
 	return
 }
 
-func (s *Service) ValidateUser(ctx context.Context, req user.Request) (id string, err error) {
+// ValidateUser checks the credentials of a login attempt made from ip.
+// Failed attempts are counted per account and per IP, and a *LockedError is
+// returned while either of them is locked out.
+func (s *Service) ValidateUser(ctx context.Context, req user.Request, ip string) (id string, err error) {
 	logger := log.LoggerFromContext(ctx).Named("ValidateUser")
 
+	if err = s.checkLockout(ctx, *req.Email, ip); err != nil {
+		s.loginFailed(ctx, "", *req.Email, err)
+		return
+	}
+
+	// An unknown email is reported like a wrong password, so the response
+	// does not reveal which addresses are registered.
 	data, err := s.userRepository.GetByEmail(ctx, *req.Email)
 	if err != nil {
+		if errors.Is(err, store.ErrorNotFound) {
+			err = ErrInvalidCredentials
+			s.recordLoginFailure(ctx, *req.Email, ip)
+			s.loginFailed(ctx, "", *req.Email, err)
+			return
+		}
 		logger.Error("failed to validate user", zap.Error(err))
 		return
 	}
 	id = data.ID
 
-	err = bcrypt.CompareHashAndPassword([]byte(*data.Password), []byte(*req.Password))
+	ok, err := s.credentials.Verify(ctx, data, *req.Password)
 	if err != nil {
+		return
+	}
+	if !ok {
+		err = ErrInvalidCredentials
 		logger.Error("invalid email or password", zap.Error(err))
+		s.recordLoginFailure(ctx, *req.Email, ip)
+		s.loginFailed(ctx, id, *req.Email, err)
+		return
+	}
+
+	// With two-factor authentication the password alone is not a successful
+	// login; CompleteTwoFactorLogin clears the failures after the code.
+	pending, err := s.twoFactorEnabled(ctx, id)
+	if err != nil {
+		return
+	}
+	if !pending {
+		s.resetLoginFailures(ctx, *req.Email)
+	}
+
+	if s.requireVerifiedEmail && data.EmailVerifiedAt == nil {
+		logger.Warn("email address is not verified", zap.String("userID", id))
+		err = ErrEmailNotVerified
+		s.loginFailed(ctx, id, *req.Email, err)
 		return
 	}
 
 	return
 }
 
-func (s *Service) GenerateJWT(ctx context.Context, id string, jwtKey []byte) (tokenString string, err error) {
-	logger := log.LoggerFromContext(ctx).Named("GenerateJWT")
-
-	expirationTime := time.Now().Add(24 * time.Hour)
+// loginFailed records a rejected login in the audit trail. targetID is
+// empty when the email address does not belong to an account.
+func (s *Service) loginFailed(ctx context.Context, targetID, email string, reason error) {
+	var locked *LockedError
+	if errors.As(reason, &locked) {
+		reason = errors.New("too many failed attempts")
+	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, &Claims{
-		UserID: id,
-		StandardClaims: jwt.StandardClaims{
-			ExpiresAt: expirationTime.Unix(),
-		},
+	s.audit.Record(ctx, audit.ActionLoginFailed, "", targetID, map[string]any{
+		"email":  email,
+		"reason": reason.Error(),
 	})
+}
 
-	tokenString, err = token.SignedString(jwtKey)
+func (s *Service) GenerateJWT(ctx context.Context, id, role, sessionID string) (tokenString string, err error) {
+	logger := log.LoggerFromContext(ctx).Named("GenerateJWT")
+
+	tokenString, err = s.signJWT(&Claims{
+		UserID:    id,
+		Role:      role,
+		SessionID: sessionID,
+	}, s.accessTTL)
 	if err != nil {
 		logger.Error("failed to generate token", zap.Error(err))
 		return
@@ -82,13 +175,28 @@ func (s *Service) GenerateJWT(ctx context.Context, id string, jwtKey []byte) (to
 	return
 }
 
-func (s *Service) ValidateJWT(ctx context.Context, tokenString string, jwtKey []byte) (claims *Claims, err error) {
+// signJWT gives claims a new token ID and a lifetime of ttl and signs them.
+func (s *Service) signJWT(claims *Claims, ttl time.Duration) (tokenString string, err error) {
+	jti, err := helpers.GenerateToken(16)
+	if err != nil {
+		return
+	}
+
+	now := time.Now()
+	claims.StandardClaims = jwt.StandardClaims{
+		Id:        jti,
+		IssuedAt:  now.Unix(),
+		ExpiresAt: now.Add(ttl).Unix(),
+	}
+
+	return s.keys.sign(claims)
+}
+
+func (s *Service) ValidateJWT(ctx context.Context, tokenString string) (claims *Claims, err error) {
 	logger := log.LoggerFromContext(ctx).Named("ValidateJWT")
 
 	claims = &Claims{}
-	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-		return jwtKey, nil
-	})
+	token, err := s.keys.parse(tokenString, claims)
 	if err != nil {
 		if err == jwt.ErrSignatureInvalid {
 			logger.Error("invalid token signature", zap.Error(err))
@@ -103,5 +211,33 @@ func (s *Service) ValidateJWT(ctx context.Context, tokenString string, jwtKey []
 		return nil, fmt.Errorf("invalid token")
 	}
 
+	if claims.Id != "" {
+		revoked, err := s.tokenRepository.IsAccessRevoked(ctx, claims.Id)
+		if err != nil {
+			logger.Error("failed to check token revocation", zap.Error(err))
+			return nil, err
+		}
+		if revoked {
+			logger.Warn("token is revoked", zap.String("jti", claims.Id))
+			return nil, ErrRevokedToken
+		}
+	}
+
+	if claims.SessionID != "" {
+		if err = s.checkSession(ctx, claims.SessionID); err != nil {
+			return nil, err
+		}
+	}
+
+	if claims.ImpersonatorID != "" {
+		if err = s.checkImpersonator(ctx, claims.ImpersonatorID); err != nil {
+			return nil, err
+		}
+	}
+
 	return claims, nil
 }
+
+func (s *Service) JWKS() JWKS {
+	return s.keys.JWKS()
+}
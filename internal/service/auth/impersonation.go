@@ -0,0 +1,104 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"github.com/yrss1/todo/internal/domain/audit"
+	"github.com/yrss1/todo/internal/domain/user"
+	"github.com/yrss1/todo/pkg/log"
+	"github.com/yrss1/todo/pkg/store"
+	"go.uber.org/zap"
+	"time"
+)
+
+var (
+	ErrImpersonationNotAllowed = errors.New("impersonation requires an admin login")
+	ErrImpersonateSelf         = errors.New("cannot impersonate yourself")
+	ErrImpersonateAdmin        = errors.New("cannot impersonate another admin")
+)
+
+// Impersonate issues a short-lived access token that acts as targetID for
+// the admin described by actor. The token has the role of the target, no
+// refresh token and no session, and names the admin in its claims.
+func (s *Service) Impersonate(ctx context.Context, actor *Claims, targetID, reason string) (res TokenPair, err error) {
+	logger := log.LoggerFromContext(ctx).Named("Impersonate").
+		With(zap.String("actorID", actor.UserID), zap.String("targetID", targetID))
+
+	// Tokens of scripts and of an ongoing impersonation cannot start one.
+	if actor.Role != user.RoleAdmin || actor.Scopes != nil || actor.ImpersonatorID != "" {
+		err = ErrImpersonationNotAllowed
+		return
+	}
+	if targetID == actor.UserID {
+		err = ErrImpersonateSelf
+		return
+	}
+
+	target, err := s.userRepository.Get(ctx, targetID)
+	if err != nil {
+		if !errors.Is(err, store.ErrorNotFound) {
+			logger.Error("failed to get target", zap.Error(err))
+		}
+		return
+	}
+
+	var role string
+	if target.Role != nil {
+		role = *target.Role
+	}
+	if role == user.RoleAdmin {
+		err = ErrImpersonateAdmin
+		return
+	}
+
+	res.AccessToken, err = s.signJWT(&Claims{
+		UserID:         targetID,
+		Role:           role,
+		ImpersonatorID: actor.UserID,
+	}, s.impersonationTTL)
+	if err != nil {
+		logger.Error("failed to generate token", zap.Error(err))
+		return
+	}
+	res.ExpiresIn = s.impersonationTTL
+
+	securityEvent(ctx, "impersonation_started", zap.String("actorID", actor.UserID), zap.String("targetID", targetID))
+	s.audit.Record(ctx, audit.ActionImpersonationStarted, actor.UserID, targetID, map[string]any{
+		"reason":     reason,
+		"expires_at": time.Now().Add(s.impersonationTTL).UTC().Format(time.RFC3339),
+	})
+
+	return
+}
+
+// ImpersonatedRequest records a request made with an impersonation token,
+// attributed to the admin behind it.
+func (s *Service) ImpersonatedRequest(ctx context.Context, claims *Claims, method, route string, status int) {
+	s.audit.Record(ctx, audit.ActionImpersonatedRequest, claims.ImpersonatorID, claims.UserID, map[string]any{
+		"method": method,
+		"route":  route,
+		"status": status,
+	})
+}
+
+// checkImpersonator rejects impersonation tokens once the admin behind them
+// lost the role or the account.
+func (s *Service) checkImpersonator(ctx context.Context, id string) (err error) {
+	logger := log.LoggerFromContext(ctx).Named("checkImpersonator").With(zap.String("impersonatorID", id))
+
+	data, err := s.userRepository.Get(ctx, id)
+	if err != nil {
+		if errors.Is(err, store.ErrorNotFound) {
+			return ErrRevokedToken
+		}
+		logger.Error("failed to get impersonator", zap.Error(err))
+		return
+	}
+
+	if data.Role == nil || *data.Role != user.RoleAdmin {
+		logger.Warn("impersonator is no longer an admin")
+		return ErrRevokedToken
+	}
+
+	return
+}
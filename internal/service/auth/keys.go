@@ -0,0 +1,225 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"github.com/dgrijalva/jwt-go"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+var ErrUnknownKey = errors.New("unknown signing key")
+
+// KeySet holds the keys used to sign and verify tokens. Tokens are signed
+// with the active key only and verified with whichever key their kid header
+// names, so old keys can stay in the set until their tokens have expired.
+type KeySet struct {
+	method jwt.SigningMethod
+	active *signingKey
+	keys   map[string]*signingKey
+}
+
+type signingKey struct {
+	id      string
+	private crypto.PrivateKey
+	public  crypto.PublicKey
+}
+
+// JWK is the public part of a signing key as published in the JWKS document.
+type JWK struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	N         string `json:"n,omitempty"`
+	E         string `json:"e,omitempty"`
+	Curve     string `json:"crv,omitempty"`
+	X         string `json:"x,omitempty"`
+}
+
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// NewHMACKeySet returns a key set that signs and verifies with a single
+// shared secret. It publishes no public keys.
+func NewHMACKeySet(secret []byte) (*KeySet, error) {
+	if len(secret) == 0 {
+		return nil, errors.New("jwt: secret cannot be blank")
+	}
+
+	key := &signingKey{private: secret, public: secret}
+
+	return &KeySet{
+		method: jwt.SigningMethodHS256,
+		active: key,
+		keys:   map[string]*signingKey{"": key},
+	}, nil
+}
+
+// LoadKeySet reads PEM encoded keys for algorithm ("RS256" or "EdDSA") from
+// files. The key ID of each file is its base name without extension. The
+// key named activeID signs new tokens; when activeID is empty the first
+// file is used. Files holding only a public key are accepted for keys that
+// are retired but must still verify tokens.
+func LoadKeySet(algorithm string, files []string, activeID string) (*KeySet, error) {
+	method := jwt.GetSigningMethod(algorithm)
+	if method == nil || (method != jwt.SigningMethodRS256 && method != SigningMethodEdDSA) {
+		return nil, fmt.Errorf("jwt: unsupported algorithm %q", algorithm)
+	}
+	if len(files) == 0 {
+		return nil, errors.New("jwt: no key files configured")
+	}
+
+	set := &KeySet{
+		method: method,
+		keys:   make(map[string]*signingKey, len(files)),
+	}
+
+	for _, file := range files {
+		key, err := loadKey(method, file)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := set.keys[key.id]; ok {
+			return nil, fmt.Errorf("jwt: duplicate key id %q", key.id)
+		}
+		set.keys[key.id] = key
+
+		if set.active == nil && (activeID == "" || activeID == key.id) {
+			set.active = key
+		}
+	}
+
+	if set.active == nil {
+		return nil, fmt.Errorf("jwt: active key %q not found", activeID)
+	}
+	if set.active.private == nil {
+		return nil, fmt.Errorf("jwt: active key %q has no private key", set.active.id)
+	}
+
+	return set, nil
+}
+
+func loadKey(method jwt.SigningMethod, file string) (*signingKey, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("jwt: %s is not PEM encoded", file)
+	}
+
+	key := &signingKey{
+		id: strings.TrimSuffix(filepath.Base(file), filepath.Ext(file)),
+	}
+
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		key.private, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		key.private, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		key.public, err = x509.ParsePKIXPublicKey(block.Bytes)
+	default:
+		err = fmt.Errorf("unsupported PEM block %q", block.Type)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("jwt: %s: %w", file, err)
+	}
+
+	if signer, ok := key.private.(crypto.Signer); ok {
+		key.public = signer.Public()
+	}
+
+	switch key.public.(type) {
+	case *rsa.PublicKey:
+		if method != jwt.SigningMethodRS256 {
+			return nil, fmt.Errorf("jwt: %s holds an RSA key, %s expected", file, method.Alg())
+		}
+	case ed25519.PublicKey:
+		if method != SigningMethodEdDSA {
+			return nil, fmt.Errorf("jwt: %s holds an Ed25519 key, %s expected", file, method.Alg())
+		}
+	default:
+		return nil, fmt.Errorf("jwt: %s holds an unsupported key type", file)
+	}
+
+	return key, nil
+}
+
+// Algorithm returns the name of the only algorithm accepted by the set.
+func (k *KeySet) Algorithm() string {
+	return k.method.Alg()
+}
+
+func (k *KeySet) sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(k.method, claims)
+	if k.active.id != "" {
+		token.Header["kid"] = k.active.id
+	}
+
+	return token.SignedString(k.active.private)
+}
+
+// parse verifies the token with the algorithm of the set pinned, so a token
+// signed with any other algorithm, including "none", is rejected.
+func (k *KeySet) parse(tokenString string, claims jwt.Claims) (*jwt.Token, error) {
+	parser := jwt.Parser{ValidMethods: []string{k.method.Alg()}}
+
+	return parser.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		id, _ := token.Header["kid"].(string)
+
+		key, ok := k.keys[id]
+		if !ok {
+			return nil, ErrUnknownKey
+		}
+
+		return key.public, nil
+	})
+}
+
+// JWKS returns the public keys of the set. It is empty for HMAC key sets.
+func (k *KeySet) JWKS() (res JWKS) {
+	res.Keys = make([]JWK, 0, len(k.keys))
+
+	for _, key := range k.keys {
+		switch public := key.public.(type) {
+		case *rsa.PublicKey:
+			res.Keys = append(res.Keys, JWK{
+				KeyType:   "RSA",
+				KeyID:     key.id,
+				Use:       "sig",
+				Algorithm: k.method.Alg(),
+				N:         base64.RawURLEncoding.EncodeToString(public.N.Bytes()),
+				E:         base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes()),
+			})
+		case ed25519.PublicKey:
+			res.Keys = append(res.Keys, JWK{
+				KeyType:   "OKP",
+				KeyID:     key.id,
+				Use:       "sig",
+				Algorithm: k.method.Alg(),
+				Curve:     "Ed25519",
+				X:         base64.RawURLEncoding.EncodeToString(public),
+			})
+		}
+	}
+
+	sort.Slice(res.Keys, func(i, j int) bool {
+		return res.Keys[i].KeyID < res.Keys[j].KeyID
+	})
+
+	return
+}
@@ -0,0 +1,202 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"github.com/yrss1/todo/internal/domain/identity"
+	"github.com/yrss1/todo/internal/domain/user"
+	"github.com/yrss1/todo/pkg/helpers"
+	"github.com/yrss1/todo/pkg/log"
+	"github.com/yrss1/todo/pkg/oidc"
+	"github.com/yrss1/todo/pkg/store"
+	"go.uber.org/zap"
+	"strings"
+	"time"
+)
+
+const oidcStateTTL = 10 * time.Minute
+
+var (
+	ErrOIDCDisabled      = errors.New("single sign-on is not configured")
+	ErrInvalidOIDCState  = errors.New("invalid or expired login state")
+	ErrOIDCEmailRequired = errors.New("the identity provider did not return a verified email address")
+	ErrOIDCNoAccount     = errors.New("no account exists for this email address")
+
+	// ErrOIDCUnverifiedAccount keeps an external login from taking over an
+	// account someone registered with the address before proving they own it.
+	ErrOIDCUnverifiedAccount = errors.New("the account with this email address is not verified; log in with its password and verify the address first")
+)
+
+// OIDCLoginURL starts a login at the identity provider and returns the URL
+// to send the user to. The state, nonce and PKCE verifier stay on the
+// server until the callback.
+func (s *Service) OIDCLoginURL(ctx context.Context) (link string, err error) {
+	logger := log.LoggerFromContext(ctx).Named("OIDCLoginURL")
+
+	if s.oidcProvider == nil {
+		err = ErrOIDCDisabled
+		return
+	}
+
+	state, err := helpers.GenerateToken(32)
+	if err != nil {
+		logger.Error("failed to generate state", zap.Error(err))
+		return
+	}
+	nonce, err := helpers.GenerateToken(16)
+	if err != nil {
+		logger.Error("failed to generate nonce", zap.Error(err))
+		return
+	}
+	verifier, challenge, err := oidc.NewPKCE()
+	if err != nil {
+		logger.Error("failed to generate code verifier", zap.Error(err))
+		return
+	}
+
+	data := identity.State{
+		StateHash:    helpers.HashToken(state),
+		CodeVerifier: verifier,
+		Nonce:        nonce,
+		ExpiresAt:    time.Now().Add(oidcStateTTL),
+	}
+
+	if err = s.identityRepository.AddState(ctx, data); err != nil {
+		logger.Error("failed to store login state", zap.Error(err))
+		return
+	}
+
+	link, err = s.oidcProvider.AuthCodeURL(ctx, state, nonce, challenge)
+	if err != nil {
+		logger.Error("failed to build authorization url", zap.Error(err))
+		return
+	}
+
+	return
+}
+
+// OIDCCallback completes a login from the identity provider and returns the
+// ID of the user to issue tokens for. Known identities sign in directly;
+// new ones are linked to the account with the same verified email, or get
+// a new account when auto-registration is enabled.
+func (s *Service) OIDCCallback(ctx context.Context, state, code string) (id string, err error) {
+	logger := log.LoggerFromContext(ctx).Named("OIDCCallback")
+
+	if s.oidcProvider == nil {
+		err = ErrOIDCDisabled
+		return
+	}
+
+	pending, err := s.identityRepository.ConsumeState(ctx, helpers.HashToken(state))
+	if err != nil {
+		if errors.Is(err, store.ErrorNotFound) {
+			err = ErrInvalidOIDCState
+			return
+		}
+		logger.Error("failed to consume login state", zap.Error(err))
+		return
+	}
+
+	token, err := s.oidcProvider.Exchange(ctx, code, pending.CodeVerifier, pending.Nonce)
+	if err != nil {
+		logger.Error("failed to exchange authorization code", zap.Error(err))
+		return
+	}
+	logger = logger.With(zap.String("subject", token.Subject))
+
+	linked, err := s.identityRepository.GetBySubject(ctx, token.Issuer, token.Subject)
+	switch {
+	case err == nil:
+		if err = s.identityRepository.Touch(ctx, linked.ID); err != nil {
+			logger.Warn("failed to update last login", zap.Error(err))
+		}
+		securityEvent(ctx, "oidc_login", zap.String("userID", linked.UserID), zap.String("provider", token.Issuer))
+		return linked.UserID, nil
+	case !errors.Is(err, store.ErrorNotFound):
+		logger.Error("failed to get identity", zap.Error(err))
+		return
+	}
+
+	email := strings.TrimSpace(token.Email)
+	if email == "" || !token.EmailVerified {
+		err = ErrOIDCEmailRequired
+		return
+	}
+
+	account, err := s.userRepository.GetByEmail(ctx, email)
+	switch {
+	case err == nil && account.EmailVerifiedAt == nil:
+		securityEvent(ctx, "oidc_link_refused", zap.String("userID", account.ID), zap.String("provider", token.Issuer))
+		err = ErrOIDCUnverifiedAccount
+		return
+	case err == nil:
+		id = account.ID
+	case errors.Is(err, store.ErrorNotFound) && s.oidcAutoRegister:
+		if id, err = s.registerExternalUser(ctx, email, token.Name); err != nil {
+			return
+		}
+
+		now := time.Now()
+		if err = s.userRepository.Update(ctx, id, user.Entity{EmailVerifiedAt: &now}); err != nil {
+			logger.Error("failed to mark email verified", zap.Error(err))
+			return
+		}
+	case errors.Is(err, store.ErrorNotFound):
+		err = ErrOIDCNoAccount
+		return
+	default:
+		logger.Error("failed to get by email", zap.Error(err))
+		return
+	}
+
+	link := identity.Entity{
+		UserID:   id,
+		Provider: token.Issuer,
+		Subject:  token.Subject,
+		Email:    &email,
+	}
+
+	if _, err = s.identityRepository.Add(ctx, link); err != nil {
+		logger.Error("failed to link identity", zap.Error(err))
+		return
+	}
+	securityEvent(ctx, "oidc_identity_linked", zap.String("userID", id), zap.String("provider", token.Issuer))
+
+	return
+}
+
+// registerExternalUser creates an account for a first-time external login.
+// The account gets a random password nobody knows; the user can set one
+// through the password reset flow.
+func (s *Service) registerExternalUser(ctx context.Context, email, name string) (id string, err error) {
+	logger := log.LoggerFromContext(ctx).Named("registerExternalUser")
+
+	if name == "" {
+		name, _, _ = strings.Cut(email, "@")
+	}
+
+	password, err := helpers.GenerateToken(32)
+	if err != nil {
+		logger.Error("failed to generate password", zap.Error(err))
+		return
+	}
+
+	hashedPassword, err := s.credentials.Hash(password, email)
+	if err != nil {
+		logger.Error("failed to generate password hash", zap.Error(err))
+		return
+	}
+
+	data := user.Entity{
+		Name:     &name,
+		Email:    &email,
+		Password: &hashedPassword,
+	}
+
+	if id, err = s.userRepository.Add(ctx, data); err != nil {
+		logger.Error("failed to create", zap.Error(err))
+		return
+	}
+
+	return
+}
@@ -0,0 +1,98 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"github.com/yrss1/todo/internal/domain/verification"
+	"github.com/yrss1/todo/pkg/helpers"
+	"github.com/yrss1/todo/pkg/log"
+	"github.com/yrss1/todo/pkg/store"
+	"go.uber.org/zap"
+	"time"
+)
+
+// PasswordChangeChallengeTTL is how long a user who logged in with a
+// temporary password has to choose a new one.
+const PasswordChangeChallengeTTL = 10 * time.Minute
+
+// PasswordChangeChallenge returns a short-lived challenge token when the
+// user must change the password before getting tokens, and an empty string
+// otherwise. The token is exchanged with CompletePasswordChange.
+func (s *Service) PasswordChangeChallenge(ctx context.Context, userID string) (challenge string, err error) {
+	logger := log.LoggerFromContext(ctx).Named("PasswordChangeChallenge").With(zap.String("userID", userID))
+
+	data, err := s.userRepository.Get(ctx, userID)
+	if err != nil {
+		logger.Error("failed to get by id", zap.Error(err))
+		return
+	}
+	if data.PasswordChangeRequired == nil || !*data.PasswordChangeRequired {
+		return
+	}
+
+	challenge, err = helpers.GenerateToken(32)
+	if err != nil {
+		logger.Error("failed to generate challenge", zap.Error(err))
+		return
+	}
+
+	entity := verification.Entity{
+		UserID:    userID,
+		Purpose:   verification.PurposePasswordChange,
+		TokenHash: helpers.HashToken(challenge),
+		ExpiresAt: time.Now().Add(PasswordChangeChallengeTTL),
+	}
+
+	if _, err = s.verificationRepository.Add(ctx, entity); err != nil {
+		logger.Error("failed to store challenge", zap.Error(err))
+		return
+	}
+
+	return
+}
+
+// CompletePasswordChange sets the new password for a login challenge and
+// returns the user ID to issue tokens for. A password rejected by the policy
+// leaves the challenge valid for another try; otherwise the challenge is used
+// up before the password is set, so it cannot complete twice.
+func (s *Service) CompletePasswordChange(ctx context.Context, req verification.PasswordChangeRequest) (id string, err error) {
+	logger := log.LoggerFromContext(ctx).Named("CompletePasswordChange")
+
+	hash := helpers.HashToken(*req.ChallengeToken)
+
+	challenge, err := s.verificationRepository.Get(ctx, verification.PurposePasswordChange, hash)
+	if err != nil {
+		if errors.Is(err, store.ErrorNotFound) {
+			err = ErrInvalidVerificationToken
+			return
+		}
+		logger.Error("failed to get challenge", zap.Error(err))
+		return
+	}
+	logger = logger.With(zap.String("userID", challenge.UserID))
+
+	account, err := s.userRepository.Get(ctx, challenge.UserID)
+	if err != nil {
+		logger.Error("failed to get by id", zap.Error(err))
+		return
+	}
+
+	if err = s.credentials.Check(*req.Password, *account.Email); err != nil {
+		return
+	}
+
+	if _, err = s.verificationRepository.Consume(ctx, verification.PurposePasswordChange, hash); err != nil {
+		if errors.Is(err, store.ErrorNotFound) {
+			err = ErrInvalidVerificationToken
+			return
+		}
+		logger.Error("failed to consume challenge", zap.Error(err))
+		return
+	}
+
+	if err = s.credentials.SetPassword(ctx, challenge.UserID, *req.Password, false); err != nil {
+		return
+	}
+
+	return challenge.UserID, nil
+}
@@ -1,17 +1,57 @@
 package auth
 
 import (
+	"github.com/yrss1/todo/internal/domain/accesstoken"
+	"github.com/yrss1/todo/internal/domain/identity"
+	"github.com/yrss1/todo/internal/domain/lockout"
+	"github.com/yrss1/todo/internal/domain/session"
+	"github.com/yrss1/todo/internal/domain/token"
+	"github.com/yrss1/todo/internal/domain/twofactor"
 	"github.com/yrss1/todo/internal/domain/user"
+	"github.com/yrss1/todo/internal/domain/verification"
+	auditService "github.com/yrss1/todo/internal/service/audit"
+	"github.com/yrss1/todo/internal/service/credentials"
+	"github.com/yrss1/todo/pkg/mail"
+	"github.com/yrss1/todo/pkg/oidc"
+	"time"
 )
 
 type Configuration func(s *Service) error
 
 type Service struct {
-	userRepository user.Repository
+	userRepository         user.Repository
+	tokenRepository        token.Repository
+	sessionRepository      session.Repository
+	verificationRepository verification.Repository
+	twoFactorRepository    twofactor.Repository
+	accessTokenRepository  accesstoken.Repository
+
+	mailer               mail.Mailer
+	publicURL            string
+	requireVerifiedEmail bool
+
+	lockoutRepository lockout.Repository
+	lockoutPolicy     LockoutPolicy
+
+	identityRepository identity.Repository
+	oidcProvider       *oidc.Provider
+	oidcAutoRegister   bool
+
+	credentials *credentials.Service
+	audit       *auditService.Service
+
+	keys             *KeySet
+	accessTTL        time.Duration
+	refreshTTL       time.Duration
+	impersonationTTL time.Duration
 }
 
 func New(configs ...Configuration) (s *Service, err error) {
-	s = &Service{}
+	s = &Service{
+		accessTTL:        15 * time.Minute,
+		refreshTTL:       30 * 24 * time.Hour,
+		impersonationTTL: 15 * time.Minute,
+	}
 
 	for _, cfg := range configs {
 		if err = cfg(s); err != nil {
@@ -28,3 +68,119 @@ func WithUserRepository(userRepository user.Repository) Configuration {
 		return nil
 	}
 }
+
+func WithTokenRepository(tokenRepository token.Repository) Configuration {
+	return func(s *Service) error {
+		s.tokenRepository = tokenRepository
+		return nil
+	}
+}
+
+func WithSessionRepository(sessionRepository session.Repository) Configuration {
+	return func(s *Service) error {
+		s.sessionRepository = sessionRepository
+		return nil
+	}
+}
+
+func WithTokenTTL(accessTTL, refreshTTL time.Duration) Configuration {
+	return func(s *Service) error {
+		if accessTTL > 0 {
+			s.accessTTL = accessTTL
+		}
+		if refreshTTL > 0 {
+			s.refreshTTL = refreshTTL
+		}
+		return nil
+	}
+}
+
+// WithImpersonationTTL sets how long impersonation tokens stay valid.
+func WithImpersonationTTL(ttl time.Duration) Configuration {
+	return func(s *Service) error {
+		if ttl > 0 {
+			s.impersonationTTL = ttl
+		}
+		return nil
+	}
+}
+
+func WithKeySet(keys *KeySet) Configuration {
+	return func(s *Service) error {
+		s.keys = keys
+		return nil
+	}
+}
+
+func WithVerificationRepository(verificationRepository verification.Repository) Configuration {
+	return func(s *Service) error {
+		s.verificationRepository = verificationRepository
+		return nil
+	}
+}
+
+func WithTwoFactorRepository(twoFactorRepository twofactor.Repository) Configuration {
+	return func(s *Service) error {
+		s.twoFactorRepository = twoFactorRepository
+		return nil
+	}
+}
+
+func WithAccessTokenRepository(accessTokenRepository accesstoken.Repository) Configuration {
+	return func(s *Service) error {
+		s.accessTokenRepository = accessTokenRepository
+		return nil
+	}
+}
+
+func WithMailer(mailer mail.Mailer, publicURL string) Configuration {
+	return func(s *Service) error {
+		s.mailer = mailer
+		s.publicURL = publicURL
+		return nil
+	}
+}
+
+// WithRequireVerifiedEmail makes ValidateUser reject accounts whose email
+// address has not been verified yet.
+func WithRequireVerifiedEmail(required bool) Configuration {
+	return func(s *Service) error {
+		s.requireVerifiedEmail = required
+		return nil
+	}
+}
+
+// WithLockout enables failed-login tracking with the given policy.
+func WithLockout(lockoutRepository lockout.Repository, policy LockoutPolicy) Configuration {
+	return func(s *Service) error {
+		s.lockoutRepository = lockoutRepository
+		s.lockoutPolicy = policy
+		return nil
+	}
+}
+
+// WithOIDC enables login through an external OpenID provider. With
+// autoRegister, first-time users without an account get one.
+func WithOIDC(identityRepository identity.Repository, provider *oidc.Provider, autoRegister bool) Configuration {
+	return func(s *Service) error {
+		s.identityRepository = identityRepository
+		s.oidcProvider = provider
+		s.oidcAutoRegister = autoRegister
+		return nil
+	}
+}
+
+func WithCredentials(credentials *credentials.Service) Configuration {
+	return func(s *Service) error {
+		s.credentials = credentials
+		return nil
+	}
+}
+
+// WithAudit records logins and token refreshes in the audit trail.
+func WithAudit(audit *auditService.Service) Configuration {
+	return func(s *Service) error {
+		s.audit = audit
+		return nil
+	}
+}
@@ -0,0 +1,137 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"github.com/yrss1/todo/internal/domain/session"
+	"github.com/yrss1/todo/pkg/log"
+	"github.com/yrss1/todo/pkg/store"
+	"go.uber.org/zap"
+)
+
+// maxUserAgent is the length of sessions.user_agent.
+const maxUserAgent = 512
+
+// ListSessions returns the active sessions of the user. currentID is the
+// session of the request and is flagged in the response.
+func (s *Service) ListSessions(ctx context.Context, userID, currentID string) (res []session.Response, err error) {
+	logger := log.LoggerFromContext(ctx).Named("ListSessions").With(zap.String("userID", userID))
+
+	data, err := s.sessionRepository.List(ctx, userID)
+	if err != nil {
+		logger.Error("failed to select", zap.Error(err))
+		return
+	}
+	res = session.ParseFromEntities(data, currentID)
+
+	return
+}
+
+// RevokeSession signs a device out: its refresh tokens are revoked and its
+// access tokens are rejected from the next request on.
+func (s *Service) RevokeSession(ctx context.Context, userID, id string) (err error) {
+	logger := log.LoggerFromContext(ctx).Named("RevokeSession").With(zap.String("userID", userID))
+
+	if err = s.endSession(ctx, userID, id); err != nil {
+		if !errors.Is(err, store.ErrorNotFound) {
+			logger.Error("failed to revoke", zap.Error(err))
+		}
+		return
+	}
+	securityEvent(ctx, "session_revoked", zap.String("userID", userID), zap.String("sessionID", id))
+
+	return
+}
+
+// RevokeOtherSessions signs out every device of the user except the one of
+// currentID. Without a current session all of them are signed out.
+func (s *Service) RevokeOtherSessions(ctx context.Context, userID, currentID string) (err error) {
+	logger := log.LoggerFromContext(ctx).Named("RevokeOtherSessions").With(zap.String("userID", userID))
+
+	var familyID string
+	if currentID != "" {
+		current, err := s.sessionRepository.Get(ctx, currentID)
+		if err != nil && !errors.Is(err, store.ErrorNotFound) {
+			logger.Error("failed to get current session", zap.Error(err))
+			return err
+		}
+		if err == nil && current.UserID == userID {
+			familyID = current.FamilyID
+		}
+	}
+
+	if err = s.tokenRepository.RevokeOtherFamilies(ctx, userID, familyID); err != nil {
+		logger.Error("failed to revoke", zap.Error(err))
+		return
+	}
+	securityEvent(ctx, "other_sessions_revoked", zap.String("userID", userID), zap.String("sessionID", currentID))
+
+	return
+}
+
+// TouchSession records that the session was used by client.
+func (s *Service) TouchSession(ctx context.Context, id string, client session.Client) {
+	if id == "" {
+		return
+	}
+
+	if err := s.sessionRepository.Touch(ctx, id, newClient(client)); err != nil {
+		log.LoggerFromContext(ctx).Named("TouchSession").Warn("failed to update last use", zap.Error(err))
+	}
+}
+
+// endSession revokes the refresh token family of a session of the user,
+// which ends the session too. It returns store.ErrorNotFound for sessions
+// of other users and sessions that already ended.
+func (s *Service) endSession(ctx context.Context, userID, id string) (err error) {
+	data, err := s.sessionRepository.Get(ctx, id)
+	if err != nil {
+		return
+	}
+	if data.UserID != userID || data.RevokedAt != nil {
+		return store.ErrorNotFound
+	}
+
+	return s.tokenRepository.RevokeFamily(ctx, data.FamilyID)
+}
+
+// checkSession rejects access tokens of sessions that were revoked.
+func (s *Service) checkSession(ctx context.Context, id string) (err error) {
+	logger := log.LoggerFromContext(ctx).Named("checkSession").With(zap.String("sessionID", id))
+
+	data, err := s.sessionRepository.Get(ctx, id)
+	if err != nil {
+		if errors.Is(err, store.ErrorNotFound) {
+			return ErrRevokedToken
+		}
+		logger.Error("failed to get session", zap.Error(err))
+		return
+	}
+
+	if data.RevokedAt != nil {
+		logger.Warn("session is revoked")
+		return ErrRevokedToken
+	}
+
+	return
+}
+
+func newSession(userID, familyID string, client session.Client) session.Entity {
+	client = newClient(client)
+
+	return session.Entity{
+		UserID:    userID,
+		FamilyID:  familyID,
+		UserAgent: client.UserAgent,
+		IP:        client.IP,
+	}
+}
+
+// newClient cuts the user agent to the length the sessions table stores.
+func newClient(client session.Client) session.Client {
+	if runes := []rune(client.UserAgent); len(runes) > maxUserAgent {
+		client.UserAgent = string(runes[:maxUserAgent])
+	}
+
+	return client
+}
@@ -0,0 +1,183 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"github.com/yrss1/todo/internal/domain/audit"
+	"github.com/yrss1/todo/internal/domain/session"
+	"github.com/yrss1/todo/internal/domain/token"
+	"github.com/yrss1/todo/pkg/helpers"
+	"github.com/yrss1/todo/pkg/log"
+	"github.com/yrss1/todo/pkg/store"
+	"go.uber.org/zap"
+	"time"
+)
+
+var (
+	ErrInvalidRefreshToken = errors.New("invalid refresh token")
+	ErrRefreshTokenReuse   = errors.New("refresh token reuse detected")
+	ErrRevokedToken        = errors.New("token has been revoked")
+)
+
+type TokenPair struct {
+	AccessToken  string
+	RefreshToken string
+	ExpiresIn    time.Duration
+}
+
+// IssueTokens returns a new access token together with a refresh token that
+// starts a new rotation family, and opens a session for the client.
+func (s *Service) IssueTokens(ctx context.Context, userID string, client session.Client) (res TokenPair, err error) {
+	familyID, err := helpers.GenerateToken(16)
+	if err != nil {
+		return
+	}
+
+	if res, err = s.issueTokens(ctx, userID, familyID, client); err != nil {
+		return
+	}
+	s.audit.Record(ctx, audit.ActionLoginSucceeded, userID, userID, nil)
+
+	return
+}
+
+// RefreshTokens exchanges a refresh token for a new token pair. Every
+// refresh token can be used once; presenting a used token again revokes its
+// whole family, so a stolen token stops working for both parties.
+func (s *Service) RefreshTokens(ctx context.Context, refreshToken string, client session.Client) (res TokenPair, err error) {
+	logger := log.LoggerFromContext(ctx).Named("RefreshTokens")
+
+	data, err := s.tokenRepository.GetByHash(ctx, helpers.HashToken(refreshToken))
+	if err != nil {
+		if errors.Is(err, store.ErrorNotFound) {
+			err = ErrInvalidRefreshToken
+			return
+		}
+		logger.Error("failed to get refresh token", zap.Error(err))
+		return
+	}
+	logger = logger.With(zap.String("userID", data.UserID), zap.String("familyID", data.FamilyID))
+
+	if data.RevokedAt != nil || time.Now().After(data.ExpiresAt) {
+		err = ErrInvalidRefreshToken
+		return
+	}
+
+	if data.UsedAt == nil {
+		err = s.tokenRepository.MarkUsed(ctx, data.ID)
+	}
+	if data.UsedAt != nil || errors.Is(err, store.ErrorNotFound) {
+		logger.Warn("refresh token reuse detected, revoking family")
+		if err = s.tokenRepository.RevokeFamily(ctx, data.FamilyID); err != nil {
+			logger.Error("failed to revoke refresh token family", zap.Error(err))
+			return
+		}
+		s.audit.Record(ctx, audit.ActionTokenReused, "", data.UserID, nil)
+		err = ErrRefreshTokenReuse
+		return
+	}
+	if err != nil {
+		logger.Error("failed to rotate refresh token", zap.Error(err))
+		return
+	}
+
+	if res, err = s.issueTokens(ctx, data.UserID, data.FamilyID, client); err != nil {
+		return
+	}
+	s.audit.Record(ctx, audit.ActionTokenRefreshed, data.UserID, data.UserID, nil)
+
+	return
+}
+
+// Logout revokes the access token described by claims, ends its session
+// and, when given, the family of refreshToken.
+func (s *Service) Logout(ctx context.Context, claims *Claims, refreshToken string) (err error) {
+	logger := log.LoggerFromContext(ctx).Named("Logout").With(zap.String("userID", claims.UserID))
+
+	if claims.Id != "" {
+		expiresAt := time.Unix(claims.ExpiresAt, 0)
+		if err = s.tokenRepository.RevokeAccess(ctx, claims.Id, expiresAt); err != nil {
+			logger.Error("failed to revoke access token", zap.Error(err))
+			return
+		}
+	}
+
+	if claims.SessionID != "" {
+		if err = s.endSession(ctx, claims.UserID, claims.SessionID); err != nil && !errors.Is(err, store.ErrorNotFound) {
+			logger.Error("failed to end session", zap.Error(err))
+			return
+		}
+		err = nil
+	}
+
+	if refreshToken == "" {
+		return
+	}
+
+	data, err := s.tokenRepository.GetByHash(ctx, helpers.HashToken(refreshToken))
+	if err != nil {
+		if errors.Is(err, store.ErrorNotFound) {
+			err = nil
+			return
+		}
+		logger.Error("failed to get refresh token", zap.Error(err))
+		return
+	}
+	if data.UserID != claims.UserID {
+		return
+	}
+
+	if err = s.tokenRepository.RevokeFamily(ctx, data.FamilyID); err != nil {
+		logger.Error("failed to revoke refresh token family", zap.Error(err))
+		return
+	}
+
+	return
+}
+
+func (s *Service) issueTokens(ctx context.Context, userID, familyID string, client session.Client) (res TokenPair, err error) {
+	logger := log.LoggerFromContext(ctx).Named("issueTokens").With(zap.String("userID", userID))
+
+	account, err := s.userRepository.Get(ctx, userID)
+	if err != nil {
+		logger.Error("failed to get user", zap.Error(err))
+		return
+	}
+
+	var role string
+	if account.Role != nil {
+		role = *account.Role
+	}
+
+	sessionID, err := s.sessionRepository.Save(ctx, newSession(userID, familyID, client))
+	if err != nil {
+		logger.Error("failed to save session", zap.Error(err))
+		return
+	}
+
+	res.AccessToken, err = s.GenerateJWT(ctx, userID, role, sessionID)
+	if err != nil {
+		return
+	}
+	res.ExpiresIn = s.accessTTL
+
+	res.RefreshToken, err = helpers.GenerateToken(32)
+	if err != nil {
+		logger.Error("failed to generate refresh token", zap.Error(err))
+		return
+	}
+
+	data := token.Entity{
+		UserID:    userID,
+		FamilyID:  familyID,
+		TokenHash: helpers.HashToken(res.RefreshToken),
+		ExpiresAt: time.Now().Add(s.refreshTTL),
+	}
+
+	if _, err = s.tokenRepository.Add(ctx, data); err != nil {
+		logger.Error("failed to store refresh token", zap.Error(err))
+		return
+	}
+
+	return
+}
@@ -0,0 +1,372 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base32"
+	"errors"
+	"github.com/yrss1/todo/internal/domain/twofactor"
+	"github.com/yrss1/todo/internal/domain/verification"
+	"github.com/yrss1/todo/pkg/helpers"
+	"github.com/yrss1/todo/pkg/log"
+	"github.com/yrss1/todo/pkg/store"
+	"github.com/yrss1/todo/pkg/totp"
+	"go.uber.org/zap"
+	"strings"
+	"time"
+)
+
+const (
+	totpIssuer = "Todo"
+	totpSkew   = 1
+
+	TwoFactorChallengeTTL = 5 * time.Minute
+
+	// TwoFactorChallengeAttempts is how many codes can be tried with one
+	// login challenge before the password has to be entered again.
+	TwoFactorChallengeAttempts = 5
+
+	recoveryCodeCount = 10
+)
+
+var (
+	ErrTwoFactorEnabled     = errors.New("two-factor authentication is already enabled")
+	ErrTwoFactorNotEnabled  = errors.New("two-factor authentication is not enabled")
+	ErrInvalidTwoFactorCode = errors.New("invalid two-factor code")
+)
+
+// EnrollTwoFactor creates a new TOTP secret for the user. It does not protect
+// logins until it is confirmed with ConfirmTwoFactor.
+func (s *Service) EnrollTwoFactor(ctx context.Context, userID string) (res twofactor.EnrollmentResponse, err error) {
+	logger := log.LoggerFromContext(ctx).Named("EnrollTwoFactor").With(zap.String("userID", userID))
+
+	current, err := s.twoFactorRepository.Get(ctx, userID)
+	switch {
+	case err == nil && current.Enabled():
+		err = ErrTwoFactorEnabled
+		return
+	case err != nil && !errors.Is(err, store.ErrorNotFound):
+		logger.Error("failed to get two-factor settings", zap.Error(err))
+		return
+	}
+
+	data, err := s.userRepository.Get(ctx, userID)
+	if err != nil {
+		logger.Error("failed to get by id", zap.Error(err))
+		return
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		logger.Error("failed to generate secret", zap.Error(err))
+		return
+	}
+
+	if err = s.twoFactorRepository.Save(ctx, twofactor.Entity{UserID: userID, Secret: secret}); err != nil {
+		logger.Error("failed to save two-factor settings", zap.Error(err))
+		return
+	}
+
+	res = twofactor.EnrollmentResponse{
+		Secret: secret,
+		URI:    totp.URI(totpIssuer, *data.Email, secret),
+	}
+
+	return
+}
+
+// ConfirmTwoFactor enables two-factor authentication once the user proves
+// the authenticator works, and returns the initial recovery codes. They are
+// shown only this once.
+func (s *Service) ConfirmTwoFactor(ctx context.Context, userID, code string) (codes []string, err error) {
+	logger := log.LoggerFromContext(ctx).Named("ConfirmTwoFactor").With(zap.String("userID", userID))
+
+	data, err := s.twoFactorRepository.Get(ctx, userID)
+	if err != nil {
+		if errors.Is(err, store.ErrorNotFound) {
+			err = ErrTwoFactorNotEnabled
+			return
+		}
+		logger.Error("failed to get two-factor settings", zap.Error(err))
+		return
+	}
+	if data.Enabled() {
+		err = ErrTwoFactorEnabled
+		return
+	}
+
+	if err = s.verifyAccountSecondFactor(ctx, data, code, false); err != nil {
+		return
+	}
+
+	if err = s.twoFactorRepository.Confirm(ctx, userID); err != nil {
+		logger.Error("failed to confirm two-factor settings", zap.Error(err))
+		return
+	}
+	securityEvent(ctx, "two_factor_enabled", zap.String("userID", userID))
+
+	return s.replaceRecoveryCodes(ctx, userID)
+}
+
+// DisableTwoFactor removes the secret and the recovery codes after checking
+// a current TOTP or recovery code.
+func (s *Service) DisableTwoFactor(ctx context.Context, userID, code string) (err error) {
+	logger := log.LoggerFromContext(ctx).Named("DisableTwoFactor").With(zap.String("userID", userID))
+
+	data, err := s.enabledTwoFactor(ctx, userID)
+	if err != nil {
+		return
+	}
+
+	if err = s.verifyAccountSecondFactor(ctx, data, code, true); err != nil {
+		return
+	}
+
+	if err = s.twoFactorRepository.Delete(ctx, userID); err != nil {
+		logger.Error("failed to delete two-factor settings", zap.Error(err))
+		return
+	}
+	securityEvent(ctx, "two_factor_disabled", zap.String("userID", userID))
+
+	return
+}
+
+// RegenerateRecoveryCodes replaces all recovery codes of the user, e.g. when
+// most of them were used up. It requires a current TOTP code.
+func (s *Service) RegenerateRecoveryCodes(ctx context.Context, userID, code string) (codes []string, err error) {
+	data, err := s.enabledTwoFactor(ctx, userID)
+	if err != nil {
+		return
+	}
+
+	if err = s.verifyAccountSecondFactor(ctx, data, code, false); err != nil {
+		return
+	}
+
+	return s.replaceRecoveryCodes(ctx, userID)
+}
+
+// twoFactorEnabled reports whether a login of the user still needs a second
+// factor after the password.
+func (s *Service) twoFactorEnabled(ctx context.Context, userID string) (enabled bool, err error) {
+	data, err := s.twoFactorRepository.Get(ctx, userID)
+	if err != nil {
+		if errors.Is(err, store.ErrorNotFound) {
+			return false, nil
+		}
+		log.LoggerFromContext(ctx).Named("twoFactorEnabled").Error("failed to get two-factor settings", zap.Error(err))
+		return
+	}
+
+	return data.Enabled(), nil
+}
+
+// TwoFactorChallenge returns a short-lived challenge token when the user has
+// two-factor authentication enabled, and an empty string otherwise. The
+// token is exchanged for the real tokens with CompleteTwoFactorLogin.
+func (s *Service) TwoFactorChallenge(ctx context.Context, userID string) (challenge string, err error) {
+	logger := log.LoggerFromContext(ctx).Named("TwoFactorChallenge").With(zap.String("userID", userID))
+
+	data, err := s.twoFactorRepository.Get(ctx, userID)
+	if err != nil {
+		if errors.Is(err, store.ErrorNotFound) {
+			return "", nil
+		}
+		logger.Error("failed to get two-factor settings", zap.Error(err))
+		return
+	}
+	if !data.Enabled() {
+		return
+	}
+
+	challenge, err = helpers.GenerateToken(32)
+	if err != nil {
+		logger.Error("failed to generate challenge", zap.Error(err))
+		return
+	}
+
+	entity := verification.Entity{
+		UserID:    userID,
+		Purpose:   verification.PurposeTwoFactorLogin,
+		TokenHash: helpers.HashToken(challenge),
+		ExpiresAt: time.Now().Add(TwoFactorChallengeTTL),
+	}
+
+	if _, err = s.verificationRepository.Add(ctx, entity); err != nil {
+		logger.Error("failed to store challenge", zap.Error(err))
+		return
+	}
+
+	return
+}
+
+// CompleteTwoFactorLogin checks the second factor for a login challenge and
+// returns the user ID to issue tokens for. Wrong codes count as failed
+// logins, so they are throttled like wrong passwords, and the challenge is
+// dropped after TwoFactorChallengeAttempts codes. The failure counter of the
+// account is only cleared here, once both factors were checked.
+func (s *Service) CompleteTwoFactorLogin(ctx context.Context, req twofactor.ChallengeRequest, ip string) (id string, err error) {
+	logger := log.LoggerFromContext(ctx).Named("CompleteTwoFactorLogin")
+
+	hash := helpers.HashToken(*req.ChallengeToken)
+
+	challenge, err := s.verificationRepository.Attempt(ctx, verification.PurposeTwoFactorLogin, hash, TwoFactorChallengeAttempts)
+	if err != nil {
+		if errors.Is(err, store.ErrorNotFound) {
+			err = ErrInvalidVerificationToken
+			return
+		}
+		logger.Error("failed to get challenge", zap.Error(err))
+		return
+	}
+	logger = logger.With(zap.String("userID", challenge.UserID))
+
+	account, err := s.userRepository.Get(ctx, challenge.UserID)
+	if err != nil {
+		logger.Error("failed to get by id", zap.Error(err))
+		return
+	}
+
+	if err = s.checkLockout(ctx, *account.Email, ip); err != nil {
+		s.loginFailed(ctx, challenge.UserID, *account.Email, err)
+		return
+	}
+
+	data, err := s.enabledTwoFactor(ctx, challenge.UserID)
+	if err != nil {
+		return
+	}
+
+	if err = s.verifySecondFactor(ctx, data, *req.Code, true); err != nil {
+		if errors.Is(err, ErrInvalidTwoFactorCode) {
+			s.recordLoginFailure(ctx, *account.Email, ip)
+			s.loginFailed(ctx, challenge.UserID, *account.Email, err)
+			if challenge.Attempts >= TwoFactorChallengeAttempts {
+				securityEvent(ctx, "two_factor_challenge_exhausted", zap.String("userID", challenge.UserID))
+			}
+		}
+		return
+	}
+
+	if _, err = s.verificationRepository.Consume(ctx, verification.PurposeTwoFactorLogin, hash); err != nil {
+		if errors.Is(err, store.ErrorNotFound) {
+			err = ErrInvalidVerificationToken
+			return
+		}
+		logger.Error("failed to consume challenge", zap.Error(err))
+		return
+	}
+	s.resetLoginFailures(ctx, *account.Email)
+
+	return challenge.UserID, nil
+}
+
+func (s *Service) enabledTwoFactor(ctx context.Context, userID string) (data twofactor.Entity, err error) {
+	data, err = s.twoFactorRepository.Get(ctx, userID)
+	if err != nil {
+		if errors.Is(err, store.ErrorNotFound) {
+			err = ErrTwoFactorNotEnabled
+			return
+		}
+		log.LoggerFromContext(ctx).Named("enabledTwoFactor").Error("failed to get two-factor settings", zap.Error(err))
+		return
+	}
+	if !data.Enabled() {
+		err = ErrTwoFactorNotEnabled
+	}
+
+	return
+}
+
+// verifyAccountSecondFactor checks a code for a signed-in user. Wrong codes
+// count as failed logins of the account, so holding an access token does not
+// allow guessing codes to change the two-factor settings.
+func (s *Service) verifyAccountSecondFactor(ctx context.Context, data twofactor.Entity, code string, allowRecovery bool) (err error) {
+	account, err := s.userRepository.Get(ctx, data.UserID)
+	if err != nil {
+		log.LoggerFromContext(ctx).Named("verifyAccountSecondFactor").Error("failed to get by id", zap.String("userID", data.UserID), zap.Error(err))
+		return
+	}
+
+	if err = s.checkLockout(ctx, *account.Email, ""); err != nil {
+		return
+	}
+
+	if err = s.verifySecondFactor(ctx, data, code, allowRecovery); err != nil {
+		if errors.Is(err, ErrInvalidTwoFactorCode) {
+			s.recordLoginFailure(ctx, *account.Email, "")
+		}
+		return
+	}
+
+	return
+}
+
+// verifySecondFactor accepts a TOTP code that was not used before and, if
+// allowRecovery is set, an unused recovery code.
+func (s *Service) verifySecondFactor(ctx context.Context, data twofactor.Entity, code string, allowRecovery bool) (err error) {
+	logger := log.LoggerFromContext(ctx).Named("verifySecondFactor").With(zap.String("userID", data.UserID))
+
+	if step, ok := totp.Validate(data.Secret, code, time.Now(), totpSkew); ok {
+		if err = s.twoFactorRepository.UseStep(ctx, data.UserID, step); err != nil {
+			if errors.Is(err, store.ErrorNotFound) {
+				securityEvent(ctx, "two_factor_code_replayed", zap.String("userID", data.UserID))
+				return ErrInvalidTwoFactorCode
+			}
+			logger.Error("failed to record used code", zap.Error(err))
+		}
+		return
+	}
+
+	if allowRecovery {
+		err = s.twoFactorRepository.UseRecoveryCode(ctx, data.UserID, helpers.HashToken(normalizeRecoveryCode(code)))
+		if err == nil {
+			securityEvent(ctx, "recovery_code_used", zap.String("userID", data.UserID))
+			return
+		}
+		if !errors.Is(err, store.ErrorNotFound) {
+			logger.Error("failed to use recovery code", zap.Error(err))
+			return
+		}
+	}
+
+	securityEvent(ctx, "two_factor_failed", zap.String("userID", data.UserID))
+	return ErrInvalidTwoFactorCode
+}
+
+func (s *Service) replaceRecoveryCodes(ctx context.Context, userID string) (codes []string, err error) {
+	logger := log.LoggerFromContext(ctx).Named("replaceRecoveryCodes").With(zap.String("userID", userID))
+
+	codes = make([]string, recoveryCodeCount)
+	hashes := make([]string, recoveryCodeCount)
+	for i := range codes {
+		if codes[i], err = generateRecoveryCode(); err != nil {
+			logger.Error("failed to generate recovery code", zap.Error(err))
+			return nil, err
+		}
+		hashes[i] = helpers.HashToken(normalizeRecoveryCode(codes[i]))
+	}
+
+	if err = s.twoFactorRepository.ReplaceRecoveryCodes(ctx, userID, hashes); err != nil {
+		logger.Error("failed to store recovery codes", zap.Error(err))
+		return nil, err
+	}
+
+	return
+}
+
+// generateRecoveryCode returns a code like "k3j5q-7mzpa" that is easy to
+// copy by hand.
+func generateRecoveryCode() (string, error) {
+	b := make([]byte, 7)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	code := strings.ToLower(base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(b))[:10]
+	return code[:5] + "-" + code[5:], nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(strings.TrimSpace(code))
+	return strings.NewReplacer("-", "", " ", "").Replace(code)
+}
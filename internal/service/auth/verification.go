@@ -0,0 +1,224 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/yrss1/todo/internal/domain/user"
+	"github.com/yrss1/todo/internal/domain/verification"
+	"github.com/yrss1/todo/pkg/helpers"
+	"github.com/yrss1/todo/pkg/log"
+	"github.com/yrss1/todo/pkg/mail"
+	"github.com/yrss1/todo/pkg/store"
+	"go.uber.org/zap"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	passwordResetTTL     = time.Hour
+	emailVerificationTTL = 48 * time.Hour
+)
+
+var (
+	ErrInvalidVerificationToken = errors.New("invalid or expired token")
+	ErrEmailNotVerified         = errors.New("email address is not verified")
+)
+
+// ForgotPassword mails a password reset link to the account with the given
+// email. It succeeds for unknown addresses too, so callers cannot probe
+// which emails are registered.
+func (s *Service) ForgotPassword(ctx context.Context, email string) (err error) {
+	logger := log.LoggerFromContext(ctx).Named("ForgotPassword")
+
+	data, err := s.userRepository.GetByEmail(ctx, email)
+	if err != nil {
+		if errors.Is(err, store.ErrorNotFound) {
+			logger.Info("password reset requested for unknown email")
+			return nil
+		}
+		logger.Error("failed to get by email", zap.Error(err))
+		return
+	}
+
+	link, err := s.issueVerificationToken(ctx, data.ID, verification.PurposePasswordReset, passwordResetTTL, "/reset-password")
+	if err != nil {
+		return
+	}
+
+	msg := mail.Message{
+		To:      *data.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf("Hi %s,\n\nUse the link below to choose a new password. It expires in %s.\n\n%s\n\n"+
+			"If you did not ask for this, you can ignore this email.\n", *data.Name, helpers.FormatDuration(passwordResetTTL), link),
+	}
+
+	if err = s.mailer.Send(ctx, msg); err != nil {
+		logger.Error("failed to send password reset email", zap.Error(err))
+		return
+	}
+
+	return
+}
+
+// ResetPassword sets a new password using a token from ForgotPassword. The
+// token is single-use, and all refresh tokens of the user are revoked. A
+// password rejected by the policy leaves the token valid for another try;
+// otherwise the token is used up before the password is set, so concurrent
+// requests with the same token cannot both set one.
+func (s *Service) ResetPassword(ctx context.Context, req verification.ResetPasswordRequest) (err error) {
+	logger := log.LoggerFromContext(ctx).Named("ResetPassword")
+
+	hash := helpers.HashToken(*req.Token)
+
+	data, err := s.verificationRepository.Get(ctx, verification.PurposePasswordReset, hash)
+	if err != nil {
+		if errors.Is(err, store.ErrorNotFound) {
+			err = ErrInvalidVerificationToken
+			return
+		}
+		logger.Error("failed to get token", zap.Error(err))
+		return
+	}
+	logger = logger.With(zap.String("userID", data.UserID))
+
+	account, err := s.userRepository.Get(ctx, data.UserID)
+	if err != nil {
+		logger.Error("failed to get by id", zap.Error(err))
+		return
+	}
+
+	if err = s.credentials.Check(*req.Password, *account.Email); err != nil {
+		return
+	}
+
+	if _, err = s.verificationRepository.Consume(ctx, verification.PurposePasswordReset, hash); err != nil {
+		if errors.Is(err, store.ErrorNotFound) {
+			err = ErrInvalidVerificationToken
+			return
+		}
+		logger.Error("failed to consume token", zap.Error(err))
+		return
+	}
+
+	if err = s.credentials.SetPassword(ctx, data.UserID, *req.Password, false); err != nil {
+		return
+	}
+
+	// Following the emailed link also proves the address belongs to the user.
+	now := time.Now()
+	if err = s.userRepository.Update(ctx, data.UserID, user.Entity{EmailVerifiedAt: &now}); err != nil {
+		logger.Error("failed to update by id", zap.Error(err))
+		return
+	}
+
+	return
+}
+
+// SendEmailVerification mails a verification link to the address of the user.
+func (s *Service) SendEmailVerification(ctx context.Context, userID string) (err error) {
+	logger := log.LoggerFromContext(ctx).Named("SendEmailVerification").With(zap.String("userID", userID))
+
+	data, err := s.userRepository.Get(ctx, userID)
+	if err != nil {
+		logger.Error("failed to get by id", zap.Error(err))
+		return
+	}
+
+	link, err := s.issueVerificationToken(ctx, data.ID, verification.PurposeEmailVerification, emailVerificationTTL, "/verify-email")
+	if err != nil {
+		return
+	}
+
+	msg := mail.Message{
+		To:      *data.Email,
+		Subject: "Verify your email address",
+		Body: fmt.Sprintf("Hi %s,\n\nPlease confirm your email address by opening the link below. It expires in %s.\n\n%s\n",
+			*data.Name, helpers.FormatDuration(emailVerificationTTL), link),
+	}
+
+	if err = s.mailer.Send(ctx, msg); err != nil {
+		logger.Error("failed to send verification email", zap.Error(err))
+		return
+	}
+
+	return
+}
+
+// ResendEmailVerification mails a new verification link to an unverified
+// account. Like ForgotPassword it does not reveal whether the email exists.
+func (s *Service) ResendEmailVerification(ctx context.Context, email string) (err error) {
+	logger := log.LoggerFromContext(ctx).Named("ResendEmailVerification")
+
+	data, err := s.userRepository.GetByEmail(ctx, email)
+	if err != nil {
+		if errors.Is(err, store.ErrorNotFound) {
+			return nil
+		}
+		logger.Error("failed to get by email", zap.Error(err))
+		return
+	}
+
+	if data.EmailVerifiedAt != nil {
+		return
+	}
+
+	return s.SendEmailVerification(ctx, data.ID)
+}
+
+func (s *Service) VerifyEmail(ctx context.Context, token string) (err error) {
+	logger := log.LoggerFromContext(ctx).Named("VerifyEmail")
+
+	data, err := s.verificationRepository.Consume(ctx, verification.PurposeEmailVerification, helpers.HashToken(token))
+	if err != nil {
+		if errors.Is(err, store.ErrorNotFound) {
+			err = ErrInvalidVerificationToken
+			return
+		}
+		logger.Error("failed to consume token", zap.Error(err))
+		return
+	}
+
+	now := time.Now()
+	if err = s.userRepository.Update(ctx, data.UserID, user.Entity{EmailVerifiedAt: &now}); err != nil {
+		logger.Error("failed to update by id", zap.Error(err), zap.String("userID", data.UserID))
+		return
+	}
+
+	return
+}
+
+// issueVerificationToken replaces any pending token of the same purpose with
+// a new one and returns the link to send. Only the hash is stored.
+func (s *Service) issueVerificationToken(ctx context.Context, userID, purpose string, ttl time.Duration, path string) (link string, err error) {
+	logger := log.LoggerFromContext(ctx).Named("issueVerificationToken").
+		With(zap.String("userID", userID), zap.String("purpose", purpose))
+
+	if err = s.verificationRepository.Invalidate(ctx, userID, purpose); err != nil {
+		logger.Error("failed to invalidate pending tokens", zap.Error(err))
+		return
+	}
+
+	token, err := helpers.GenerateToken(32)
+	if err != nil {
+		logger.Error("failed to generate token", zap.Error(err))
+		return
+	}
+
+	data := verification.Entity{
+		UserID:    userID,
+		Purpose:   purpose,
+		TokenHash: helpers.HashToken(token),
+		ExpiresAt: time.Now().Add(ttl),
+	}
+
+	if _, err = s.verificationRepository.Add(ctx, data); err != nil {
+		logger.Error("failed to store token", zap.Error(err))
+		return
+	}
+
+	link = strings.TrimSuffix(s.publicURL, "/") + path + "?token=" + url.QueryEscape(token)
+
+	return
+}
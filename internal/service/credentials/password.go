@@ -0,0 +1,189 @@
+package credentials
+
+import (
+	"context"
+	"errors"
+	"github.com/yrss1/todo/internal/domain/audit"
+	"github.com/yrss1/todo/internal/domain/user"
+	"github.com/yrss1/todo/pkg/helpers"
+	"github.com/yrss1/todo/pkg/log"
+	"github.com/yrss1/todo/pkg/password"
+	"github.com/yrss1/todo/pkg/store"
+	"go.uber.org/zap"
+)
+
+var ErrInvalidPassword = errors.New("old password is incorrect")
+
+// temporaryPasswordSize gives temporary passwords 96 bits of randomness,
+// encoded as 16 characters that are easy to read out.
+const temporaryPasswordSize = 12
+
+// Check reports whether plain is acceptable as a new password for the
+// account with the given email.
+func (s *Service) Check(plain, email string) error {
+	return s.policy.Check(plain, email)
+}
+
+// Hash checks a new password against the policy for the account with the
+// given email and returns the hash to store.
+func (s *Service) Hash(plain, email string) (hash string, err error) {
+	if err = s.Check(plain, email); err != nil {
+		return
+	}
+
+	return s.hasher.Hash(plain)
+}
+
+// Verify reports whether plain is the password of data. A hash made with
+// an older algorithm or cost is replaced while the plain password is at
+// hand; failing to do so only delays the upgrade.
+func (s *Service) Verify(ctx context.Context, data user.Entity, plain string) (ok bool, err error) {
+	logger := log.LoggerFromContext(ctx).Named("Verify").With(zap.String("id", data.ID))
+
+	if data.Password == nil {
+		return false, nil
+	}
+
+	ok, rehash, err := s.hasher.Verify(*data.Password, plain)
+	if err != nil {
+		if errors.Is(err, password.ErrUnknownHash) {
+			logger.Warn("stored password is not a known hash")
+			return false, nil
+		}
+		logger.Error("failed to verify password", zap.Error(err))
+		return
+	}
+
+	if ok && rehash {
+		hash, hashErr := s.hasher.Hash(plain)
+		if hashErr == nil {
+			hashErr = s.userRepository.Update(ctx, data.ID, user.Entity{Password: &hash})
+		}
+		if hashErr != nil {
+			logger.Warn("failed to upgrade password hash", zap.Error(hashErr))
+		}
+	}
+
+	return
+}
+
+// SetPassword replaces the password of the user and revokes all refresh
+// tokens, so every device has to log in again. With changeRequired the user
+// must choose a new password on the next login.
+func (s *Service) SetPassword(ctx context.Context, id, plain string, changeRequired bool) (err error) {
+	return s.setPassword(ctx, id, plain, changeRequired, "")
+}
+
+// setPassword is SetPassword that keeps the session keepSessionID signed in,
+// if it is set and belongs to the user.
+func (s *Service) setPassword(ctx context.Context, id, plain string, changeRequired bool, keepSessionID string) (err error) {
+	logger := log.LoggerFromContext(ctx).Named("SetPassword").With(zap.String("id", id))
+
+	data, err := s.userRepository.Get(ctx, id)
+	if err != nil {
+		logger.Error("failed to get by id", zap.Error(err))
+		return
+	}
+
+	hash, err := s.Hash(plain, *data.Email)
+	if err != nil {
+		return
+	}
+
+	update := user.Entity{
+		Password:               &hash,
+		PasswordChangeRequired: &changeRequired,
+	}
+
+	if err = s.userRepository.Update(ctx, id, update); err != nil {
+		logger.Error("failed to update by id", zap.Error(err))
+		return
+	}
+
+	if err = s.revokeSessions(ctx, id, keepSessionID); err != nil {
+		logger.Error("failed to revoke refresh tokens", zap.Error(err))
+		return
+	}
+	s.audit.Record(ctx, audit.ActionPasswordChanged, "", id, map[string]any{"change_required": changeRequired})
+
+	return
+}
+
+// revokeSessions signs out every session of the user except keepSessionID.
+// Without a live session to keep, all of them are signed out.
+func (s *Service) revokeSessions(ctx context.Context, id, keepSessionID string) (err error) {
+	if keepSessionID != "" && s.sessionRepository != nil {
+		current, err := s.sessionRepository.Get(ctx, keepSessionID)
+		switch {
+		case err == nil && current.UserID == id && current.RevokedAt == nil:
+			return s.tokenRepository.RevokeOtherFamilies(ctx, id, current.FamilyID)
+		case err != nil && !errors.Is(err, store.ErrorNotFound):
+			return err
+		}
+	}
+
+	return s.tokenRepository.RevokeByUser(ctx, id)
+}
+
+// ChangePassword replaces the password after checking the current one. The
+// session the user changed it from, sessionID, stays signed in; every other
+// session is signed out.
+func (s *Service) ChangePassword(ctx context.Context, id, sessionID, oldPassword, newPassword string) (err error) {
+	logger := log.LoggerFromContext(ctx).Named("ChangePassword").With(zap.String("id", id))
+
+	data, err := s.userRepository.Get(ctx, id)
+	if err != nil {
+		logger.Error("failed to get by id", zap.Error(err))
+		return
+	}
+
+	ok, err := s.Verify(ctx, data, oldPassword)
+	if err != nil {
+		return
+	}
+	if !ok {
+		logger.Warn("invalid old password")
+		err = ErrInvalidPassword
+		return
+	}
+
+	return s.setPassword(ctx, id, newPassword, false, sessionID)
+}
+
+// SetTemporaryPassword gives the user a random password that has to be
+// changed on the next login, and returns it so an admin can pass it on.
+func (s *Service) SetTemporaryPassword(ctx context.Context, id string) (temporary string, err error) {
+	logger := log.LoggerFromContext(ctx).Named("SetTemporaryPassword").With(zap.String("id", id))
+
+	temporary, err = helpers.GenerateToken(temporaryPasswordSize)
+	if err != nil {
+		logger.Error("failed to generate password", zap.Error(err))
+		return
+	}
+
+	if err = s.SetPassword(ctx, id, temporary, true); err != nil {
+		return "", err
+	}
+
+	return
+}
+
+// RequirePasswordChange keeps the current password but makes the user
+// choose a new one on the next login. Existing logins are signed out.
+func (s *Service) RequirePasswordChange(ctx context.Context, id string) (err error) {
+	logger := log.LoggerFromContext(ctx).Named("RequirePasswordChange").With(zap.String("id", id))
+
+	required := true
+	if err = s.userRepository.Update(ctx, id, user.Entity{PasswordChangeRequired: &required}); err != nil {
+		logger.Error("failed to update by id", zap.Error(err))
+		return
+	}
+
+	if err = s.tokenRepository.RevokeByUser(ctx, id); err != nil {
+		logger.Error("failed to revoke refresh tokens", zap.Error(err))
+		return
+	}
+	s.audit.Record(ctx, audit.ActionPasswordChangeRequired, "", id, nil)
+
+	return
+}
@@ -0,0 +1,89 @@
+package credentials
+
+import (
+	"github.com/yrss1/todo/internal/domain/session"
+	"github.com/yrss1/todo/internal/domain/token"
+	"github.com/yrss1/todo/internal/domain/user"
+	auditService "github.com/yrss1/todo/internal/service/audit"
+	"github.com/yrss1/todo/pkg/password"
+)
+
+type Configuration func(s *Service) error
+
+// Service owns everything about user passwords: the policy, hashing and
+// upgrading hashes, and rotating passwords. The auth and account services
+// share one instance so every path stores passwords the same way.
+type Service struct {
+	userRepository    user.Repository
+	tokenRepository   token.Repository
+	sessionRepository session.Repository
+
+	hasher *password.Hasher
+	policy *password.Policy
+
+	audit *auditService.Service
+}
+
+func New(configs ...Configuration) (s *Service, err error) {
+	s = &Service{}
+
+	if s.hasher, err = password.NewHasher(password.Argon2id, password.DefaultArgon2Params, 0); err != nil {
+		return
+	}
+	if s.policy, err = password.NewPolicy(password.DefaultMinLength, password.DefaultMaxLength, ""); err != nil {
+		return
+	}
+
+	for _, cfg := range configs {
+		if err = cfg(s); err != nil {
+			return
+		}
+	}
+
+	return
+}
+
+func WithUserRepository(userRepository user.Repository) Configuration {
+	return func(s *Service) error {
+		s.userRepository = userRepository
+		return nil
+	}
+}
+
+func WithTokenRepository(tokenRepository token.Repository) Configuration {
+	return func(s *Service) error {
+		s.tokenRepository = tokenRepository
+		return nil
+	}
+}
+
+// WithSessionRepository lets ChangePassword keep the session it was called
+// from signed in.
+func WithSessionRepository(sessionRepository session.Repository) Configuration {
+	return func(s *Service) error {
+		s.sessionRepository = sessionRepository
+		return nil
+	}
+}
+
+func WithHasher(hasher *password.Hasher) Configuration {
+	return func(s *Service) error {
+		s.hasher = hasher
+		return nil
+	}
+}
+
+func WithPolicy(policy *password.Policy) Configuration {
+	return func(s *Service) error {
+		s.policy = policy
+		return nil
+	}
+}
+
+// WithAudit records password changes in the audit trail.
+func WithAudit(audit *auditService.Service) Configuration {
+	return func(s *Service) error {
+		s.audit = audit
+		return nil
+	}
+}
@@ -0,0 +1,142 @@
+package privacy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/yrss1/todo/internal/domain/audit"
+	"github.com/yrss1/todo/internal/domain/privacy"
+	"github.com/yrss1/todo/internal/service/credentials"
+	"github.com/yrss1/todo/pkg/log"
+	"github.com/yrss1/todo/pkg/mail"
+	"github.com/yrss1/todo/pkg/store"
+	"go.uber.org/zap"
+	"strings"
+	"time"
+)
+
+// ScheduleDeletion deletes or anonymizes the account after the grace
+// period, once the password confirms the request. Scheduling again replaces
+// the earlier request and restarts the grace period.
+func (s *Service) ScheduleDeletion(ctx context.Context, userID string, req privacy.DeletionRequest) (res privacy.DeletionResponse, err error) {
+	logger := log.LoggerFromContext(ctx).Named("ScheduleDeletion").With(zap.String("userID", userID))
+
+	account, err := s.userRepository.Get(ctx, userID)
+	if err != nil {
+		logger.Error("failed to get by id", zap.Error(err))
+		return
+	}
+
+	ok, err := s.credentials.Verify(ctx, account, *req.Password)
+	if err != nil {
+		return
+	}
+	if !ok {
+		logger.Warn("invalid password")
+		err = credentials.ErrInvalidPassword
+		return
+	}
+
+	data, err := s.privacyRepository.ScheduleDeletion(ctx, privacy.Deletion{
+		UserID:       userID,
+		Mode:         *req.Mode,
+		ScheduledFor: time.Now().Add(s.deletionGrace),
+	})
+	if err != nil {
+		logger.Error("failed to schedule deletion", zap.Error(err))
+		return
+	}
+	res = privacy.ParseFromDeletion(data)
+
+	s.notify(ctx, mail.Message{
+		To:      *account.Email,
+		Subject: "Your account is scheduled for deletion",
+		Body: fmt.Sprintf("Hi %s,\n\nYour account will be deleted on %s.\n\n"+
+			"If you did not ask for this or changed your mind, log in before then and cancel the deletion at %s.\n",
+			*account.Name, data.ScheduledFor.In(account.Location()).Format(time.RFC1123), s.link("/settings/account")),
+	})
+
+	return
+}
+
+func (s *Service) GetDeletion(ctx context.Context, userID string) (res privacy.DeletionResponse, err error) {
+	logger := log.LoggerFromContext(ctx).Named("GetDeletion").With(zap.String("userID", userID))
+
+	data, err := s.privacyRepository.GetDeletion(ctx, userID)
+	if err != nil {
+		if !errors.Is(err, store.ErrorNotFound) {
+			logger.Error("failed to get deletion", zap.Error(err))
+		}
+		return
+	}
+
+	res = privacy.ParseFromDeletion(data)
+
+	return
+}
+
+func (s *Service) CancelDeletion(ctx context.Context, userID string) (err error) {
+	logger := log.LoggerFromContext(ctx).Named("CancelDeletion").With(zap.String("userID", userID))
+
+	err = s.privacyRepository.CancelDeletion(ctx, userID)
+	if err != nil && !errors.Is(err, store.ErrorNotFound) {
+		logger.Error("failed to cancel deletion", zap.Error(err))
+		return
+	}
+
+	return
+}
+
+// ProcessDeletions carries out every deletion whose grace period is over.
+// A failed deletion stays scheduled and is tried again on the next run.
+func (s *Service) ProcessDeletions(ctx context.Context) (err error) {
+	logger := log.LoggerFromContext(ctx).Named("ProcessDeletions")
+
+	data, err := s.privacyRepository.DueDeletions(ctx, time.Now())
+	if err != nil {
+		logger.Error("failed to select due deletions", zap.Error(err))
+		return
+	}
+
+	for _, object := range data {
+		logger := logger.With(zap.String("userID", object.UserID), zap.String("mode", object.Mode))
+
+		switch object.Mode {
+		case privacy.ModeAnonymize:
+			// The deletion row outlives the anonymized user, so drop it too.
+			if err = s.userRepository.Anonymize(ctx, object.UserID); err == nil {
+				err = s.privacyRepository.CancelDeletion(ctx, object.UserID)
+			}
+		default:
+			err = s.userRepository.Delete(ctx, object.UserID)
+		}
+		if err != nil && !errors.Is(err, store.ErrorNotFound) {
+			logger.Error("failed to delete account", zap.Error(err))
+			continue
+		}
+
+		// The owner asked for the deletion, so they are recorded as the actor.
+		if err == nil {
+			s.audit.Record(ctx, audit.ActionUserDeleted, object.UserID, object.UserID, map[string]any{"mode": object.Mode, "scheduled": true})
+		}
+		logger.Info("account deleted")
+	}
+
+	return nil
+}
+
+// notify sends msg if a mailer is configured. Notices are best effort; the
+// action they describe has already happened.
+func (s *Service) notify(ctx context.Context, msg mail.Message) {
+	if s.mailer == nil {
+		return
+	}
+
+	if err := s.mailer.Send(ctx, msg); err != nil {
+		log.LoggerFromContext(ctx).Named("notify").Warn("failed to send email", zap.String("subject", msg.Subject), zap.Error(err))
+	}
+}
+
+func (s *Service) link(path string) string {
+	return strings.TrimSuffix(s.publicURL, "/") + path
+}
@@ -0,0 +1,241 @@
+package privacy
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/yrss1/todo/internal/domain/accesstoken"
+	"github.com/yrss1/todo/internal/domain/privacy"
+	"github.com/yrss1/todo/internal/domain/task"
+	"github.com/yrss1/todo/internal/domain/template"
+	"github.com/yrss1/todo/internal/domain/user"
+	"github.com/yrss1/todo/internal/domain/workspace"
+	"github.com/yrss1/todo/pkg/log"
+	"github.com/yrss1/todo/pkg/mail"
+	"github.com/yrss1/todo/pkg/store"
+	"go.uber.org/zap"
+	"io"
+	"strings"
+	"time"
+)
+
+// staleExportAfter is how long an export may run before another worker
+// assumes its instance died and starts it over.
+const staleExportAfter = time.Hour
+
+// RequestExport queues an archive of all data of the user. While one is
+// still queued or running, that one is returned instead of a new one.
+func (s *Service) RequestExport(ctx context.Context, userID string) (res privacy.ExportResponse, err error) {
+	logger := log.LoggerFromContext(ctx).Named("RequestExport").With(zap.String("userID", userID))
+
+	exports, err := s.privacyRepository.ListExports(ctx, userID)
+	if err != nil {
+		logger.Error("failed to select exports", zap.Error(err))
+		return
+	}
+	for _, object := range exports {
+		if object.Status == privacy.ExportPending || object.Status == privacy.ExportRunning {
+			return privacy.ParseFromExport(object), nil
+		}
+	}
+
+	data, err := s.privacyRepository.AddExport(ctx, userID)
+	if err != nil {
+		logger.Error("failed to add export", zap.Error(err))
+		return
+	}
+
+	res = privacy.ParseFromExport(data)
+
+	return
+}
+
+func (s *Service) ListExports(ctx context.Context, userID string) (res []privacy.ExportResponse, err error) {
+	logger := log.LoggerFromContext(ctx).Named("ListExports").With(zap.String("userID", userID))
+
+	data, err := s.privacyRepository.ListExports(ctx, userID)
+	if err != nil {
+		logger.Error("failed to select exports", zap.Error(err))
+		return
+	}
+
+	res = privacy.ParseFromExports(data)
+
+	return
+}
+
+func (s *Service) GetArchive(ctx context.Context, userID, id string) (archive []byte, err error) {
+	logger := log.LoggerFromContext(ctx).Named("GetArchive").With(zap.String("userID", userID), zap.String("id", id))
+
+	archive, err = s.privacyRepository.GetArchive(ctx, userID, id)
+	if err != nil && !errors.Is(err, store.ErrorNotFound) {
+		logger.Error("failed to get archive", zap.Error(err))
+		return
+	}
+
+	return
+}
+
+// ProcessExports builds the archives of all queued exports, one at a time.
+func (s *Service) ProcessExports(ctx context.Context) (err error) {
+	logger := log.LoggerFromContext(ctx).Named("ProcessExports")
+
+	for ctx.Err() == nil {
+		data, err := s.privacyRepository.ClaimExport(ctx, staleExportAfter)
+		if err != nil {
+			if errors.Is(err, store.ErrorNotFound) {
+				return nil
+			}
+			logger.Error("failed to claim export", zap.Error(err))
+			return err
+		}
+		logger := logger.With(zap.String("id", data.ID), zap.String("userID", data.UserID))
+
+		archive, err := s.buildArchive(ctx, data.UserID)
+		if err != nil {
+			logger.Error("failed to build archive", zap.Error(err))
+			if err = s.privacyRepository.FailExport(ctx, data.ID, "the archive could not be built"); err != nil {
+				logger.Error("failed to mark export as failed", zap.Error(err))
+			}
+			continue
+		}
+
+		expiresAt := time.Now().Add(s.exportTTL)
+		if err = s.privacyRepository.CompleteExport(ctx, data.ID, archive, expiresAt); err != nil {
+			logger.Error("failed to store archive", zap.Error(err))
+			continue
+		}
+		logger.Info("export ready", zap.Int("size", len(archive)))
+
+		s.notifyExportReady(ctx, data.UserID, expiresAt)
+	}
+
+	return ctx.Err()
+}
+
+func (s *Service) notifyExportReady(ctx context.Context, userID string, expiresAt time.Time) {
+	account, err := s.userRepository.Get(ctx, userID)
+	if err != nil {
+		log.LoggerFromContext(ctx).Named("notifyExportReady").Warn("failed to get by id", zap.String("userID", userID), zap.Error(err))
+		return
+	}
+
+	s.notify(ctx, mail.Message{
+		To:      *account.Email,
+		Subject: "Your data export is ready",
+		Body: fmt.Sprintf("Hi %s,\n\nThe archive of your data is ready. Download it from %s before %s.\n",
+			*account.Name, s.link("/settings/account"), expiresAt.In(account.Location()).Format(time.RFC1123)),
+	})
+}
+
+// exportProfile is the profile part of an archive. Unlike user.Response it
+// includes everything stored about the account except secrets.
+type exportProfile struct {
+	user.Response
+	user.SettingsResponse
+
+	EmailVerifiedAt *time.Time       `json:"email_verified_at,omitempty"`
+	Identities      []exportIdentity `json:"identities"`
+}
+
+type exportIdentity struct {
+	Provider string  `json:"provider"`
+	Subject  string  `json:"subject"`
+	Email    *string `json:"email,omitempty"`
+}
+
+// buildArchive collects the data of the user into a zip file with one JSON
+// document per kind of data, plus the avatar image.
+func (s *Service) buildArchive(ctx context.Context, userID string) (archive []byte, err error) {
+	account, err := s.userRepository.Get(ctx, userID)
+	if err != nil {
+		return
+	}
+
+	profile := exportProfile{
+		Response:         user.ParseFromEntity(account),
+		SettingsResponse: user.ParseSettingsFromEntity(account),
+		EmailVerifiedAt:  account.EmailVerifiedAt,
+		Identities:       make([]exportIdentity, 0),
+	}
+
+	identities, err := s.identityRepository.List(ctx, userID)
+	if err != nil {
+		return
+	}
+	for _, object := range identities {
+		profile.Identities = append(profile.Identities, exportIdentity{Provider: object.Provider, Subject: object.Subject, Email: object.Email})
+	}
+
+	tasks := make([]task.Response, 0)
+	err = s.taskRepository.Stream(ctx, userID, "", "", "", "", "", func(data task.Entity) error {
+		tasks = append(tasks, task.ParseFromEntity(data))
+		return nil
+	})
+	if err != nil {
+		return
+	}
+
+	templates, err := s.templateRepository.List(ctx, userID, "")
+	if err != nil {
+		return
+	}
+
+	tokens, err := s.accessTokenRepository.List(ctx, userID)
+	if err != nil {
+		return
+	}
+
+	workspaces, err := s.workspaceRepository.List(ctx, userID)
+	if err != nil {
+		return
+	}
+
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+
+	documents := []struct {
+		name string
+		data any
+	}{
+		{"profile.json", profile},
+		{"tasks.json", tasks},
+		{"templates.json", template.ParseFromEntities(templates)},
+		{"workspaces.json", workspace.ParseFromEntities(workspaces)},
+		{"access_tokens.json", accesstoken.ParseFromEntities(tokens)},
+	}
+	for _, document := range documents {
+		var f io.Writer
+		if f, err = w.Create(document.name); err != nil {
+			return
+		}
+		encoder := json.NewEncoder(f)
+		encoder.SetIndent("", "  ")
+		if err = encoder.Encode(document.data); err != nil {
+			return
+		}
+	}
+
+	avatar, err := s.userRepository.GetAvatar(ctx, userID)
+	switch {
+	case err == nil:
+		var f io.Writer
+		if f, err = w.Create("avatar." + strings.TrimPrefix(avatar.ContentType, "image/")); err != nil {
+			return
+		}
+		if _, err = f.Write(avatar.Data); err != nil {
+			return
+		}
+	case !errors.Is(err, store.ErrorNotFound):
+		return
+	}
+
+	if err = w.Close(); err != nil {
+		return
+	}
+
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,146 @@
+package privacy
+
+import (
+	"github.com/yrss1/todo/internal/domain/accesstoken"
+	"github.com/yrss1/todo/internal/domain/identity"
+	"github.com/yrss1/todo/internal/domain/privacy"
+	"github.com/yrss1/todo/internal/domain/task"
+	"github.com/yrss1/todo/internal/domain/template"
+	"github.com/yrss1/todo/internal/domain/user"
+	"github.com/yrss1/todo/internal/domain/workspace"
+	auditService "github.com/yrss1/todo/internal/service/audit"
+	"github.com/yrss1/todo/internal/service/credentials"
+	"github.com/yrss1/todo/pkg/mail"
+	"time"
+)
+
+const (
+	defaultDeletionGrace = 14 * 24 * time.Hour
+	defaultExportTTL     = 7 * 24 * time.Hour
+)
+
+type Configuration func(s *Service) error
+
+// Service handles the data of a user as a whole: scheduled account
+// deletion or anonymization, and archives of everything stored about them.
+// The slow parts run in the background through Run.
+type Service struct {
+	privacyRepository     privacy.Repository
+	userRepository        user.Repository
+	taskRepository        task.Repository
+	templateRepository    template.Repository
+	accessTokenRepository accesstoken.Repository
+	identityRepository    identity.Repository
+	workspaceRepository   workspace.Repository
+
+	credentials *credentials.Service
+	audit       *auditService.Service
+	mailer      mail.Mailer
+	publicURL   string
+
+	deletionGrace time.Duration
+	exportTTL     time.Duration
+}
+
+func New(configs ...Configuration) (s *Service, err error) {
+	s = &Service{
+		deletionGrace: defaultDeletionGrace,
+		exportTTL:     defaultExportTTL,
+	}
+
+	for _, cfg := range configs {
+		if err = cfg(s); err != nil {
+			return
+		}
+	}
+
+	return
+}
+
+func WithPrivacyRepository(privacyRepository privacy.Repository) Configuration {
+	return func(s *Service) error {
+		s.privacyRepository = privacyRepository
+		return nil
+	}
+}
+
+func WithUserRepository(userRepository user.Repository) Configuration {
+	return func(s *Service) error {
+		s.userRepository = userRepository
+		return nil
+	}
+}
+
+func WithTaskRepository(taskRepository task.Repository) Configuration {
+	return func(s *Service) error {
+		s.taskRepository = taskRepository
+		return nil
+	}
+}
+
+func WithTemplateRepository(templateRepository template.Repository) Configuration {
+	return func(s *Service) error {
+		s.templateRepository = templateRepository
+		return nil
+	}
+}
+
+func WithAccessTokenRepository(accessTokenRepository accesstoken.Repository) Configuration {
+	return func(s *Service) error {
+		s.accessTokenRepository = accessTokenRepository
+		return nil
+	}
+}
+
+func WithIdentityRepository(identityRepository identity.Repository) Configuration {
+	return func(s *Service) error {
+		s.identityRepository = identityRepository
+		return nil
+	}
+}
+
+func WithWorkspaceRepository(workspaceRepository workspace.Repository) Configuration {
+	return func(s *Service) error {
+		s.workspaceRepository = workspaceRepository
+		return nil
+	}
+}
+
+func WithCredentials(credentials *credentials.Service) Configuration {
+	return func(s *Service) error {
+		s.credentials = credentials
+		return nil
+	}
+}
+
+// WithMailer sends notices about scheduled deletions and finished exports.
+// publicURL is the base of the links in them.
+func WithMailer(mailer mail.Mailer, publicURL string) Configuration {
+	return func(s *Service) error {
+		s.mailer = mailer
+		s.publicURL = publicURL
+		return nil
+	}
+}
+
+// WithRetention sets how long a deletion waits before it runs and how long
+// a finished export can be downloaded.
+func WithRetention(deletionGrace, exportTTL time.Duration) Configuration {
+	return func(s *Service) error {
+		if deletionGrace > 0 {
+			s.deletionGrace = deletionGrace
+		}
+		if exportTTL > 0 {
+			s.exportTTL = exportTTL
+		}
+		return nil
+	}
+}
+
+// WithAudit records scheduled account deletions in the audit trail.
+func WithAudit(audit *auditService.Service) Configuration {
+	return func(s *Service) error {
+		s.audit = audit
+		return nil
+	}
+}
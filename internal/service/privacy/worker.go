@@ -0,0 +1,42 @@
+package privacy
+
+import (
+	"context"
+	"github.com/yrss1/todo/pkg/log"
+	"go.uber.org/zap"
+	"time"
+)
+
+// Run processes due deletions and queued exports every interval until ctx
+// is cancelled. Every instance of the service may run it; the repository
+// makes sure each export is built once.
+func (s *Service) Run(ctx context.Context, interval time.Duration) {
+	logger := log.LoggerFromContext(ctx).Named("privacy")
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		s.runOnce(ctx, logger)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *Service) runOnce(ctx context.Context, logger *zap.Logger) {
+	if err := s.ProcessDeletions(ctx); err != nil {
+		logger.Warn("deletion run failed", zap.Error(err))
+	}
+
+	if err := s.ProcessExports(ctx); err != nil && ctx.Err() == nil {
+		logger.Warn("export run failed", zap.Error(err))
+	}
+
+	if err := s.privacyRepository.DeleteExpiredExports(ctx, time.Now()); err != nil && ctx.Err() == nil {
+		logger.Warn("failed to delete expired exports", zap.Error(err))
+	}
+}
@@ -0,0 +1,244 @@
+package todo
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"github.com/yrss1/todo/internal/domain/task"
+	"github.com/yrss1/todo/pkg/helpers"
+	"github.com/yrss1/todo/pkg/log"
+	"go.uber.org/zap"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrUnknownExportFormat = errors.New("format must be one of 'csv', 'json' or 'ics'")
+
+const icsTimeLayout = "20060102T150405Z"
+
+// exportFlushRows is how many tasks are written between flushes of the
+// response, so large exports reach the client while they are being read.
+const exportFlushRows = 100
+
+// ExportTasks writes every task of the user in the workspace matching the filters to w in
+// the given format while the rows are being read from the repository.
+func (s *Service) ExportTasks(ctx context.Context, w io.Writer, format, userID, workspaceID, titleFilter, statusFilter, sortBy, sortOrder string) (err error) {
+	logger := log.LoggerFromContext(ctx).Named("ExportTasks").
+		With(zap.String("userID", userID), zap.String("workspaceID", workspaceID), zap.String("format", format))
+
+	var exporter taskExporter
+	switch format {
+	case "csv":
+		exporter = newCSVExporter(w, s.location(ctx, userID))
+	case "json":
+		exporter = newJSONExporter(w)
+	case "ics":
+		exporter = newICSExporter(w)
+	default:
+		return ErrUnknownExportFormat
+	}
+
+	if err = exporter.begin(); err != nil {
+		return
+	}
+
+	var rows int
+	err = s.taskRepository.Stream(ctx, userID, workspaceID, titleFilter, statusFilter, sortBy, sortOrder, func(data task.Entity) error {
+		if err := exporter.write(data); err != nil {
+			return err
+		}
+		if rows++; rows%exportFlushRows == 0 {
+			helpers.Flush(w)
+		}
+		return nil
+	})
+	if err != nil {
+		logger.Error("failed to export", zap.Error(err))
+		return
+	}
+
+	if err = exporter.end(); err != nil {
+		return
+	}
+	helpers.Flush(w)
+
+	return
+}
+
+type taskExporter interface {
+	begin() error
+	write(task.Entity) error
+	end() error
+}
+
+type csvExporter struct {
+	writer *csv.Writer
+
+	// loc is the time zone due dates are written in, so the file reads
+	// the way the user sees the tasks.
+	loc *time.Location
+}
+
+func newCSVExporter(w io.Writer, loc *time.Location) *csvExporter {
+	return &csvExporter{writer: csv.NewWriter(w), loc: loc}
+}
+
+func (e *csvExporter) begin() error {
+	return e.writer.Write(importFields)
+}
+
+func (e *csvExporter) write(data task.Entity) error {
+	res := task.ParseFromEntity(data)
+
+	record := []string{res.Title, res.Description, res.Status, "", "", "", strings.Join(res.Tags, ",")}
+	if res.StoryPoints != nil {
+		record[3] = strconv.Itoa(*res.StoryPoints)
+	}
+	if res.EstimatedMinutes != nil {
+		record[4] = strconv.Itoa(*res.EstimatedMinutes)
+	}
+	if res.DueDate != nil {
+		record[5] = res.DueDate.In(e.loc).Format(time.RFC3339)
+	}
+	for i := range record {
+		record[i] = helpers.EscapeCSVCell(record[i])
+	}
+
+	if err := e.writer.Write(record); err != nil {
+		return err
+	}
+	e.writer.Flush()
+
+	return e.writer.Error()
+}
+
+func (e *csvExporter) end() error {
+	e.writer.Flush()
+	return e.writer.Error()
+}
+
+type jsonExporter struct {
+	writer  io.Writer
+	encoder *json.Encoder
+	count   int
+}
+
+func newJSONExporter(w io.Writer) *jsonExporter {
+	return &jsonExporter{writer: w, encoder: json.NewEncoder(w)}
+}
+
+func (e *jsonExporter) begin() (err error) {
+	_, err = io.WriteString(e.writer, "[")
+	return
+}
+
+func (e *jsonExporter) write(data task.Entity) (err error) {
+	if e.count > 0 {
+		if _, err = io.WriteString(e.writer, ","); err != nil {
+			return
+		}
+	}
+	e.count++
+
+	return e.encoder.Encode(task.ParseFromEntity(data))
+}
+
+func (e *jsonExporter) end() (err error) {
+	_, err = io.WriteString(e.writer, "]\n")
+	return
+}
+
+// icsExporter writes tasks as VTODO components of an RFC 5545 calendar.
+type icsExporter struct {
+	writer *bufio.Writer
+	stamp  string
+}
+
+func newICSExporter(w io.Writer) *icsExporter {
+	return &icsExporter{
+		writer: bufio.NewWriter(w),
+		stamp:  time.Now().UTC().Format(icsTimeLayout),
+	}
+}
+
+func (e *icsExporter) begin() error {
+	e.line("BEGIN:VCALENDAR")
+	e.line("VERSION:2.0")
+	e.line("PRODID:-//yrss1//todo//EN")
+	e.line("CALSCALE:GREGORIAN")
+
+	return e.writer.Flush()
+}
+
+func (e *icsExporter) write(data task.Entity) error {
+	res := task.ParseFromEntity(data)
+
+	e.line("BEGIN:VTODO")
+	e.line("UID:" + res.ID + "@todo")
+	e.line("DTSTAMP:" + e.stamp)
+	e.line("SUMMARY:" + icsEscape(res.Title))
+	if res.Description != "" {
+		e.line("DESCRIPTION:" + icsEscape(res.Description))
+	}
+	if res.Status == "done" {
+		e.line("STATUS:COMPLETED")
+		if res.CompletedAt != nil {
+			e.line("COMPLETED:" + res.CompletedAt.UTC().Format(icsTimeLayout))
+		}
+	} else {
+		e.line("STATUS:NEEDS-ACTION")
+	}
+	if res.DueDate != nil {
+		e.line("DUE:" + res.DueDate.UTC().Format(icsTimeLayout))
+	}
+	if len(res.Tags) > 0 {
+		categories := make([]string, 0, len(res.Tags))
+		for _, tag := range res.Tags {
+			categories = append(categories, icsEscape(tag))
+		}
+		e.line("CATEGORIES:" + strings.Join(categories, ","))
+	}
+	e.line("END:VTODO")
+
+	return e.writer.Flush()
+}
+
+func (e *icsExporter) end() error {
+	e.line("END:VCALENDAR")
+	return e.writer.Flush()
+}
+
+// line writes a content line folded at 75 octets as RFC 5545 requires.
+func (e *icsExporter) line(s string) {
+	limit := 75
+	for len(s) > limit {
+		cut := limit
+		for cut > 0 && !isRuneStart(s[cut]) {
+			cut--
+		}
+		e.writer.WriteString(s[:cut])
+		e.writer.WriteString("\r\n ")
+		s = s[cut:]
+		limit = 74
+	}
+	e.writer.WriteString(s)
+	e.writer.WriteString("\r\n")
+}
+
+func isRuneStart(b byte) bool {
+	return b&0xC0 != 0x80
+}
+
+func icsEscape(s string) string {
+	return strings.NewReplacer(
+		`\`, `\\`,
+		";", `\;`,
+		",", `\,`,
+		"\r\n", `\n`,
+		"\n", `\n`,
+	).Replace(s)
+}
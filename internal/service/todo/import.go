@@ -0,0 +1,253 @@
+package todo
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/yrss1/todo/internal/domain/task"
+	"github.com/yrss1/todo/pkg/helpers"
+	"github.com/yrss1/todo/pkg/log"
+	"go.uber.org/zap"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrInvalidImport       = errors.New("import contains invalid rows")
+	ErrMalformedImport     = errors.New("import file is malformed")
+	ErrUnknownImportFormat = errors.New("format must be either 'csv' or 'json'")
+)
+
+// importFields lists the task fields that can be filled from an import row.
+// Unless a mapping says otherwise, each field is read from the column of the
+// same name.
+var importFields = []string{"title", "description", "status", "story_points", "estimated_minutes", "due_date", "tags"}
+
+// ImportTasks reads CSV or JSON rows from r, validates each one as a
+// task.Request and stores all of them in one transaction. mapping maps task
+// fields to source columns. With dryRun nothing is stored and the report
+// only lists validation errors. When any row is invalid nothing is stored
+// and ErrInvalidImport is returned together with the report.
+func (s *Service) ImportTasks(ctx context.Context, userID, workspaceID, format string, r io.Reader, mapping map[string]string, dryRun bool) (res task.ImportResponse, err error) {
+	logger := log.LoggerFromContext(ctx).Named("ImportTasks").
+		With(zap.String("userID", userID), zap.String("workspaceID", workspaceID), zap.String("format", format), zap.Bool("dryRun", dryRun))
+
+	var rows []map[string]string
+	switch format {
+	case "csv":
+		rows, err = readCSVRows(r)
+	case "json":
+		rows, err = readJSONRows(r)
+	default:
+		err = ErrUnknownImportFormat
+		return
+	}
+	if err != nil {
+		err = fmt.Errorf("%w: %v", ErrMalformedImport, err)
+		return
+	}
+
+	columns := make(map[string]string, len(importFields))
+	for _, field := range importFields {
+		columns[field] = field
+	}
+	for field, column := range mapping {
+		if _, ok := columns[field]; !ok {
+			err = fmt.Errorf("%w: unknown mapping field %q", ErrMalformedImport, field)
+			return
+		}
+		columns[field] = strings.ToLower(strings.TrimSpace(column))
+	}
+
+	res = task.ImportResponse{
+		DryRun: dryRun,
+		Total:  len(rows),
+		Errors: make([]task.ImportRowError, 0),
+	}
+
+	loc := s.location(ctx, userID)
+
+	data := make([]task.Entity, 0, len(rows))
+	for i, row := range rows {
+		req, rowErr := parseImportRow(row, columns, loc)
+		if rowErr == nil {
+			req.UserID = &userID
+			req.WorkspaceID = &workspaceID
+			rowErr = req.Validate()
+		}
+		if rowErr != nil {
+			res.Errors = append(res.Errors, task.ImportRowError{Row: i + 1, Error: rowErr.Error()})
+			continue
+		}
+
+		data = append(data, task.Entity{
+			UserID:           req.UserID,
+			WorkspaceID:      req.WorkspaceID,
+			Title:            req.Title,
+			Description:      req.Description,
+			Status:           req.Status,
+			StoryPoints:      req.StoryPoints,
+			EstimatedMinutes: req.EstimatedMinutes,
+			DueDate:          req.DueDate,
+			Tags:             req.Tags,
+		})
+	}
+
+	if len(res.Errors) > 0 && !dryRun {
+		err = ErrInvalidImport
+		return
+	}
+	if dryRun || len(data) == 0 {
+		return
+	}
+
+	ids, err := s.taskRepository.AddMany(ctx, data)
+	if err != nil {
+		logger.Error("failed to import", zap.Error(err))
+		return
+	}
+	res.Imported = len(ids)
+
+	return
+}
+
+// parseImportRow turns one row into a task request. Due dates without a time
+// mean midnight in loc.
+func parseImportRow(row map[string]string, columns map[string]string, loc *time.Location) (req task.Request, err error) {
+	value := func(field string) string {
+		return strings.TrimSpace(row[columns[field]])
+	}
+
+	req.Title = helpers.GetStringPtr(value("title"))
+	req.Description = helpers.GetStringPtr(value("description"))
+	req.Status = helpers.GetStringPtr(strings.ToLower(value("status")))
+
+	if req.StoryPoints, err = parseImportInt("story_points", value("story_points")); err != nil {
+		return
+	}
+	if req.EstimatedMinutes, err = parseImportInt("estimated_minutes", value("estimated_minutes")); err != nil {
+		return
+	}
+
+	if v := value("due_date"); v != "" {
+		dueDate, parseErr := time.Parse(time.RFC3339, v)
+		if parseErr != nil {
+			if dueDate, parseErr = time.ParseInLocation(time.DateOnly, v, loc); parseErr != nil {
+				err = errors.New("due_date: must be RFC 3339 or YYYY-MM-DD")
+				return
+			}
+		}
+		req.DueDate = &dueDate
+	}
+
+	if v := value("tags"); v != "" {
+		for _, tag := range strings.Split(v, ",") {
+			if tag = strings.TrimSpace(tag); tag != "" {
+				req.Tags = append(req.Tags, tag)
+			}
+		}
+	}
+
+	return
+}
+
+func parseImportInt(field, value string) (*int, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	number, err := strconv.Atoi(value)
+	if err != nil {
+		return nil, fmt.Errorf("%s: must be an integer", field)
+	}
+
+	return &number, nil
+}
+
+func readCSVRows(r io.Reader) (rows []map[string]string, err error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			err = errors.New("csv: header row is missing")
+		}
+		return
+	}
+	for i := range header {
+		header[i] = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(header[i], "\ufeff")))
+	}
+	reader.FieldsPerRecord = len(header)
+
+	for {
+		record, readErr := reader.Read()
+		if errors.Is(readErr, io.EOF) {
+			break
+		}
+		if readErr != nil {
+			err = readErr
+			return
+		}
+
+		row := make(map[string]string, len(header))
+		for i, column := range header {
+			row[column] = helpers.UnescapeCSVCell(record[i])
+		}
+		rows = append(rows, row)
+	}
+
+	return
+}
+
+func readJSONRows(r io.Reader) (rows []map[string]string, err error) {
+	decoder := json.NewDecoder(r)
+	decoder.UseNumber()
+
+	if _, err = decoder.Token(); err != nil {
+		return
+	}
+
+	for decoder.More() {
+		object := map[string]any{}
+		if err = decoder.Decode(&object); err != nil {
+			return
+		}
+
+		row := make(map[string]string, len(object))
+		for key, value := range object {
+			row[strings.ToLower(key)] = stringifyImportValue(value)
+		}
+		rows = append(rows, row)
+	}
+
+	_, err = decoder.Token()
+
+	return
+}
+
+func stringifyImportValue(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	case bool:
+		return strconv.FormatBool(v)
+	case []any:
+		items := make([]string, 0, len(v))
+		for _, item := range v {
+			items = append(items, stringifyImportValue(item))
+		}
+		return strings.Join(items, ",")
+	default:
+		data, _ := json.Marshal(v)
+		return string(data)
+	}
+}
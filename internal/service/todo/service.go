@@ -1,13 +1,21 @@
 package todo
 
 import (
+	"context"
 	"github.com/yrss1/todo/internal/domain/task"
+	"github.com/yrss1/todo/internal/domain/template"
+	"github.com/yrss1/todo/internal/domain/user"
+	"github.com/yrss1/todo/pkg/log"
+	"go.uber.org/zap"
+	"time"
 )
 
 type Configuration func(s *Service) error
 
 type Service struct {
-	taskRepository task.Repository
+	taskRepository     task.Repository
+	templateRepository template.Repository
+	userRepository     user.Repository
 }
 
 func New(configs ...Configuration) (s *Service, err error) {
@@ -28,3 +36,33 @@ func WithTaskRepository(taskRepository task.Repository) Configuration {
 		return nil
 	}
 }
+
+func WithTemplateRepository(templateRepository template.Repository) Configuration {
+	return func(s *Service) error {
+		s.templateRepository = templateRepository
+		return nil
+	}
+}
+
+func WithUserRepository(userRepository user.Repository) Configuration {
+	return func(s *Service) error {
+		s.userRepository = userRepository
+		return nil
+	}
+}
+
+// location returns the time zone of the user. Dates fall back to UTC when
+// the user cannot be read, rather than failing the request.
+func (s *Service) location(ctx context.Context, userID string) *time.Location {
+	if s.userRepository == nil {
+		return time.UTC
+	}
+
+	data, err := s.userRepository.Get(ctx, userID)
+	if err != nil {
+		log.LoggerFromContext(ctx).Named("location").Warn("failed to get time zone", zap.String("userID", userID), zap.Error(err))
+		return time.UTC
+	}
+
+	return data.Location()
+}
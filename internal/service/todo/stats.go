@@ -0,0 +1,67 @@
+package todo
+
+import (
+	"context"
+	"errors"
+	"github.com/yrss1/todo/internal/domain/task"
+	"github.com/yrss1/todo/pkg/log"
+	"go.uber.org/zap"
+	"time"
+)
+
+// statsMaxDays bounds the period of GetStatistics per interval, so that a
+// request cannot make the database generate millions of buckets.
+var statsMaxDays = map[string]int{
+	"day":  366,
+	"week": 5 * 366,
+}
+
+var ErrStatsRangeTooLong = errors.New("period is too long: at most 366 days for interval=day and 5 years for interval=week")
+
+func (s *Service) GetEstimateRollup(ctx context.Context, userID, workspaceID string) (res task.RollupResponse, err error) {
+	logger := log.LoggerFromContext(ctx).Named("GetEstimateRollup").With(zap.String("userID", userID), zap.String("workspaceID", workspaceID))
+
+	data, err := s.taskRepository.Rollup(ctx, userID, workspaceID)
+	if err != nil {
+		logger.Error("failed to select", zap.Error(err))
+		return
+	}
+
+	res = task.ParseFromRollupEntities(data)
+
+	return
+}
+
+// GetStatistics counts the tasks of the user in the workspace between the days from and to,
+// both inclusive and taken in the user's time zone. They default to 30 days
+// ago and today; only the calendar date of each is used. Longer periods than
+// statsMaxDays allows for the interval return ErrStatsRangeTooLong.
+func (s *Service) GetStatistics(ctx context.Context, userID, workspaceID string, from, to *time.Time, interval string) (res task.StatsResponse, err error) {
+	logger := log.LoggerFromContext(ctx).Named("GetStatistics").With(zap.String("userID", userID), zap.String("workspaceID", workspaceID))
+
+	loc := s.location(ctx, userID)
+
+	now := time.Now().In(loc)
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc)
+	start, end := today.AddDate(0, 0, -30), today
+	if to != nil {
+		end = time.Date(to.Year(), to.Month(), to.Day(), 0, 0, 0, 0, loc)
+	}
+	if from != nil {
+		start = time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, loc)
+	}
+	if end.After(start.AddDate(0, 0, statsMaxDays[interval]-1)) {
+		err = ErrStatsRangeTooLong
+		return
+	}
+
+	data, err := s.taskRepository.Stats(ctx, userID, workspaceID, start, end.AddDate(0, 0, 1), interval, loc.String())
+	if err != nil {
+		logger.Error("failed to select", zap.Error(err))
+		return
+	}
+
+	res = task.ParseFromStatsEntity(data)
+
+	return
+}
@@ -9,10 +9,10 @@ import (
 	"go.uber.org/zap"
 )
 
-func (s *Service) ListTasks(ctx context.Context, userID, titleFilter, statusFilter, sortBy, sortOrder string, page, limit int) (res []task.Response, err error) {
+func (s *Service) ListTasks(ctx context.Context, userID, workspaceID, titleFilter, statusFilter, sortBy, sortOrder string, page, limit int) (res []task.Response, err error) {
 	logger := log.LoggerFromContext(ctx).Named("ListTasks")
 
-	data, err := s.taskRepository.List(ctx, userID, titleFilter, statusFilter, sortBy, sortOrder, page, limit)
+	data, err := s.taskRepository.List(ctx, userID, workspaceID, titleFilter, statusFilter, sortBy, sortOrder, page, limit)
 	if err != nil {
 		logger.Error("failed to select", zap.Error(err))
 		return
@@ -26,10 +26,15 @@ func (s *Service) CreateTask(ctx context.Context, req task.Request) (res task.Re
 	logger := log.LoggerFromContext(ctx).Named("CreateTask")
 
 	data := task.Entity{
-		UserID:      req.UserID,
-		Title:       req.Title,
-		Description: req.Description,
-		Status:      req.Status,
+		UserID:           req.UserID,
+		WorkspaceID:      req.WorkspaceID,
+		Title:            req.Title,
+		Description:      req.Description,
+		Status:           req.Status,
+		StoryPoints:      req.StoryPoints,
+		EstimatedMinutes: req.EstimatedMinutes,
+		DueDate:          req.DueDate,
+		Tags:             req.Tags,
 	}
 
 	data.ID, err = s.taskRepository.Add(ctx, data)
@@ -43,11 +48,11 @@ func (s *Service) CreateTask(ctx context.Context, req task.Request) (res task.Re
 	return
 }
 
-func (s *Service) GetTask(ctx context.Context, userID string, taskID string) (res task.Response, err error) {
+func (s *Service) GetTask(ctx context.Context, userID, workspaceID string, taskID string) (res task.Response, err error) {
 	logger := log.LoggerFromContext(ctx).Named("GetTask").
-		With(zap.String("userID", userID), zap.String("taskID", taskID))
+		With(zap.String("userID", userID), zap.String("workspaceID", workspaceID), zap.String("taskID", taskID))
 
-	data, err := s.taskRepository.Get(ctx, userID, taskID)
+	data, err := s.taskRepository.Get(ctx, userID, workspaceID, taskID)
 	if err != nil {
 		logger.Error("failed to get by id", zap.Error(err))
 		return
@@ -58,17 +63,21 @@ func (s *Service) GetTask(ctx context.Context, userID string, taskID string) (re
 	return
 }
 
-func (s *Service) UpdateTask(ctx context.Context, userID string, taskID string, req task.Request) (err error) {
+func (s *Service) UpdateTask(ctx context.Context, userID, workspaceID string, taskID string, req task.Request) (err error) {
 	logger := log.LoggerFromContext(ctx).Named("UpdateTask").
-		With(zap.String("userID", userID), zap.String("taskID", taskID))
+		With(zap.String("userID", userID), zap.String("workspaceID", workspaceID), zap.String("taskID", taskID))
 
 	data := task.Entity{
-		Title:       req.Title,
-		Description: req.Description,
-		Status:      req.Status,
+		Title:            req.Title,
+		Description:      req.Description,
+		Status:           req.Status,
+		StoryPoints:      req.StoryPoints,
+		EstimatedMinutes: req.EstimatedMinutes,
+		DueDate:          req.DueDate,
+		Tags:             req.Tags,
 	}
 
-	err = s.taskRepository.Update(ctx, userID, taskID, data)
+	err = s.taskRepository.Update(ctx, userID, workspaceID, taskID, data)
 	if err != nil && !errors.Is(err, store.ErrorNotFound) {
 		logger.Error("failed to update by id", zap.Error(err))
 		return
@@ -77,11 +86,11 @@ func (s *Service) UpdateTask(ctx context.Context, userID string, taskID string,
 	return
 }
 
-func (s *Service) DeleteTask(ctx context.Context, userID string, taskID string) (err error) {
+func (s *Service) DeleteTask(ctx context.Context, userID, workspaceID string, taskID string) (err error) {
 	logger := log.LoggerFromContext(ctx).Named("DeleteTask").
-		With(zap.String("userID", userID), zap.String("taskID", taskID))
+		With(zap.String("userID", userID), zap.String("workspaceID", workspaceID), zap.String("taskID", taskID))
 
-	err = s.taskRepository.Delete(ctx, userID, taskID)
+	err = s.taskRepository.Delete(ctx, userID, workspaceID, taskID)
 	if err != nil && !errors.Is(err, store.ErrorNotFound) {
 		logger.Error("failed to delete by id", zap.Error(err))
 		return
@@ -0,0 +1,214 @@
+package todo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/yrss1/todo/internal/domain/task"
+	"github.com/yrss1/todo/internal/domain/template"
+	"github.com/yrss1/todo/pkg/helpers"
+	"github.com/yrss1/todo/pkg/log"
+	"github.com/yrss1/todo/pkg/store"
+	"go.uber.org/zap"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrInvalidInstance wraps the validation error of a task rendered from a
+// template.
+var ErrInvalidInstance = errors.New("invalid template instance")
+
+func (s *Service) ListTemplates(ctx context.Context, userID, workspaceID string) (res []template.Response, err error) {
+	logger := log.LoggerFromContext(ctx).Named("ListTemplates").With(zap.String("userID", userID), zap.String("workspaceID", workspaceID))
+
+	data, err := s.templateRepository.List(ctx, userID, workspaceID)
+	if err != nil {
+		logger.Error("failed to select", zap.Error(err))
+		return
+	}
+
+	res = template.ParseFromEntities(data)
+
+	return
+}
+
+func (s *Service) CreateTemplate(ctx context.Context, req template.Request) (res template.Response, err error) {
+	logger := log.LoggerFromContext(ctx).Named("CreateTemplate")
+
+	data := template.Entity{
+		UserID:      req.UserID,
+		WorkspaceID: req.WorkspaceID,
+		Name:        req.Name,
+		Title:       req.Title,
+		Description: req.Description,
+		Status:      req.Status,
+		Checklist:   req.Checklist,
+		Tags:        req.Tags,
+	}
+
+	data.ID, err = s.templateRepository.Add(ctx, data)
+	if err != nil {
+		logger.Error("failed to create", zap.Error(err))
+		return
+	}
+
+	res = template.ParseFromEntity(data)
+
+	return
+}
+
+func (s *Service) GetTemplate(ctx context.Context, userID, workspaceID string, templateID string) (res template.Response, err error) {
+	logger := log.LoggerFromContext(ctx).Named("GetTemplate").
+		With(zap.String("userID", userID), zap.String("workspaceID", workspaceID), zap.String("templateID", templateID))
+
+	data, err := s.templateRepository.Get(ctx, userID, workspaceID, templateID)
+	if err != nil {
+		logger.Error("failed to get by id", zap.Error(err))
+		return
+	}
+
+	res = template.ParseFromEntity(data)
+
+	return
+}
+
+func (s *Service) UpdateTemplate(ctx context.Context, userID, workspaceID string, templateID string, req template.Request) (err error) {
+	logger := log.LoggerFromContext(ctx).Named("UpdateTemplate").
+		With(zap.String("userID", userID), zap.String("workspaceID", workspaceID), zap.String("templateID", templateID))
+
+	data := template.Entity{
+		Name:        req.Name,
+		Title:       req.Title,
+		Description: req.Description,
+		Status:      req.Status,
+		Checklist:   req.Checklist,
+		Tags:        req.Tags,
+	}
+
+	err = s.templateRepository.Update(ctx, userID, workspaceID, templateID, data)
+	if err != nil && !errors.Is(err, store.ErrorNotFound) {
+		logger.Error("failed to update by id", zap.Error(err))
+		return
+	}
+
+	return
+}
+
+func (s *Service) DeleteTemplate(ctx context.Context, userID, workspaceID string, templateID string) (err error) {
+	logger := log.LoggerFromContext(ctx).Named("DeleteTemplate").
+		With(zap.String("userID", userID), zap.String("workspaceID", workspaceID), zap.String("templateID", templateID))
+
+	err = s.templateRepository.Delete(ctx, userID, workspaceID, templateID)
+	if err != nil && !errors.Is(err, store.ErrorNotFound) {
+		logger.Error("failed to delete by id", zap.Error(err))
+		return
+	}
+
+	return
+}
+
+// CreateTasksFromTemplate creates one task per entry of req.Instances, or a
+// single task when no instances are given. Placeholders such as {{date}} or
+// {{name}} in the template are replaced before each task is created. Every
+// task is validated first and all of them are added in one transaction, so
+// either all or none are created.
+func (s *Service) CreateTasksFromTemplate(ctx context.Context, userID, workspaceID string, templateID string, req template.InstantiateRequest) (res []task.Response, err error) {
+	logger := log.LoggerFromContext(ctx).Named("CreateTasksFromTemplate").
+		With(zap.String("userID", userID), zap.String("workspaceID", workspaceID), zap.String("templateID", templateID))
+
+	data, err := s.templateRepository.Get(ctx, userID, workspaceID, templateID)
+	if err != nil {
+		if !errors.Is(err, store.ErrorNotFound) {
+			logger.Error("failed to get by id", zap.Error(err))
+		}
+		return
+	}
+
+	instances := req.Instances
+	if len(instances) == 0 {
+		instances = []map[string]string{{}}
+	}
+
+	now := time.Now().In(s.location(ctx, userID))
+	tasks := make([]task.Entity, 0, len(instances))
+	for i, variables := range instances {
+		replacer := newTemplateReplacer(now, i+1, variables)
+
+		taskReq := task.Request{
+			UserID:      &userID,
+			WorkspaceID: &workspaceID,
+			Title:       helpers.GetStringPtr(replacer.Replace(*data.Title)),
+			Status:      data.Status,
+		}
+		taskReq.Description = helpers.GetStringPtr(renderDescription(replacer, data))
+		for _, tag := range data.Tags {
+			taskReq.Tags = append(taskReq.Tags, replacer.Replace(tag))
+		}
+
+		if err = taskReq.Validate(); err != nil {
+			return nil, fmt.Errorf("%w %d: %w", ErrInvalidInstance, i+1, err)
+		}
+
+		tasks = append(tasks, task.Entity{
+			UserID:      taskReq.UserID,
+			WorkspaceID: taskReq.WorkspaceID,
+			Title:       taskReq.Title,
+			Description: taskReq.Description,
+			Status:      taskReq.Status,
+			Tags:        taskReq.Tags,
+		})
+	}
+
+	ids, err := s.taskRepository.AddMany(ctx, tasks)
+	if err != nil {
+		logger.Error("failed to create", zap.Error(err))
+		return
+	}
+
+	res = make([]task.Response, 0, len(tasks))
+	for i, id := range ids {
+		tasks[i].ID = id
+		res = append(res, task.ParseFromEntity(tasks[i]))
+	}
+
+	return
+}
+
+func newTemplateReplacer(now time.Time, index int, variables map[string]string) *strings.Replacer {
+	values := map[string]string{
+		"date":     now.Format(time.DateOnly),
+		"time":     now.Format("15:04"),
+		"datetime": now.Format(time.DateTime),
+		"index":    strconv.Itoa(index),
+	}
+	for key, value := range variables {
+		values[key] = value
+	}
+
+	pairs := make([]string, 0, len(values)*4)
+	for key, value := range values {
+		pairs = append(pairs, "{{"+key+"}}", value, "{{ "+key+" }}", value)
+	}
+
+	return strings.NewReplacer(pairs...)
+}
+
+func renderDescription(replacer *strings.Replacer, data template.Entity) string {
+	var description strings.Builder
+	if data.Description != nil {
+		description.WriteString(replacer.Replace(*data.Description))
+	}
+
+	for i, item := range data.Checklist {
+		if i == 0 && description.Len() > 0 {
+			description.WriteString("\n\n")
+		} else if i > 0 {
+			description.WriteString("\n")
+		}
+		description.WriteString("- [ ] ")
+		description.WriteString(replacer.Replace(item))
+	}
+
+	return description.String()
+}
@@ -0,0 +1,284 @@
+package workspace
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/yrss1/todo/internal/domain/user"
+	"github.com/yrss1/todo/internal/domain/workspace"
+	"github.com/yrss1/todo/pkg/helpers"
+	"github.com/yrss1/todo/pkg/log"
+	"github.com/yrss1/todo/pkg/mail"
+	"github.com/yrss1/todo/pkg/store"
+	"go.uber.org/zap"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const invitationTTL = 7 * 24 * time.Hour
+
+var (
+	ErrInvalidInvitation  = errors.New("invalid or expired invitation")
+	ErrAccountRequired    = errors.New("name and password are required to create an account")
+	ErrInvitationAccepted = errors.New("invitation was already accepted")
+	ErrLoginRequired      = errors.New("log in as the invited account to accept the invitation")
+	ErrOtherAccount       = errors.New("invitation is for another email address")
+)
+
+func (s *Service) ListInvitations(ctx context.Context, id, userID string) (res []workspace.InvitationResponse, err error) {
+	logger := log.LoggerFromContext(ctx).Named("ListInvitations").With(zap.String("id", id))
+
+	if _, err = s.managed(ctx, id, userID); err != nil {
+		return
+	}
+
+	data, err := s.workspaceRepository.ListInvitations(ctx, id)
+	if err != nil {
+		logger.Error("failed to select", zap.Error(err))
+		return
+	}
+
+	res = workspace.ParseFromInvitations(data)
+
+	return
+}
+
+// Invite emails a link to join the workspace with the given role. Inviting
+// the same address again replaces the earlier invitation. Owners and admins
+// can invite, but only owners can invite owners.
+func (s *Service) Invite(ctx context.Context, id, userID string, req workspace.InvitationRequest) (res workspace.InvitationResponse, err error) {
+	logger := log.LoggerFromContext(ctx).Named("Invite").With(zap.String("id", id))
+
+	data, err := s.managed(ctx, id, userID)
+	if err != nil {
+		return
+	}
+	if !canAssign(data.Role, *req.Role) {
+		return res, ErrForbidden
+	}
+
+	email := user.NormalizeEmail(*req.Email)
+
+	switch existing, getErr := s.userRepository.GetByEmail(ctx, email); {
+	case getErr == nil:
+		if _, err = s.workspaceRepository.GetMember(ctx, id, existing.ID); err == nil {
+			return res, ErrAlreadyMember
+		}
+		if !errors.Is(err, store.ErrorNotFound) {
+			logger.Error("failed to get member", zap.Error(err))
+			return
+		}
+		err = nil
+	case !errors.Is(getErr, store.ErrorNotFound):
+		logger.Error("failed to get user by email", zap.Error(getErr))
+		return res, getErr
+	}
+
+	token, err := helpers.GenerateToken(32)
+	if err != nil {
+		logger.Error("failed to generate token", zap.Error(err))
+		return
+	}
+
+	invitation, err := s.workspaceRepository.AddInvitation(ctx, workspace.Invitation{
+		WorkspaceID: id,
+		Email:       email,
+		Role:        *req.Role,
+		TokenHash:   helpers.HashToken(token),
+		InvitedBy:   &userID,
+		ExpiresAt:   time.Now().Add(invitationTTL),
+	})
+	if err != nil {
+		logger.Error("failed to create invitation", zap.Error(err))
+		return
+	}
+
+	if err = s.sendInvitation(ctx, data, userID, invitation, token); err != nil {
+		return
+	}
+
+	res = workspace.ParseFromInvitation(invitation)
+
+	return
+}
+
+// ResendInvitation sends a new link for an open invitation. The previous
+// link stops working and the invitation is valid for another week.
+func (s *Service) ResendInvitation(ctx context.Context, id, userID, invitationID string) (res workspace.InvitationResponse, err error) {
+	logger := log.LoggerFromContext(ctx).Named("ResendInvitation").With(zap.String("id", id), zap.String("invitationID", invitationID))
+
+	data, err := s.managed(ctx, id, userID)
+	if err != nil {
+		return
+	}
+
+	token, err := helpers.GenerateToken(32)
+	if err != nil {
+		logger.Error("failed to generate token", zap.Error(err))
+		return
+	}
+
+	invitation, err := s.workspaceRepository.RenewInvitation(ctx, id, invitationID, helpers.HashToken(token), time.Now().Add(invitationTTL))
+	if err != nil {
+		if !errors.Is(err, store.ErrorNotFound) {
+			logger.Error("failed to renew invitation", zap.Error(err))
+		}
+		return
+	}
+
+	if err = s.sendInvitation(ctx, data, userID, invitation, token); err != nil {
+		return
+	}
+
+	res = workspace.ParseFromInvitation(invitation)
+
+	return
+}
+
+func (s *Service) RevokeInvitation(ctx context.Context, id, userID, invitationID string) (err error) {
+	logger := log.LoggerFromContext(ctx).Named("RevokeInvitation").With(zap.String("id", id), zap.String("invitationID", invitationID))
+
+	if _, err = s.managed(ctx, id, userID); err != nil {
+		return
+	}
+
+	if err = s.workspaceRepository.RevokeInvitation(ctx, id, invitationID); err != nil {
+		if !errors.Is(err, store.ErrorNotFound) {
+			logger.Error("failed to revoke invitation", zap.Error(err))
+		}
+		return
+	}
+
+	return
+}
+
+// PreviewInvitation describes the invitation of a token to the person who
+// received it.
+func (s *Service) PreviewInvitation(ctx context.Context, token string) (res workspace.InvitationPreviewResponse, err error) {
+	invitation, err := s.invitation(ctx, token)
+	if err != nil {
+		return
+	}
+
+	res = workspace.InvitationPreviewResponse{
+		WorkspaceName: invitation.WorkspaceName,
+		Email:         invitation.Email,
+		Role:          invitation.Role,
+		ExpiresAt:     invitation.ExpiresAt,
+	}
+
+	return
+}
+
+// AcceptInvitation adds the owner of the invited address to the workspace.
+// userID is the logged-in user, or empty for an anonymous request. An
+// existing account has to be logged in, since the token alone must not grant
+// access to it; otherwise an account is registered with the name and
+// password of the request. The token proves the address, so it counts as
+// verified either way.
+func (s *Service) AcceptInvitation(ctx context.Context, userID string, req workspace.AcceptInvitationRequest) (res workspace.AcceptInvitationResponse, err error) {
+	logger := log.LoggerFromContext(ctx).Named("AcceptInvitation")
+
+	invitation, err := s.invitation(ctx, *req.Token)
+	if err != nil {
+		return
+	}
+	logger = logger.With(zap.String("invitationID", invitation.ID))
+
+	account, err := s.userRepository.GetByEmail(ctx, invitation.Email)
+	switch {
+	case err == nil && userID == "":
+		return res, ErrLoginRequired
+	case err == nil && userID != account.ID, errors.Is(err, store.ErrorNotFound) && userID != "":
+		return res, ErrOtherAccount
+	case err == nil:
+		res.UserID = account.ID
+		if account.EmailVerifiedAt == nil {
+			now := time.Now()
+			if err = s.userRepository.Update(ctx, account.ID, user.Entity{EmailVerifiedAt: &now}); err != nil {
+				logger.Error("failed to verify email", zap.Error(err))
+				return
+			}
+		}
+	case errors.Is(err, store.ErrorNotFound):
+		if req.Name == nil || req.Password == nil {
+			return res, ErrAccountRequired
+		}
+
+		registration := user.Request{Name: req.Name, Email: &invitation.Email, Password: req.Password}
+		if res.UserID, err = s.authService.RegisterVerified(ctx, registration); err != nil {
+			return
+		}
+		res.AccountCreated = true
+	default:
+		logger.Error("failed to get user by email", zap.Error(err))
+		return
+	}
+
+	if err = s.workspaceRepository.AcceptInvitation(ctx, invitation.ID, res.UserID); err != nil {
+		if errors.Is(err, store.ErrorNotFound) {
+			return res, ErrInvitationAccepted
+		}
+		logger.Error("failed to accept invitation", zap.Error(err))
+		return
+	}
+
+	data, err := s.workspaceRepository.Get(ctx, invitation.WorkspaceID, res.UserID)
+	if err != nil {
+		logger.Error("failed to get workspace", zap.Error(err))
+		return
+	}
+	res.Workspace = workspace.ParseFromEntity(data)
+
+	return
+}
+
+func (s *Service) invitation(ctx context.Context, token string) (data workspace.Invitation, err error) {
+	data, err = s.workspaceRepository.GetInvitationByHash(ctx, helpers.HashToken(token))
+	if err != nil {
+		if errors.Is(err, store.ErrorNotFound) {
+			return data, ErrInvalidInvitation
+		}
+		log.LoggerFromContext(ctx).Named("GetInvitation").Error("failed to get invitation", zap.Error(err))
+	}
+
+	return
+}
+
+// managed returns the workspace if userID may manage its members.
+func (s *Service) managed(ctx context.Context, id, userID string) (data workspace.Entity, err error) {
+	if data, err = s.get(ctx, id, userID); err != nil {
+		return
+	}
+	if !workspace.CanManage(data.Role) {
+		err = ErrForbidden
+	}
+
+	return
+}
+
+func (s *Service) sendInvitation(ctx context.Context, data workspace.Entity, userID string, invitation workspace.Invitation, token string) (err error) {
+	logger := log.LoggerFromContext(ctx).Named("sendInvitation").With(zap.String("invitationID", invitation.ID))
+
+	inviter := "A member"
+	if account, getErr := s.userRepository.Get(ctx, userID); getErr == nil && account.Name != nil {
+		inviter = *account.Name
+	}
+
+	link := strings.TrimSuffix(s.publicURL, "/") + "/accept-invitation?token=" + url.QueryEscape(token)
+
+	msg := mail.Message{
+		To:      invitation.Email,
+		Subject: fmt.Sprintf("You are invited to join %s", data.Name),
+		Body: fmt.Sprintf("Hi,\n\n%s invited you to join the workspace %q as %s. Accept the invitation by opening the link below. It expires in %s.\n\n%s\n\n"+
+			"If you do not have an account yet, you can create one on the way.\n", inviter, data.Name, invitation.Role, helpers.FormatDuration(invitationTTL), link),
+	}
+
+	if err = s.mailer.Send(ctx, msg); err != nil {
+		logger.Error("failed to send invitation", zap.Error(err))
+		return
+	}
+
+	return
+}
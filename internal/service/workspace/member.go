@@ -0,0 +1,178 @@
+package workspace
+
+import (
+	"context"
+	"errors"
+	"github.com/yrss1/todo/internal/domain/user"
+	"github.com/yrss1/todo/internal/domain/workspace"
+	"github.com/yrss1/todo/pkg/log"
+	"github.com/yrss1/todo/pkg/store"
+	"go.uber.org/zap"
+)
+
+func (s *Service) ListMembers(ctx context.Context, id, userID string) (res []workspace.MemberResponse, err error) {
+	logger := log.LoggerFromContext(ctx).Named("ListMembers").With(zap.String("id", id))
+
+	if _, err = s.get(ctx, id, userID); err != nil {
+		return
+	}
+
+	data, err := s.workspaceRepository.ListMembers(ctx, id)
+	if err != nil {
+		logger.Error("failed to select", zap.Error(err))
+		return
+	}
+
+	res = workspace.ParseFromMembers(data)
+
+	return
+}
+
+// AddMember adds an existing user to the workspace. Owners and admins can
+// add members, but only owners can add other owners.
+func (s *Service) AddMember(ctx context.Context, id, userID string, req workspace.MemberRequest) (res workspace.MemberResponse, err error) {
+	logger := log.LoggerFromContext(ctx).Named("AddMember").With(zap.String("id", id))
+
+	data, err := s.get(ctx, id, userID)
+	if err != nil {
+		return
+	}
+	if !canAssign(data.Role, *req.Role) {
+		return res, ErrForbidden
+	}
+
+	member, err := s.userRepository.GetByEmail(ctx, user.NormalizeEmail(*req.Email))
+	if err != nil {
+		if !errors.Is(err, store.ErrorNotFound) {
+			logger.Error("failed to get user by email", zap.Error(err))
+		}
+		return
+	}
+
+	if err = s.workspaceRepository.AddMember(ctx, id, member.ID, *req.Role); err != nil {
+		if errors.Is(err, store.ErrorConflict) {
+			return res, ErrAlreadyMember
+		}
+		logger.Error("failed to add member", zap.Error(err))
+		return
+	}
+
+	added, err := s.workspaceRepository.GetMember(ctx, id, member.ID)
+	if err != nil {
+		logger.Error("failed to get member", zap.Error(err))
+		return
+	}
+
+	res = workspace.ParseFromMembers([]workspace.Member{added})[0]
+
+	return
+}
+
+// UpdateMember changes the role of a member. Admins cannot change owners or
+// make anyone an owner, and the last owner cannot step down.
+func (s *Service) UpdateMember(ctx context.Context, id, userID, memberID string, req workspace.RoleRequest) (err error) {
+	logger := log.LoggerFromContext(ctx).Named("UpdateMember").With(zap.String("id", id), zap.String("memberID", memberID))
+
+	data, member, err := s.manage(ctx, id, userID, memberID)
+	if err != nil {
+		return
+	}
+	if !canAssign(data.Role, *req.Role) {
+		return ErrForbidden
+	}
+
+	if member.Role == workspace.RoleOwner && *req.Role != workspace.RoleOwner {
+		if err = s.checkOwners(ctx, id); err != nil {
+			return
+		}
+	}
+
+	if err = s.workspaceRepository.UpdateMember(ctx, id, memberID, *req.Role); err != nil {
+		if !errors.Is(err, store.ErrorNotFound) {
+			logger.Error("failed to update member", zap.Error(err))
+		}
+		return
+	}
+
+	return
+}
+
+// RemoveMember takes a user out of the workspace. Members can always leave
+// by removing themselves; otherwise the rules of UpdateMember apply.
+func (s *Service) RemoveMember(ctx context.Context, id, userID, memberID string) (err error) {
+	logger := log.LoggerFromContext(ctx).Named("RemoveMember").With(zap.String("id", id), zap.String("memberID", memberID))
+
+	_, member, err := s.manage(ctx, id, userID, memberID)
+	if err != nil {
+		return
+	}
+
+	if member.Role == workspace.RoleOwner {
+		if err = s.checkOwners(ctx, id); err != nil {
+			return
+		}
+	}
+
+	if err = s.workspaceRepository.DeleteMember(ctx, id, memberID); err != nil {
+		if !errors.Is(err, store.ErrorNotFound) {
+			logger.Error("failed to remove member", zap.Error(err))
+		}
+		return
+	}
+
+	return
+}
+
+// manage returns the workspace as seen by userID and the member it wants to
+// change, provided the role of userID allows it.
+func (s *Service) manage(ctx context.Context, id, userID, memberID string) (data workspace.Entity, member workspace.Member, err error) {
+	logger := log.LoggerFromContext(ctx).Named("GetMember").With(zap.String("id", id), zap.String("memberID", memberID))
+
+	data, err = s.get(ctx, id, userID)
+	if err != nil {
+		return
+	}
+
+	member, err = s.workspaceRepository.GetMember(ctx, id, memberID)
+	if err != nil {
+		if !errors.Is(err, store.ErrorNotFound) {
+			logger.Error("failed to get member", zap.Error(err))
+		}
+		return
+	}
+
+	switch {
+	case memberID == userID:
+	case data.Role == workspace.RoleOwner:
+	case data.Role == workspace.RoleAdmin && member.Role != workspace.RoleOwner:
+	default:
+		err = ErrForbidden
+	}
+
+	return
+}
+
+func (s *Service) checkOwners(ctx context.Context, id string) (err error) {
+	count, err := s.workspaceRepository.CountOwners(ctx, id)
+	if err != nil {
+		log.LoggerFromContext(ctx).Named("CountOwners").Error("failed to count owners", zap.String("id", id), zap.Error(err))
+		return
+	}
+	if count <= 1 {
+		return ErrLastOwner
+	}
+
+	return
+}
+
+// canAssign reports whether a member with the given role may hand out role.
+func canAssign(role, assigned string) bool {
+	switch role {
+	case workspace.RoleOwner:
+		return true
+	case workspace.RoleAdmin:
+		return assigned != workspace.RoleOwner
+	default:
+		return false
+	}
+}
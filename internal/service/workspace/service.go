@@ -0,0 +1,65 @@
+package workspace
+
+import (
+	"github.com/yrss1/todo/internal/domain/user"
+	"github.com/yrss1/todo/internal/domain/workspace"
+	"github.com/yrss1/todo/internal/service/auth"
+	"github.com/yrss1/todo/pkg/mail"
+)
+
+type Configuration func(s *Service) error
+
+// Service manages workspaces, the boundary between the teams sharing a
+// deployment. Tasks and templates belong to exactly one workspace.
+type Service struct {
+	workspaceRepository workspace.Repository
+	userRepository      user.Repository
+
+	authService *auth.Service
+	mailer      mail.Mailer
+	publicURL   string
+}
+
+func New(configs ...Configuration) (s *Service, err error) {
+	s = &Service{}
+
+	for _, cfg := range configs {
+		if err = cfg(s); err != nil {
+			return
+		}
+	}
+
+	return
+}
+
+func WithWorkspaceRepository(workspaceRepository workspace.Repository) Configuration {
+	return func(s *Service) error {
+		s.workspaceRepository = workspaceRepository
+		return nil
+	}
+}
+
+func WithUserRepository(userRepository user.Repository) Configuration {
+	return func(s *Service) error {
+		s.userRepository = userRepository
+		return nil
+	}
+}
+
+// WithAuthService registers the accounts of people who accept an invitation
+// without having one.
+func WithAuthService(authService *auth.Service) Configuration {
+	return func(s *Service) error {
+		s.authService = authService
+		return nil
+	}
+}
+
+// WithMailer sends invitations. publicURL is the base of their links.
+func WithMailer(mailer mail.Mailer, publicURL string) Configuration {
+	return func(s *Service) error {
+		s.mailer = mailer
+		s.publicURL = publicURL
+		return nil
+	}
+}
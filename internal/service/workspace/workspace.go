@@ -0,0 +1,141 @@
+package workspace
+
+import (
+	"context"
+	"errors"
+	"github.com/yrss1/todo/internal/domain/workspace"
+	"github.com/yrss1/todo/pkg/log"
+	"github.com/yrss1/todo/pkg/store"
+	"go.uber.org/zap"
+	"strings"
+)
+
+// personalName is the name of the workspace created for users who are not
+// a member of any.
+const personalName = "Personal"
+
+var (
+	ErrForbidden     = errors.New("insufficient workspace permissions")
+	ErrLastOwner     = errors.New("a workspace needs at least one owner")
+	ErrAlreadyMember = errors.New("user is already a member of the workspace")
+)
+
+func (s *Service) ListWorkspaces(ctx context.Context, userID string) (res []workspace.Response, err error) {
+	logger := log.LoggerFromContext(ctx).Named("ListWorkspaces").With(zap.String("userID", userID))
+
+	data, err := s.workspaceRepository.List(ctx, userID)
+	if err != nil {
+		logger.Error("failed to select", zap.Error(err))
+		return
+	}
+
+	res = workspace.ParseFromEntities(data)
+
+	return
+}
+
+func (s *Service) CreateWorkspace(ctx context.Context, userID string, req workspace.Request) (res workspace.Response, err error) {
+	logger := log.LoggerFromContext(ctx).Named("CreateWorkspace").With(zap.String("userID", userID))
+
+	data, err := s.workspaceRepository.Add(ctx, strings.TrimSpace(*req.Name), userID)
+	if err != nil {
+		logger.Error("failed to create", zap.Error(err))
+		return
+	}
+
+	res = workspace.ParseFromEntity(data)
+
+	return
+}
+
+func (s *Service) GetWorkspace(ctx context.Context, id, userID string) (res workspace.Response, err error) {
+	data, err := s.get(ctx, id, userID)
+	if err != nil {
+		return
+	}
+
+	res = workspace.ParseFromEntity(data)
+
+	return
+}
+
+func (s *Service) UpdateWorkspace(ctx context.Context, id, userID string, req workspace.Request) (err error) {
+	logger := log.LoggerFromContext(ctx).Named("UpdateWorkspace").With(zap.String("id", id))
+
+	data, err := s.get(ctx, id, userID)
+	if err != nil {
+		return
+	}
+	if !workspace.CanManage(data.Role) {
+		return ErrForbidden
+	}
+
+	if err = s.workspaceRepository.Update(ctx, id, strings.TrimSpace(*req.Name)); err != nil {
+		logger.Error("failed to update by id", zap.Error(err))
+		return
+	}
+
+	return
+}
+
+// DeleteWorkspace removes the workspace with every task and template in it.
+// Only owners can do this.
+func (s *Service) DeleteWorkspace(ctx context.Context, id, userID string) (err error) {
+	logger := log.LoggerFromContext(ctx).Named("DeleteWorkspace").With(zap.String("id", id))
+
+	data, err := s.get(ctx, id, userID)
+	if err != nil {
+		return
+	}
+	if data.Role != workspace.RoleOwner {
+		return ErrForbidden
+	}
+
+	if err = s.workspaceRepository.Delete(ctx, id); err != nil && !errors.Is(err, store.ErrorNotFound) {
+		logger.Error("failed to delete by id", zap.Error(err))
+		return
+	}
+
+	return
+}
+
+// ResolveWorkspace returns the workspace a request of the user acts in. An
+// empty id selects the default workspace, which is the oldest membership of
+// the user. Users without any workspace get their personal one, which is
+// created at most once even for concurrent first requests.
+func (s *Service) ResolveWorkspace(ctx context.Context, id, userID string) (res workspace.Entity, err error) {
+	if id != "" {
+		return s.get(ctx, id, userID)
+	}
+
+	logger := log.LoggerFromContext(ctx).Named("ResolveWorkspace").With(zap.String("userID", userID))
+
+	data, err := s.workspaceRepository.List(ctx, userID)
+	if err != nil {
+		logger.Error("failed to select", zap.Error(err))
+		return
+	}
+	if len(data) > 0 {
+		return data[0], nil
+	}
+
+	if res, err = s.workspaceRepository.AddPersonal(ctx, personalName, userID); err != nil {
+		logger.Error("failed to create personal workspace", zap.Error(err))
+		return
+	}
+
+	return
+}
+
+// get returns the workspace with the role of the user. Workspaces of other
+// teams are reported as not found, so their IDs cannot be probed.
+func (s *Service) get(ctx context.Context, id, userID string) (data workspace.Entity, err error) {
+	logger := log.LoggerFromContext(ctx).Named("GetWorkspace").With(zap.String("id", id))
+
+	data, err = s.workspaceRepository.Get(ctx, id, userID)
+	if err != nil && !errors.Is(err, store.ErrorNotFound) {
+		logger.Error("failed to get by id", zap.Error(err))
+	}
+
+	return
+}
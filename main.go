@@ -1,6 +1,11 @@
 package main
 
-import "github.com/yrss1/todo/internal/app"
+import (
+	"github.com/yrss1/todo/internal/app"
+
+	// Embedded so user time zones resolve on images without tzdata.
+	_ "time/tzdata"
+)
 
 // @title Todo API
 // @version 1.0
@@ -0,0 +1,25 @@
+package helpers
+
+import "strings"
+
+// csvFormulaPrefixes are the first characters that make spreadsheets run a
+// cell as a formula.
+const csvFormulaPrefixes = "=+-@\t\r"
+
+// EscapeCSVCell keeps spreadsheets from running a cell as a formula by
+// prefixing values that start with =, +, -, @, tab or carriage return with
+// a quote.
+func EscapeCSVCell(s string) string {
+	if s != "" && strings.ContainsRune(csvFormulaPrefixes, rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
+
+// UnescapeCSVCell reverses EscapeCSVCell, so exported files can be read back.
+func UnescapeCSVCell(s string) string {
+	if len(s) > 1 && s[0] == '\'' && strings.ContainsRune(csvFormulaPrefixes, rune(s[1])) {
+		return s[1:]
+	}
+	return s
+}
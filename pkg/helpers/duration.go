@@ -0,0 +1,27 @@
+package helpers
+
+import (
+	"fmt"
+	"time"
+)
+
+// FormatDuration spells out d in the largest whole unit for messages to
+// users, e.g. "1 hour" or "7 days". Durations that are not whole hours are
+// given in minutes, rounded up.
+func FormatDuration(d time.Duration) string {
+	switch {
+	case d >= 24*time.Hour && d%(24*time.Hour) == 0:
+		return plural(int64(d/(24*time.Hour)), "day")
+	case d >= time.Hour && d%time.Hour == 0:
+		return plural(int64(d/time.Hour), "hour")
+	default:
+		return plural(int64((d+time.Minute-1)/time.Minute), "minute")
+	}
+}
+
+func plural(n int64, unit string) string {
+	if n == 1 {
+		return fmt.Sprintf("1 %s", unit)
+	}
+	return fmt.Sprintf("%d %ss", n, unit)
+}
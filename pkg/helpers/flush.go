@@ -0,0 +1,14 @@
+package helpers
+
+import (
+	"io"
+	"net/http"
+)
+
+// Flush sends what was written to w so far to the client when w is a
+// response writer that supports it, and does nothing otherwise.
+func Flush(w io.Writer) {
+	if f, ok := w.(http.Flusher); ok {
+		f.Flush()
+	}
+}
@@ -0,0 +1,53 @@
+package mail
+
+import (
+	"context"
+	"fmt"
+	"github.com/yrss1/todo/pkg/log"
+	"go.uber.org/zap"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FileMailer writes every message as a text file into a directory instead of
+// sending it, which is handy for local development and tests. With an empty
+// directory only the recipient and subject are logged; bodies carry account
+// tokens and never go to the log.
+type FileMailer struct {
+	dir string
+	mu  sync.Mutex
+	seq int
+}
+
+func NewFileMailer(dir string) (*FileMailer, error) {
+	if dir != "" {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, err
+		}
+	}
+	return &FileMailer{dir: dir}, nil
+}
+
+func (m *FileMailer) Send(ctx context.Context, msg Message) error {
+	logger := log.LoggerFromContext(ctx).Named("FileMailer")
+	logger.Info("mail", zap.String("to", msg.To), zap.String("subject", msg.Subject))
+
+	if m.dir == "" {
+		return nil
+	}
+
+	m.mu.Lock()
+	m.seq++
+	name := fmt.Sprintf("%s-%04d.eml", time.Now().UTC().Format("20060102T150405"), m.seq)
+	m.mu.Unlock()
+
+	var body strings.Builder
+	body.WriteString("To: " + msg.To + "\n")
+	body.WriteString("Subject: " + msg.Subject + "\n\n")
+	body.WriteString(msg.Body)
+
+	return os.WriteFile(filepath.Join(m.dir, name), []byte(body.String()), 0o644)
+}
@@ -0,0 +1,81 @@
+package password
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	DefaultMinLength = 8
+	DefaultMaxLength = 128
+)
+
+// ErrWeakPassword is wrapped by every policy violation.
+var ErrWeakPassword = errors.New("password does not meet the policy")
+
+type Policy struct {
+	MinLength int
+	MaxLength int
+
+	breached map[string]struct{}
+}
+
+// NewPolicy returns a policy with the given length limits. breachedFile, if
+// set, lists known breached or common passwords, one per line; they are
+// compared case-insensitively.
+func NewPolicy(minLength, maxLength int, breachedFile string) (*Policy, error) {
+	p := &Policy{MinLength: minLength, MaxLength: maxLength}
+
+	if breachedFile == "" {
+		return p, nil
+	}
+
+	f, err := os.Open(breachedFile)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	p.breached = make(map[string]struct{})
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" {
+			p.breached[strings.ToLower(line)] = struct{}{}
+		}
+	}
+	if err = scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
+
+// Check validates a new password for the account with the given email.
+func (p *Policy) Check(password, email string) error {
+	length := utf8.RuneCountInString(password)
+
+	if p.MinLength > 0 && length < p.MinLength {
+		return fmt.Errorf("%w: must be at least %d characters", ErrWeakPassword, p.MinLength)
+	}
+
+	if p.MaxLength > 0 && length > p.MaxLength {
+		return fmt.Errorf("%w: must be at most %d characters", ErrWeakPassword, p.MaxLength)
+	}
+
+	if email != "" {
+		local, _, _ := strings.Cut(strings.TrimSpace(email), "@")
+		if strings.EqualFold(password, strings.TrimSpace(email)) || strings.EqualFold(password, local) {
+			return fmt.Errorf("%w: must not be the email address", ErrWeakPassword)
+		}
+	}
+
+	if _, ok := p.breached[strings.ToLower(password)]; ok {
+		return fmt.Errorf("%w: appears in a list of breached passwords", ErrWeakPassword)
+	}
+
+	return nil
+}
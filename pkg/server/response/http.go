@@ -36,6 +36,38 @@ func BadRequest(c *gin.Context, err error, data any) {
 	c.JSON(http.StatusBadRequest, h)
 }
 
+func Unauthorized(c *gin.Context, err error) {
+	h := Object{
+		Success: false,
+		Message: err.Error(),
+	}
+	c.JSON(http.StatusUnauthorized, h)
+}
+
+func Forbidden(c *gin.Context, err error) {
+	h := Object{
+		Success: false,
+		Message: err.Error(),
+	}
+	c.JSON(http.StatusForbidden, h)
+}
+
+func Conflict(c *gin.Context, err error) {
+	h := Object{
+		Success: false,
+		Message: err.Error(),
+	}
+	c.JSON(http.StatusConflict, h)
+}
+
+func TooManyRequests(c *gin.Context, err error) {
+	h := Object{
+		Success: false,
+		Message: err.Error(),
+	}
+	c.JSON(http.StatusTooManyRequests, h)
+}
+
 func NotFound(c *gin.Context, err error) {
 	h := Object{
 		Success: false,
@@ -66,6 +98,7 @@ func MethodNotAllowedMiddleware() gin.HandlerFunc {
 			http.MethodGet:    true,
 			http.MethodPost:   true,
 			http.MethodPut:    true,
+			http.MethodPatch:  true,
 			http.MethodDelete: true,
 		}
 		if !allowedMethods[c.Request.Method] {
@@ -8,16 +8,22 @@ import (
 
 func New() *gin.Engine {
 	r := gin.New()
+	r.Use(gin.Recovery())
+
+	// Values stored in the request context, such as the request ID, are
+	// visible through the *gin.Context handed to services.
+	r.ContextWithFallback = true
 
 	r.Use(cors.New(cors.Config{
 		AllowOrigins:     []string{"*"},
-		AllowMethods:     []string{"GET", "PUT", "POST", "DELETE"},
+		AllowMethods:     []string{"GET", "PUT", "PATCH", "POST", "DELETE"},
 		AllowHeaders:     []string{"*"},
 		AllowCredentials: true,
 		MaxAge:           300,
 	}))
 
 	r.Use(response.MethodNotAllowedMiddleware())
+	r.Use(RequestIDMiddleware())
 
 	return r
 }
@@ -0,0 +1,42 @@
+package router
+
+import (
+	"context"
+	"github.com/gin-gonic/gin"
+	"github.com/yrss1/todo/pkg/helpers"
+	"github.com/yrss1/todo/pkg/log"
+	"go.uber.org/zap"
+	"regexp"
+)
+
+// RequestIDHeader carries the ID of a request, both ways. A client or proxy
+// may send one; otherwise it is generated.
+const RequestIDHeader = "X-Request-ID"
+
+var requestIDPattern = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+type requestID struct{}
+
+// RequestIDFromContext returns the ID of the request handled with ctx.
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestID{}).(string)
+	return id
+}
+
+// RequestIDMiddleware assigns every request an ID, echoes it in the
+// response and adds it to the logger of the request.
+func RequestIDMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(RequestIDHeader)
+		if !requestIDPattern.MatchString(id) {
+			id, _ = helpers.GenerateToken(12)
+		}
+
+		ctx := context.WithValue(c.Request.Context(), requestID{}, id)
+		ctx = log.ContextWithLogger(ctx, log.LoggerFromContext(ctx).With(zap.String("requestID", id)))
+		c.Request = c.Request.WithContext(ctx)
+
+		c.Header(RequestIDHeader, id)
+		c.Next()
+	}
+}
@@ -2,8 +2,31 @@ package store
 
 import (
 	"errors"
+	"github.com/lib/pq"
 )
 
 var (
 	ErrorNotFound = errors.New("error not found")
+	ErrorConflict = errors.New("error conflict")
 )
+
+const (
+	// uniqueViolation is the SQLSTATE of a unique constraint violation.
+	uniqueViolation = "23505"
+	// invalidTextRepresentation is the SQLSTATE of a malformed value, such as
+	// an ID that is not a UUID.
+	invalidTextRepresentation = "22P02"
+)
+
+// IsUniqueViolation reports whether err is a violated unique constraint.
+func IsUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == uniqueViolation
+}
+
+// IsInvalidInput reports whether err is a value the column type could not
+// parse.
+func IsInvalidInput(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == invalidTextRepresentation
+}